@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"math/rand"
+	"time"
 
 	"github.com/decentraland/world/internal/cli"
 	"github.com/decentraland/world/internal/commons/config"
 	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/tracing"
+	"github.com/decentraland/world/internal/commons/utils"
 )
 
 type rootConfig struct {
@@ -24,10 +27,30 @@ type rootConfig struct {
 		Email             string `overwrite-flag:"email" validate:"required"`
 		Password          string `overwrite-flag:"password" validate:"required"`
 		KeyPath           string `overwrite-flag:"keyPath" validate:"required"`
-		CenterX           int    `overwrite-flag:"centerX"`
-		CenterY           int    `overwrite-flag:"centerY"`
+		CenterX           int    `overwrite-flag:"centerX" flag-usage:"center parcel x, in parcels, not meters"`
+		CenterY           int    `overwrite-flag:"centerY" flag-usage:"center parcel y, in parcels, not meters"`
 		Radius            int    `overwrite-flag:"radius" flag-usage:"radius in parcels"`
 		TrackStats        bool   `overwrite-flag:"trackStats"`
+		DirectMessages    bool   `overwrite-flag:"directMessages" flag-usage:"receive and ack direct messages"`
+		DMRecipient       string `overwrite-flag:"dmRecipient" flag-usage:"identity to send direct messages to"`
+		Movement          cli.MovementConfig
+
+		BatchFlushInterval int `overwrite-flag:"batchFlushInterval" flag-usage:"batch flush interval in milliseconds, 0 to disable"`
+		BatchMaxSize       int `overwrite-flag:"batchMaxSize" flag-usage:"max batch size in bytes, up to 1024"`
+
+		ProfileSnapshotURL string `overwrite-flag:"profileSnapshotURL" flag-usage:"profile snapshot url to announce"`
+		ProfileKeepAlive   int    `overwrite-flag:"profileKeepAlive" flag-usage:"max time between profile announcements, in seconds"`
+
+		KeyPassphraseFile string `overwrite-flag:"keyPassphraseFile" flag-usage:"file with the passphrase of an encrypted key"`
+		KeyPassphraseEnv  string `overwrite-flag:"keyPassphraseEnv" flag-usage:"env var with the passphrase of an encrypted key, DCL_KEY_PASSPHRASE by default"`
+	}
+
+	Log logging.Config
+
+	Tracing struct {
+		Exporter    string  `overwrite-flag:"traceExporter" flag-usage:"trace exporter: otlp or stdout, empty to disable"`
+		OTLPAddress string  `overwrite-flag:"traceOTLPAddress" flag-usage:"otlp collector address"`
+		SampleRatio float64 `overwrite-flag:"traceSampleRatio" flag-usage:"fraction of the new traces sampled"`
 	}
 }
 
@@ -39,13 +62,31 @@ func main() {
 
 	fmt.Println("running random simulation")
 
-	log, err := logging.New(&logging.LoggerConfig{Level: conf.Cli.LogLevel})
+	log, closeLog, err := logging.New(conf.Log.LoggerConfig("bot", conf.Cli.LogLevel))
 	if err != nil {
-		log.Fatal().Msg("error setting log level")
+		log.Fatal().Err(err).Msg("invalid log config")
 	}
+	defer closeLog()
 	defer logging.LogPanic(log)
 
-	ephemeralKey, err := cli.ReadEphemeralKeyFromFile(conf.Cli.KeyPath)
+	if conf.Tracing.Exporter != "" {
+		tracer, err := tracing.NewProvider(&tracing.Config{
+			ServiceName: "bot",
+			Exporter:    conf.Tracing.Exporter,
+			OTLPAddress: conf.Tracing.OTLPAddress,
+			SampleRatio: conf.Tracing.SampleRatio,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot start tracing")
+		}
+		defer tracer.Close()
+	}
+
+	ephemeralKey, err := cli.ReadEphemeralKeyFromFile(conf.Cli.KeyPath, &cli.PassphraseConfig{
+		File:   conf.Cli.KeyPassphraseFile,
+		Env:    conf.Cli.KeyPassphraseEnv,
+		Prompt: true,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("error loading ephemeral key")
 	}
@@ -61,24 +102,44 @@ func main() {
 		Auth0Audience:     conf.Cli.Auth0Audience,
 	}
 
-	var checkpoints [6]cli.V3
+	center := cli.ParcelCenter(conf.Cli.CenterX, conf.Cli.CenterY)
+	movement, err := conf.Cli.Movement.Options(center, conf.Cli.Radius)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid movement configuration")
+	}
+
+	if movement.Mode == cli.FollowMovement {
+		log.Fatal().Msg("a single bot has no leader to follow, follow mode is only supported by realistictest")
+	}
 
-	for i := 0; i < len(checkpoints); i++ {
-		p := &checkpoints[i]
+	movement.Checkpoints = movement.RandomCheckpoints(6)
 
-		p.X = float64(conf.Cli.CenterX + rand.Intn(10)*conf.Cli.Radius*2 - conf.Cli.Radius)
-		p.Y = 1.6
-		p.Z = float64(conf.Cli.CenterY + rand.Intn(10)*conf.Cli.Radius*2 - conf.Cli.Radius)
+	avatar, err := cli.NewAvatar(movement)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create avatar")
 	}
 
 	opts := cli.BotOptions{
 		CoordinatorURL: conf.CoordinatorURL,
 		Auth:           auth,
-		Checkpoints:    checkpoints[:],
-		DurationMs:     10000,
+		Avatar:         avatar,
 		TrackStats:     conf.Cli.TrackStats,
 		Log:            log,
+
+		DirectMessages:         conf.Cli.DirectMessages,
+		DirectMessageRecipient: conf.Cli.DMRecipient,
+
+		BatchFlushInterval: time.Duration(conf.Cli.BatchFlushInterval) * time.Millisecond,
+		BatchMaxSize:       conf.Cli.BatchMaxSize,
+
+		ProfileSnapshotURL: conf.Cli.ProfileSnapshotURL,
+		ProfileKeepAlive:   time.Duration(conf.Cli.ProfileKeepAlive) * time.Second,
 	}
 
-	cli.StartBot(&opts)
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	if err := cli.StartBot(ctx, &opts); err != nil {
+		log.Fatal().Err(err).Msg("bot failed")
+	}
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/decentraland/world/internal/cli"
+	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/utils"
+)
+
+type rootConfig struct {
+	IdentityURL    string `overwrite-flag:"authURL" validate:"required"`
+	CoordinatorURL string `overwrite-flag:"coordinatorURL" validate:"required"`
+	Auth0          struct {
+		Domain string `overwrite-flag:"auth0Domain" validate:"required"`
+	}
+	Cli struct {
+		Auth0ClientID     string `overwrite-flag:"auth0ClientID" validate:"required"`
+		Auth0Audience     string `overwrite-flag:"auth0Audience" validate:"required"`
+		Auth0ClientSecret string `overwrite-flag:"auth0ClientSecret" validate:"required"`
+		Email             string `overwrite-flag:"email" validate:"required"`
+		Password          string `overwrite-flag:"password" validate:"required"`
+		KeyPath           string `overwrite-flag:"keyPath" validate:"required"`
+
+		KeyPassphraseFile string `overwrite-flag:"keyPassphraseFile" flag-usage:"file with the passphrase of an encrypted key"`
+		KeyPassphraseEnv  string `overwrite-flag:"keyPassphraseEnv" flag-usage:"env var with the passphrase of an encrypted key, DCL_KEY_PASSPHRASE by default"`
+	}
+	Chat struct {
+		LogLevel string `overwrite-flag:"logLevel"`
+		ParcelX  int    `overwrite-flag:"parcelX"`
+		ParcelY  int    `overwrite-flag:"parcelY"`
+	}
+
+	Log logging.Config
+}
+
+func main() {
+	var conf rootConfig
+	if err := config.ReadConfiguration("config/config", &conf); err != nil {
+		log.Fatal(err)
+	}
+
+	log, closeLog, err := logging.New(conf.Log.LoggerConfig("chat", conf.Chat.LogLevel))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid log config")
+	}
+	defer closeLog()
+	defer logging.LogPanic(log)
+
+	ephemeralKey, err := cli.ReadEphemeralKeyFromFile(conf.Cli.KeyPath, &cli.PassphraseConfig{
+		File:   conf.Cli.KeyPassphraseFile,
+		Env:    conf.Cli.KeyPassphraseEnv,
+		Prompt: true,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("error loading ephemeral key")
+	}
+
+	auth := &cli.ClientAuthenticator{
+		IdentityURL:       conf.IdentityURL,
+		EphemeralKey:      ephemeralKey,
+		Email:             conf.Cli.Email,
+		Password:          conf.Cli.Password,
+		Auth0Domain:       conf.Auth0.Domain,
+		Auth0ClientID:     conf.Cli.Auth0ClientID,
+		Auth0ClientSecret: conf.Cli.Auth0ClientSecret,
+		Auth0Audience:     conf.Cli.Auth0Audience,
+	}
+
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	err = cli.StartChat(ctx, cli.ChatOptions{
+		Auth:           auth,
+		CoordinatorURL: conf.CoordinatorURL,
+		ParcelX:        conf.Chat.ParcelX,
+		ParcelY:        conf.Chat.ParcelY,
+		Input:          os.Stdin,
+		Output:         os.Stdout,
+		Log:            log,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("chat failed")
+	}
+}
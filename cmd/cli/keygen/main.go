@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/decentraland/auth-go/pkg/ephemeral"
+	"github.com/decentraland/world/internal/cli"
+	"github.com/decentraland/world/internal/commons/config"
+)
+
+const usage = `usage: cli_keygen [flags] [command] [args]
+
+commands:
+  generate                generates a key in --outputDir, this is the default command
+  show <file>             prints the curve, public key and address of a key
+  convert <in> <out>      writes the key in --format, or encrypted with --encrypt
+  encrypt <in> <out>      encrypts a key with a passphrase
+  decrypt <in> <out>      decrypts a key, writing it in --format`
+
+type rootConfig struct {
+	Keygen struct {
+		Curve       string `overwrite-flag:"curve" flag-usage:"s256 or p256" validate:"required"`
+		Format      string `overwrite-flag:"format" flag-usage:"hex or pem, hex is only valid for s256 keys" validate:"required"`
+		OutputDir   string `overwrite-flag:"outputDir" validate:"required"`
+		Name        string `overwrite-flag:"name" flag-usage:"key file name, without the .key extension" validate:"required"`
+		Encrypt     bool   `overwrite-flag:"encrypt" flag-usage:"encrypt the key with a passphrase"`
+		LightScrypt bool   `overwrite-flag:"lightScrypt" flag-usage:"use a cheaper scrypt cost, for test keys"`
+		Force       bool   `overwrite-flag:"force" flag-usage:"overwrite existing key files"`
+
+		PassphraseFile string `overwrite-flag:"passphraseFile" flag-usage:"file with the key passphrase"`
+		PassphraseEnv  string `overwrite-flag:"passphraseEnv" flag-usage:"env var with the key passphrase, DCL_KEY_PASSPHRASE by default"`
+	}
+}
+
+type keygen struct {
+	conf       *rootConfig
+	passphrase *cli.PassphraseConfig
+}
+
+func (k *keygen) readKey(path string) (*ecdsa.PrivateKey, error) {
+	return cli.ReadPrivateKeyFile(path, k.passphrase)
+}
+
+func (k *keygen) writeKey(path string, key *ecdsa.PrivateKey, encrypt bool) error {
+	var content []byte
+	var err error
+
+	if encrypt {
+		passphrase, err := k.passphrase.Read(fmt.Sprintf("Passphrase for %s: ", path), true)
+		if err != nil {
+			return err
+		}
+
+		if passphrase == "" {
+			return errors.New("empty passphrase")
+		}
+
+		scryptN := cli.StandardScryptN
+		if k.conf.Keygen.LightScrypt {
+			scryptN = cli.LightScryptN
+		}
+
+		content, err = cli.EncryptKey(key, passphrase, scryptN)
+		if err != nil {
+			return err
+		}
+	} else {
+		content, err = cli.EncodePrivateKey(key, k.conf.Keygen.Format)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(path); err == nil && !k.conf.Keygen.Force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+func (k *keygen) generate() error {
+	key, err := cli.GenerateKey(k.conf.Keygen.Curve)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(k.conf.Keygen.OutputDir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(k.conf.Keygen.OutputDir, k.conf.Keygen.Name+".key")
+	if err := k.writeKey(path, key, k.conf.Keygen.Encrypt); err != nil {
+		return err
+	}
+
+	fmt.Println("key:", path)
+
+	return printKey(key)
+}
+
+func (k *keygen) show(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: show <file>")
+	}
+
+	key, err := k.readKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	return printKey(key)
+}
+
+func (k *keygen) convert(args []string, encrypt bool) error {
+	if len(args) != 2 {
+		return errors.New("usage: <in> <out>")
+	}
+
+	key, err := k.readKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	return k.writeKey(args[1], key, encrypt)
+}
+
+func printKey(key *ecdsa.PrivateKey) error {
+	curve := cli.KeyCurve(&key.PublicKey)
+	fmt.Println("curve:", curve)
+
+	if curve != cli.CurveS256 {
+		fmt.Println("public key:", cli.CompressPublicKey(&key.PublicKey))
+		return nil
+	}
+
+	ephemeralKey, err := ephemeral.NewEphemeralKey(&ephemeral.EphemeralKeyConfig{PrivateKey: key})
+	if err != nil {
+		return err
+	}
+
+	address, err := cli.KeyAddress(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("public key:", cli.EncodePublicKey(ephemeralKey))
+	fmt.Println("address:", address)
+
+	return nil
+}
+
+func main() {
+	var conf rootConfig
+	if err := config.ReadConfiguration("config/config", &conf); err != nil {
+		log.Fatal(err)
+	}
+
+	k := &keygen{
+		conf: &conf,
+		passphrase: &cli.PassphraseConfig{
+			File:   conf.Keygen.PassphraseFile,
+			Env:    conf.Keygen.PassphraseEnv,
+			Prompt: true,
+		},
+	}
+	args := flag.Args()
+
+	var err error
+
+	if len(args) == 0 {
+		args = []string{"generate"}
+	}
+
+	switch args[0] {
+	case "generate":
+		err = k.generate()
+	case "show":
+		err = k.show(args[1:])
+	case "convert":
+		err = k.convert(args[1:], conf.Keygen.Encrypt)
+	case "encrypt":
+		err = k.convert(args[1:], true)
+	case "decrypt":
+		err = k.convert(args[1:], false)
+	default:
+		fmt.Fprintf(os.Stderr, "%s\n", usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
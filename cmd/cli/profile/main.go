@@ -1,16 +1,33 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
-
-	"bufio"
+	"strconv"
 	"strings"
 
 	"github.com/decentraland/world/internal/cli"
 	"github.com/decentraland/world/internal/commons/config"
 )
 
+const usage = `usage: cli_profile [flags] <command> [args]
+
+commands:
+  get [field]                 prints the profile as json, or a single field, e.g. avatar.hair.color
+  set-field <field> <value>   sets a field, the value is parsed as json or taken as a string
+  store                       stores the profile read from stdin
+  diff <file>                 compares the stored profile with a local one
+  validate <file>             validates a local profile against the schema
+  bulk-seed <n> [template]    stores a profile for n test identities, --email has to be a pattern
+                              with a %d, e.g. test+%d@example.com
+
+--retrieve and --store are kept as aliases of get and store`
+
 type rootConfig struct {
 	IdentityURL string `overwrite-flag:"authURL" validate:"required"`
 	ProfileURL  string `overwrite-flag:"profileURL" validate:"required"`
@@ -20,75 +37,330 @@ type rootConfig struct {
 	Cli struct {
 		Store             bool   `overwrite-flag:"store"`
 		Retrieve          bool   `overwrite-flag:"retrieve"`
+		ProfileSchema     string `overwrite-flag:"profileSchema" flag-usage:"profile json schema, to validate before storing"`
 		Auth0ClientID     string `overwrite-flag:"auth0ClientID" validate:"required"`
 		Auth0Audience     string `overwrite-flag:"auth0Audience" validate:"required"`
-		Auth0ClientSecret string `overwrite-flag:"auth0ClientSecret" validate:"required"`
-		Email             string `overwrite-flag:"email" validate:"required"`
-		Password          string `overwrite-flag:"password" validate:"required"`
-		KeyPath           string `overwrite-flag:"keyPath" validate:"required"`
+		Auth0ClientSecret string `overwrite-flag:"auth0ClientSecret"`
+		Email             string `overwrite-flag:"email"`
+		Password          string `overwrite-flag:"password"`
+		KeyPath           string `overwrite-flag:"keyPath"`
+
+		KeyPassphraseFile string `overwrite-flag:"keyPassphraseFile" flag-usage:"file with the passphrase of an encrypted key"`
+		KeyPassphraseEnv  string `overwrite-flag:"keyPassphraseEnv" flag-usage:"env var with the passphrase of an encrypted key, DCL_KEY_PASSPHRASE by default"`
 	}
 }
 
-func main() {
-	var conf rootConfig
-	if err := config.ReadConfiguration("config/config", &conf); err != nil {
-		log.Fatal(err)
+type profileCommand struct {
+	conf   *rootConfig
+	client *cli.ProfileClient
+}
+
+func (c *profileCommand) login(email string) (string, error) {
+	if email == "" || c.conf.Cli.Password == "" || c.conf.Cli.Auth0ClientSecret == "" {
+		return "", errors.New("missing credentials, --email, --password and --auth0ClientSecret are required")
 	}
 
-	if conf.Cli.Store == conf.Cli.Retrieve {
-		log.Fatal("please specify --store or --retrieve")
+	auth0 := cli.Auth0{
+		Domain:       c.conf.Auth0.Domain,
+		ClientID:     c.conf.Cli.Auth0ClientID,
+		ClientSecret: c.conf.Cli.Auth0ClientSecret,
+		Audience:     c.conf.Cli.Auth0Audience,
+		Email:        email,
+		Password:     c.conf.Cli.Password,
 	}
 
-	ephemeralKey, err := cli.ReadEphemeralKeyFromFile(conf.Cli.KeyPath)
+	auth := cli.Auth{
+		IdentityURL: c.conf.IdentityURL,
+		PubKey:      cli.EncodePublicKey(c.client.EphemeralKey),
+	}
+
+	accessToken, err := cli.ExecuteAuthFlow(&auth0, &auth)
 	if err != nil {
-		log.Fatalf("error loading ephemeral key: %v", err)
+		return "", fmt.Errorf("auth failure: %v", err)
 	}
 
-	auth0 := cli.Auth0{
-		Domain:       conf.Auth0.Domain,
-		ClientID:     conf.Cli.Auth0ClientID,
-		ClientSecret: conf.Cli.Auth0ClientSecret,
-		Audience:     conf.Cli.Auth0Audience,
-		Email:        conf.Cli.Email,
-		Password:     conf.Cli.Password,
+	return accessToken, nil
+}
+
+func (c *profileCommand) retrieve() (map[string]interface{}, string, error) {
+	accessToken, err := c.login(c.conf.Cli.Email)
+	if err != nil {
+		return nil, "", err
 	}
 
-	auth := cli.Auth{
-		IdentityURL: conf.IdentityURL,
-		PubKey:      cli.EncodePublicKey(ephemeralKey),
+	profile, err := c.client.RetrieveProfile(accessToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("error retrieving profile: %v", err)
 	}
 
-	accessToken, err := cli.ExecuteAuthFlow(&auth0, &auth)
+	return profile, accessToken, nil
+}
+
+func (c *profileCommand) store(accessToken string, profile map[string]interface{}) error {
+	encoded, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.StoreProfile(accessToken, strings.NewReader(string(encoded))); err != nil {
+		return fmt.Errorf("error storing profile: %v", err)
+	}
+
+	return nil
+}
+
+func (c *profileCommand) get(args []string) error {
+	profile, _, err := c.retrieve()
+	if err != nil {
+		return err
+	}
+
+	var value interface{} = profile
+
+	if len(args) > 0 {
+		value, err = cli.GetProfileField(profile, args[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	return printJSON(value)
+}
+
+func (c *profileCommand) setField(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: set-field <field> <value>")
+	}
+
+	profile, accessToken, err := c.retrieve()
+	if err != nil {
+		return err
+	}
+
+	if err := cli.SetProfileField(profile, args[0], cli.ParseFieldValue(args[1])); err != nil {
+		return err
+	}
+
+	return c.store(accessToken, profile)
+}
+
+func (c *profileCommand) storeStdin() error {
+	profile, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("error reading from stdin: %v", err)
+	}
+
+	accessToken, err := c.login(c.conf.Cli.Email)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.StoreProfile(accessToken, strings.NewReader(string(profile))); err != nil {
+		return fmt.Errorf("error storing profile: %v", err)
+	}
+
+	return nil
+}
+
+func (c *profileCommand) diff(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: diff <file>")
+	}
+
+	local, err := readProfile(args[0])
+	if err != nil {
+		return err
+	}
+
+	profile, _, err := c.retrieve()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range cli.DiffProfiles(profile, local) {
+		fmt.Println(d)
+	}
+
+	return nil
+}
+
+func (c *profileCommand) validate(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: validate <file>")
+	}
+
+	if c.client.Schema == nil {
+		return errors.New("missing --profileSchema")
+	}
+
+	profile, err := ioutil.ReadFile(args[0])
 	if err != nil {
-		log.Fatalf("auth failure: %v", err)
+		return err
+	}
+
+	if err := cli.ValidateProfile(c.client.Schema, profile); err != nil {
+		return err
+	}
+
+	fmt.Println("valid profile")
+
+	return nil
+}
+
+func (c *profileCommand) bulkSeed(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: bulk-seed <n> [template]")
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid number of identities: %s", args[0])
+	}
+
+	if !strings.Contains(c.conf.Cli.Email, "%d") {
+		return errors.New("--email has to be a pattern with a %d for bulk-seed")
+	}
+
+	template := map[string]interface{}{
+		"name":   "test",
+		"avatar": map[string]interface{}{"bodyShape": "dcl://base-avatars/BaseMale", "wearables": []interface{}{}},
 	}
 
-	client := cli.ProfileClient{
-		ProfileURL:   conf.ProfileURL,
-		EphemeralKey: ephemeralKey,
+	if len(args) == 2 {
+		template, err = readProfile(args[1])
+		if err != nil {
+			return err
+		}
 	}
 
-	if conf.Cli.Retrieve {
-		profile, err := client.RetrieveProfile(accessToken)
+	name, _ := template["name"].(string)
+	failed := 0
+
+	for i := 0; i < n; i++ {
+		email := fmt.Sprintf(c.conf.Cli.Email, i)
+
+		template["name"] = fmt.Sprintf("%s%d", name, i)
+
+		accessToken, err := c.login(email)
+		if err == nil {
+			err = c.store(accessToken, template)
+		}
+
 		if err != nil {
-			log.Fatalf("error retrieving profile: %v", err)
+			failed++
+			fmt.Printf("%s: %v\n", email, err)
+			continue
 		}
-		log.Println(profile)
-	} else {
-		builder := strings.Builder{}
-		reader := bufio.NewReader(os.Stdin)
-		scanner := bufio.NewScanner(reader)
-		for scanner.Scan() {
-			builder.WriteString(scanner.Text())
+
+		fmt.Printf("%s: stored\n", email)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d profiles failed", failed, n)
+	}
+
+	return nil
+}
+
+func readProfile(path string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := make(map[string]interface{})
+	if err := json.Unmarshal(content, &profile); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %v", path, err)
+	}
+
+	return profile, nil
+}
+
+func printJSON(v interface{}) error {
+	encoded, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(encoded))
+
+	return nil
+}
+
+func main() {
+	var conf rootConfig
+	if err := config.ReadConfiguration("config/config", &conf); err != nil {
+		log.Fatal(err)
+	}
+
+	args := flag.Args()
+
+	switch {
+	case conf.Cli.Store && conf.Cli.Retrieve:
+		log.Fatal("please specify --store or --retrieve")
+	case conf.Cli.Store:
+		args = append([]string{"store"}, args...)
+	case conf.Cli.Retrieve:
+		args = append([]string{"get"}, args...)
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "%s\n", usage)
+		os.Exit(2)
+	}
+
+	client := &cli.ProfileClient{ProfileURL: conf.ProfileURL}
+
+	if conf.Cli.ProfileSchema != "" {
+		schema, err := cli.LoadProfileSchema(conf.Cli.ProfileSchema)
+		if err != nil {
+			log.Fatal(err)
 		}
 
-		if err = scanner.Err(); err != nil {
-			log.Fatalf("error reading from stdin: %v", err)
+		client.Schema = schema
+	}
+
+	command := &profileCommand{conf: &conf, client: client}
+
+	if args[0] != "validate" {
+		if conf.Cli.KeyPath == "" {
+			log.Fatal("missing --keyPath")
 		}
 
-		profile := strings.NewReader(builder.String())
-		if err = client.StoreProfile(accessToken, profile); err != nil {
-			log.Fatalf("error storing profile: %v", err)
+		// store reads the profile from stdin, so the passphrase can't be prompted
+		ephemeralKey, err := cli.ReadEphemeralKeyFromFile(conf.Cli.KeyPath, &cli.PassphraseConfig{
+			File:   conf.Cli.KeyPassphraseFile,
+			Env:    conf.Cli.KeyPassphraseEnv,
+			Prompt: args[0] != "store",
+		})
+		if err != nil {
+			log.Fatalf("error loading ephemeral key: %v", err)
 		}
+
+		client.EphemeralKey = ephemeralKey
+	}
+
+	var err error
+
+	switch args[0] {
+	case "get":
+		err = command.get(args[1:])
+	case "set-field":
+		err = command.setField(args[1:])
+	case "store":
+		err = command.storeStdin()
+	case "diff":
+		err = command.diff(args[1:])
+	case "validate":
+		err = command.validate(args[1:])
+	case "bulk-seed":
+		err = command.bulkSeed(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "%s\n", usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
 	}
 }
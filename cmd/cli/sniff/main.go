@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	brokerAuth "github.com/decentraland/webrtc-broker/pkg/authentication"
+	"github.com/decentraland/world/internal/cli"
+	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/utils"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+type rootConfig struct {
+	CoordinatorURL string `overwrite-flag:"coordinatorURL" validate:"required"`
+	Sniff          struct {
+		LogLevel   string `overwrite-flag:"logLevel"`
+		Topics     string `overwrite-flag:"topics" flag-usage:"comma separated topics, overrides the parcel radius"`
+		CenterX    int    `overwrite-flag:"centerX" flag-usage:"center parcel x"`
+		CenterY    int    `overwrite-flag:"centerY" flag-usage:"center parcel y"`
+		Radius     int    `overwrite-flag:"radius" flag-usage:"radius in parcels"`
+		Format     string `overwrite-flag:"format" flag-usage:"text or ndjson"`
+		Categories string `overwrite-flag:"categories" flag-usage:"comma separated categories, e.g: POSITION,CHAT"`
+		Aliases    string `overwrite-flag:"aliases" flag-usage:"comma separated peer aliases"`
+		Text       string `overwrite-flag:"text" flag-usage:"only chat messages containing this text"`
+	}
+
+	Log logging.Config
+}
+
+func splitList(list string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func main() {
+	var conf rootConfig
+	if err := config.ReadConfiguration("config/config", &conf); err != nil {
+		log.Fatal(err)
+	}
+
+	log, closeLog, err := logging.New(conf.Log.LoggerConfig("sniff", conf.Sniff.LogLevel))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid log config")
+	}
+	defer closeLog()
+	defer logging.LogPanic(log)
+
+	subscription := make(map[string]bool)
+
+	topics := splitList(conf.Sniff.Topics)
+	if len(topics) > 0 {
+		for _, topic := range topics {
+			subscription[topic] = true
+		}
+	} else {
+		center := cli.ParcelCenter(conf.Sniff.CenterX, conf.Sniff.CenterY)
+		subscription = cli.ParcelTopics(center, conf.Sniff.Radius)
+	}
+
+	filter := cli.SniffFilter{
+		Categories: make(map[protocol.Category]bool),
+		Aliases:    make(map[uint64]bool),
+		Text:       conf.Sniff.Text,
+	}
+
+	for _, name := range splitList(conf.Sniff.Categories) {
+		category, ok := protocol.Category_value[strings.ToUpper(name)]
+		if !ok {
+			log.Fatal().Str("category", name).Msg("unknown category")
+		}
+
+		filter.Categories[protocol.Category(category)] = true
+	}
+
+	for _, value := range splitList(conf.Sniff.Aliases) {
+		alias, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid alias")
+		}
+
+		filter.Aliases[alias] = true
+	}
+
+	var ndjson bool
+
+	switch conf.Sniff.Format {
+	case "", "text":
+	case "ndjson":
+		ndjson = true
+	default:
+		log.Fatal().Str("format", conf.Sniff.Format).Msg("unknown format")
+	}
+
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	err = cli.StartSniffer(ctx, cli.SnifferOptions{
+		Auth:           &brokerAuth.NoopAuthenticator{},
+		CoordinatorURL: conf.CoordinatorURL,
+		Subscription:   subscription,
+		Filter:         filter,
+		NDJSON:         ndjson,
+		Output:         os.Stdout,
+		Log:            log,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("sniffer failed")
+	}
+}
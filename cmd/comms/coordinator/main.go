@@ -15,6 +15,8 @@ import (
 	"github.com/decentraland/world/internal/commons/config"
 	"github.com/decentraland/world/internal/commons/logging"
 	"github.com/decentraland/world/internal/commons/metrics"
+	"github.com/decentraland/world/internal/commons/supervisor"
+	"github.com/decentraland/world/internal/commons/tracing"
 	"github.com/decentraland/world/internal/commons/version"
 
 	zl "github.com/rs/zerolog/log"
@@ -24,6 +26,20 @@ type rootConfig struct {
 	IdentityURL    string `overwrite-flag:"authURL" validate:"required"`
 	CoordinatorURL string `overwrite-flag:"coordinatorURL" validate:"required"`
 
+	Log logging.Config
+
+	Tracing struct {
+		Exporter    string  `overwrite-flag:"traceExporter" flag-usage:"trace exporter: otlp or stdout, empty to disable"`
+		OTLPAddress string  `overwrite-flag:"traceOTLPAddress" flag-usage:"otlp collector address"`
+		SampleRatio float64 `overwrite-flag:"traceSampleRatio" flag-usage:"fraction of the new traces sampled"`
+	}
+
+	Supervisor struct {
+		Policy       string `overwrite-flag:"panicPolicy" flag-usage:"restart to restart the stateless goroutines after a panic, fail to exit on any panic"`
+		MaxRestarts  int    `overwrite-flag:"panicMaxRestarts" flag-usage:"consecutive restarts after which a goroutine panic exits, 0 for no limit"`
+		RestartDelay int    `overwrite-flag:"panicRestartDelay" flag-usage:"delay before restarting a goroutine after a panic, in seconds, doubled on each consecutive panic"`
+	}
+
 	Coordinator struct {
 		LogLevel string `overwrite-flag:"logLevel"`
 
@@ -49,14 +65,31 @@ func main() {
 		zl.Fatal().Err(err).Msg("cannot read config")
 	}
 
-	loggerConfig := logging.LoggerConfig{Level: conf.Coordinator.LogLevel}
-	log, err := logging.New(&loggerConfig)
+	loggerConfig := conf.Log.LoggerConfig("coordinator", conf.Coordinator.LogLevel)
+	loggerConfig.Cluster = conf.Coordinator.Metrics.Cluster
+
+	loggers, err := logging.NewLoggers(loggerConfig)
 	if err != nil {
-		log.Fatal().Msg("error setting log level")
+		zl.Fatal().Err(err).Msg("invalid log config")
 	}
+	defer loggers.Close()
 
+	log := loggers.Root()
 	defer logging.LogPanic(log)
 
+	if conf.Tracing.Exporter != "" {
+		tracer, err := tracing.NewProvider(&tracing.Config{
+			ServiceName: "coordinator",
+			Exporter:    conf.Tracing.Exporter,
+			OTLPAddress: conf.Tracing.OTLPAddress,
+			SampleRatio: conf.Tracing.SampleRatio,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot start tracing")
+		}
+		defer tracer.Close()
+	}
+
 	var authenticator brokerAuth.CoordinatorAuthenticator
 
 	if conf.Coordinator.AuthEnabled {
@@ -65,7 +98,7 @@ func main() {
 			CoordinatorURL: conf.CoordinatorURL,
 			Secret:         conf.Coordinator.ServerSecret,
 			RequestTTL:     conf.Coordinator.AuthTTL,
-			Log:            log,
+			Log:            loggers.Component("auth"),
 		})
 		if err != nil {
 			log.Fatal().Err(err).Msg("cannot build authenticator")
@@ -74,19 +107,22 @@ func main() {
 		authenticator = &brokerAuth.NoopAuthenticator{}
 	}
 
+	coordinatorLog := loggers.Component("coordinator")
+
 	config := coordinator.Config{
 		Auth:         authenticator,
-		Log:          &log,
+		Log:          &coordinatorLog,
 		ReportPeriod: 10 * time.Second,
 	}
 
-	if conf.Coordinator.Metrics.Enabled {
-		traceName := conf.Coordinator.Metrics.TraceName
-		versionTag := fmt.Sprintf("version:%s", version.Version())
-		clusterTag := fmt.Sprintf("cluster:%s", conf.Coordinator.Metrics.Cluster)
-		tags := []string{"env:local", versionTag, clusterTag}
+	var metricsClient *metrics.Client
 
-		metricsClient, err := metrics.NewClient(traceName, log)
+	versionTag := fmt.Sprintf("version:%s", version.Version())
+	clusterTag := fmt.Sprintf("cluster:%s", conf.Coordinator.Metrics.Cluster)
+	tags := []string{"env:local", versionTag, clusterTag}
+
+	if conf.Coordinator.Metrics.Enabled {
+		metricsClient, err = metrics.NewClient(conf.Coordinator.Metrics.TraceName, log)
 		if err != nil {
 			log.Fatal().Err(err).Msg("cannot start metrics agent")
 		}
@@ -110,21 +146,37 @@ func main() {
 		}
 	}
 
+	panicPolicy, err := supervisor.ParsePolicy(conf.Supervisor.Policy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid panic policy")
+	}
+
+	sup := supervisor.New(&supervisor.Config{
+		Policy:       panicPolicy,
+		RestartDelay: time.Duration(conf.Supervisor.RestartDelay) * time.Second,
+		MaxRestarts:  conf.Supervisor.MaxRestarts,
+		DDClient:     metricsClient,
+		Tags:         tags,
+		Log:          log,
+	})
+
 	state := coordinator.MakeState(&config)
 
-	go func() {
+	// NOTE: the http servers keep no state, they panic so a listen error goes through the policy
+	sup.Go("profiler", supervisor.Restart, func() {
 		addr := fmt.Sprintf("0.0.0.0:9081")
 		log.Info().Str("address", addr).Msg("Starting profiler")
-		log.Error().Err(http.ListenAndServe(addr, nil))
-	}()
+		panic(fmt.Errorf("profiler stopped: %v", http.ListenAndServe(addr, nil)))
+	})
 
-	go coordinator.Start(state)
+	sup.Go("coordinator", supervisor.Fail, func() {
+		coordinator.Start(state)
+	})
 
-	go func() {
+	sup.Go("api", supervisor.Restart, func() {
 		versionResponse, err := json.Marshal(map[string]string{"version": version.Version()})
 		if err != nil {
-			log.Fatal().Err(err).Msg("invalid version")
-			return
+			panic(err)
 		}
 
 		mux := http.NewServeMux()
@@ -138,13 +190,13 @@ func main() {
 		})
 		addr := fmt.Sprintf("%s:%d", conf.Coordinator.Host, conf.Coordinator.APIPort)
 		log.Info().Str("address", addr).Msg("Starting HTTP API")
-		log.Fatal().Err(http.ListenAndServe(addr, mux)).Msg("")
-	}()
+		panic(fmt.Errorf("http api stopped: %v", http.ListenAndServe(addr, mux)))
+	})
 
 	mux := http.NewServeMux()
 	coordinator.Register(state, mux)
 
 	addr := fmt.Sprintf("%s:%d", conf.Coordinator.Host, conf.Coordinator.Port)
 	log.Info().Str("addr", addr).Str("version", version.Version()).Msg("starting coordinator")
-	log.Fatal().Err(http.ListenAndServe(addr, mux)).Msg("")
+	log.Fatal().Err(http.ListenAndServe(addr, tracing.Handler(mux))).Msg("")
 }
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
-
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/utils"
 	"github.com/decentraland/world/internal/commtest"
 )
 
@@ -20,10 +22,12 @@ type rootConfig struct {
 		SpawnObserver bool `overwrite-flag:"observer"`
 		Duration      int  `overwrite-flag:"duration" flag-usage:"duration in seconds"`
 	}
+
+	Log logging.Config
 }
 
-func newLogger(name string) zerolog.Logger {
-	return zerolog.New(os.Stdout).Level(zerolog.InfoLevel).With().Timestamp().Str("name", name).Logger()
+func newLogger(loggers *logging.Loggers, name string) zerolog.Logger {
+	return loggers.Component("bot").With().Str("name", name).Logger()
 }
 
 func main() {
@@ -34,10 +38,35 @@ func main() {
 
 	fmt.Println("starting test: ", conf.CoordinatorURL)
 
+	loggers, err := logging.NewLoggers(conf.Log.LoggerConfig("densetest", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer loggers.Close()
+
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	if conf.DenseTest.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(conf.DenseTest.Duration)*time.Second)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+
+	startBot := func(opts commtest.Options) {
+		defer wg.Done()
+
+		if err := commtest.StartBot(ctx, opts); err != nil {
+			opts.Log.Error().Err(err).Msg("bot failed")
+		}
+	}
+
 	for i := 0; i < conf.DenseTest.NBots; i++ {
-		log := newLogger(fmt.Sprintf("client-%d", i))
+		log := newLogger(loggers, fmt.Sprintf("client-%d", i))
 
-		go commtest.StartBot(commtest.Options{
+		wg.Add(1)
+		go startBot(commtest.Options{
 			CoordinatorURL: conf.CoordinatorURL,
 			Topic:          "testtopic",
 			Subscription:   map[string]bool{"testtopic": true},
@@ -46,23 +75,18 @@ func main() {
 		})
 	}
 
-	if conf.DenseTest.Duration > 0 {
-		go func() {
-			time.Sleep(time.Duration(conf.DenseTest.Duration) * time.Second)
-			os.Exit(0)
-		}()
-	}
-
 	if conf.DenseTest.SpawnObserver {
-		log := newLogger("observer")
-		commtest.StartBot(commtest.Options{
+		log := newLogger(loggers, "observer")
+
+		wg.Add(1)
+		go startBot(commtest.Options{
 			CoordinatorURL: conf.CoordinatorURL,
 			Topic:          "testtopic",
 			Subscription:   map[string]bool{"testtopic": true},
 			TrackStats:     true,
 			Log:            log,
 		})
-	} else {
-		select {}
 	}
+
+	wg.Wait()
 }
@@ -1,11 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"math/rand"
-	"os"
-
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -13,6 +12,9 @@ import (
 	brokerAuth "github.com/decentraland/webrtc-broker/pkg/authentication"
 	"github.com/decentraland/world/internal/cli"
 	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/tracing"
+	"github.com/decentraland/world/internal/commons/utils"
 )
 
 type rootConfig struct {
@@ -22,14 +24,27 @@ type rootConfig struct {
 		SpawnObserver bool `overwrite-flag:"observer"`
 		Duration      int  `overwrite-flag:"duration" flag-usage:"duration in seconds"`
 
-		CenterX int `overwrite-flag:"centerX"`
-		CenterY int `overwrite-flag:"centerY"`
+		CenterX int `overwrite-flag:"centerX" flag-usage:"center parcel x, in parcels, not meters"`
+		CenterY int `overwrite-flag:"centerY" flag-usage:"center parcel y, in parcels, not meters"`
 		Radius  int `overwrite-flag:"radius" flag-usage:"radius in parcels"`
+
+		BatchFlushInterval int `overwrite-flag:"batchFlushInterval" flag-usage:"batch flush interval in milliseconds, 0 to disable"`
+		BatchMaxSize       int `overwrite-flag:"batchMaxSize" flag-usage:"max batch size in bytes, up to 1024"`
+
+		Movement cli.MovementConfig
+	}
+
+	Log logging.Config
+
+	Tracing struct {
+		Exporter    string  `overwrite-flag:"traceExporter" flag-usage:"trace exporter: otlp or stdout, empty to disable"`
+		OTLPAddress string  `overwrite-flag:"traceOTLPAddress" flag-usage:"otlp collector address"`
+		SampleRatio float64 `overwrite-flag:"traceSampleRatio" flag-usage:"fraction of the new traces sampled"`
 	}
 }
 
-func newLogger(name string) zerolog.Logger {
-	return zerolog.New(os.Stdout).Level(zerolog.InfoLevel).With().Timestamp().Str("name", name).Logger()
+func newLogger(loggers *logging.Loggers, name string) zerolog.Logger {
+	return loggers.Component("bot").With().Str("name", name).Logger()
 }
 
 func main() {
@@ -40,62 +55,119 @@ func main() {
 
 	fmt.Println("starting test: ", conf.CoordinatorURL)
 
+	loggers, err := logging.NewLoggers(conf.Log.LoggerConfig("realistictest", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer loggers.Close()
+
+	if conf.Tracing.Exporter != "" {
+		tracer, err := tracing.NewProvider(&tracing.Config{
+			ServiceName: "realistictest",
+			Exporter:    conf.Tracing.Exporter,
+			OTLPAddress: conf.Tracing.OTLPAddress,
+			SampleRatio: conf.Tracing.SampleRatio,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer tracer.Close()
+	}
+
 	auth := &brokerAuth.NoopAuthenticator{}
 
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	if conf.RealisticTest.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(conf.RealisticTest.Duration)*time.Second)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+
+	batchFlushInterval := time.Duration(conf.RealisticTest.BatchFlushInterval) * time.Millisecond
+
+	startBot := func(opts *cli.BotOptions) {
+		defer wg.Done()
+
+		if err := cli.StartBot(ctx, opts); err != nil {
+			opts.Log.Error().Err(err).Msg("bot failed")
+		}
+	}
+
+	center := cli.ParcelCenter(conf.RealisticTest.CenterX, conf.RealisticTest.CenterY)
+	movement, err := conf.RealisticTest.Movement.Options(center, conf.RealisticTest.Radius)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// NOTE: in follow mode the first bot walks randomly and every other bot follows the previous one
+	var leader cli.PositionProvider
+
 	for i := 0; i < conf.RealisticTest.NBots; i++ {
-		log := newLogger(fmt.Sprintf("client-%d", i))
+		log := newLogger(loggers, fmt.Sprintf("client-%d", i))
 
-		var checkpoints [6]cli.V3
+		botMovement := *movement
+		botMovement.Checkpoints = movement.RandomCheckpoints(6)
 
-		for i := 0; i < len(checkpoints); i++ {
-			p := &checkpoints[i]
+		if botMovement.Mode == cli.FollowMovement {
+			if leader == nil {
+				botMovement.Mode = cli.RandomWalkMovement
+			} else {
+				botMovement.Leader = leader
+			}
+		}
 
-			p.X = float64(conf.RealisticTest.CenterX + rand.Intn(10)*conf.RealisticTest.Radius*2 - conf.RealisticTest.Radius)
-			p.Y = 1.6
-			p.Z = float64(conf.RealisticTest.CenterY + rand.Intn(10)*conf.RealisticTest.Radius*2 - conf.RealisticTest.Radius)
+		avatar, err := cli.NewAvatar(&botMovement)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create avatar")
 		}
 
+		leader = avatar
+
 		opts := cli.BotOptions{
 			CoordinatorURL: conf.CoordinatorURL,
 			Auth:           auth,
-			Checkpoints:    checkpoints[:],
-			DurationMs:     10000,
+			Avatar:         avatar,
 			TrackStats:     false,
 			Log:            log,
-		}
 
-		go cli.StartBot(&opts)
-	}
+			BatchFlushInterval: batchFlushInterval,
+			BatchMaxSize:       conf.RealisticTest.BatchMaxSize,
+		}
 
-	if conf.RealisticTest.Duration > 0 {
-		go func() {
-			time.Sleep(time.Duration(conf.RealisticTest.Duration) * time.Second)
-			os.Exit(0)
-		}()
+		wg.Add(1)
+		go startBot(&opts)
 	}
 
 	if conf.RealisticTest.SpawnObserver {
-		log := newLogger("observer")
-
-		var checkpoints [6]cli.V3
-		for i := 0; i < len(checkpoints); i++ {
-			p := &checkpoints[i]
-			p.X = float64(conf.RealisticTest.CenterX)
-			p.Y = 1.6
-			p.Z = float64(conf.RealisticTest.CenterY)
+		log := newLogger(loggers, "observer")
+
+		// NOTE: a random walk with no radius keeps the observer still at the center
+		avatar, err := cli.NewAvatar(&cli.MovementOptions{
+			Mode:   cli.RandomWalkMovement,
+			Center: center,
+			Speed:  movement.Speed,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create avatar")
 		}
 
 		opts := cli.BotOptions{
 			CoordinatorURL: conf.CoordinatorURL,
 			Auth:           auth,
-			Checkpoints:    checkpoints[:],
-			DurationMs:     10000,
+			Avatar:         avatar,
 			TrackStats:     true,
 			Log:            log,
+
+			BatchFlushInterval: batchFlushInterval,
+			BatchMaxSize:       conf.RealisticTest.BatchMaxSize,
 		}
 
-		cli.StartBot(&opts)
-	} else {
-		select {}
+		wg.Add(1)
+		go startBot(&opts)
 	}
+
+	wg.Wait()
 }
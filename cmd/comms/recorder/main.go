@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/decentraland/world/internal/cli"
+	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/utils"
+	"github.com/decentraland/world/internal/commtest"
+)
+
+type rootConfig struct {
+	CoordinatorURL string `overwrite-flag:"coordinatorURL" validate:"required"`
+	Recorder       struct {
+		Output  string `overwrite-flag:"output" flag-usage:"recording file path" validate:"required"`
+		Topics  string `overwrite-flag:"topics" flag-usage:"comma separated topics, overrides the parcel radius"`
+		CenterX int    `overwrite-flag:"centerX" flag-usage:"center parcel x"`
+		CenterY int    `overwrite-flag:"centerY" flag-usage:"center parcel y"`
+		Radius  int    `overwrite-flag:"radius" flag-usage:"radius in parcels"`
+	}
+
+	Log logging.Config
+}
+
+func main() {
+	var conf rootConfig
+	if err := config.ReadConfiguration("config/config", &conf); err != nil {
+		log.Fatal(err)
+	}
+
+	loggers, err := logging.NewLoggers(conf.Log.LoggerConfig("recorder", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer loggers.Close()
+
+	subscription := make(map[string]bool)
+
+	if conf.Recorder.Topics != "" {
+		for _, topic := range strings.Split(conf.Recorder.Topics, ",") {
+			subscription[strings.TrimSpace(topic)] = true
+		}
+	} else {
+		center := cli.ParcelCenter(conf.Recorder.CenterX, conf.Recorder.CenterY)
+		subscription = cli.ParcelTopics(center, conf.Recorder.Radius)
+	}
+
+	file, err := os.Create(conf.Recorder.Output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	fmt.Println("recording to: ", conf.Recorder.Output)
+
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	err = commtest.StartRecorder(ctx, commtest.RecorderOptions{
+		CoordinatorURL: conf.CoordinatorURL,
+		Subscription:   subscription,
+		Output:         file,
+		Log:            loggers.Root(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/utils"
+	"github.com/decentraland/world/internal/commtest"
+)
+
+type rootConfig struct {
+	CoordinatorURL string `overwrite-flag:"coordinatorURL" validate:"required"`
+	Replayer       struct {
+		Input string  `overwrite-flag:"input" flag-usage:"recording file path" validate:"required"`
+		Speed float64 `overwrite-flag:"speed" flag-usage:"time scale, 2 replays twice as fast"`
+	}
+
+	Log logging.Config
+}
+
+func main() {
+	var conf rootConfig
+	if err := config.ReadConfiguration("config/config", &conf); err != nil {
+		log.Fatal(err)
+	}
+
+	loggers, err := logging.NewLoggers(conf.Log.LoggerConfig("replayer", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer loggers.Close()
+
+	file, err := os.Open(conf.Replayer.Input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	fmt.Println("replaying: ", conf.Replayer.Input)
+
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	err = commtest.Replay(ctx, commtest.ReplayOptions{
+		CoordinatorURL: conf.CoordinatorURL,
+		Input:          file,
+		Speed:          conf.Replayer.Speed,
+		Log:            loggers.Root(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
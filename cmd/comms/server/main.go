@@ -3,8 +3,14 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	_ "net/http/pprof"
@@ -16,8 +22,11 @@ import (
 	"github.com/decentraland/world/internal/commons/config"
 	"github.com/decentraland/world/internal/commons/logging"
 	"github.com/decentraland/world/internal/commons/metrics"
+	"github.com/decentraland/world/internal/commons/supervisor"
+	"github.com/decentraland/world/internal/commons/tracing"
 	"github.com/decentraland/world/internal/commons/version"
 	"github.com/decentraland/world/internal/commserver"
+	"github.com/decentraland/world/internal/commserver/statsstore"
 	_ "github.com/lib/pq"
 	pion "github.com/pion/webrtc/v2"
 	"github.com/rs/zerolog"
@@ -28,6 +37,21 @@ type rootConfig struct {
 	IdentityURL    string `overwrite-flag:"authURL" validate:"required"`
 	CoordinatorURL string `overwrite-flag:"coordinatorURL" flag-usage:"coordinator url" validate:"required"`
 
+	Log logging.Config
+
+	Tracing struct {
+		Exporter           string  `overwrite-flag:"traceExporter" flag-usage:"trace exporter: otlp or stdout, empty to disable"`
+		OTLPAddress        string  `overwrite-flag:"traceOTLPAddress" flag-usage:"otlp collector address"`
+		SampleRatio        float64 `overwrite-flag:"traceSampleRatio" flag-usage:"fraction of the new traces sampled"`
+		ForwardSampleRatio float64 `overwrite-flag:"traceForwardSampleRatio" flag-usage:"fraction of the forwarded messages traced, before the trace sampling"`
+	}
+
+	Supervisor struct {
+		Policy       string `overwrite-flag:"panicPolicy" flag-usage:"restart to restart the stateless goroutines after a panic, fail to exit on any panic"`
+		MaxRestarts  int    `overwrite-flag:"panicMaxRestarts" flag-usage:"consecutive restarts after which a goroutine panic exits, 0 for no limit"`
+		RestartDelay int    `overwrite-flag:"panicRestartDelay" flag-usage:"delay before restarting a goroutine after a panic, in seconds, doubled on each consecutive panic"`
+	}
+
 	CommServer struct {
 		LogLevel string `overwrite-flag:"logLevel"`
 
@@ -40,6 +64,44 @@ type rootConfig struct {
 
 		MaxPeers int `overwrite-flag:"maxPeers"`
 
+		BatchFlushInterval int `overwrite-flag:"batchFlushInterval" flag-usage:"forwarded messages batch flush interval in milliseconds, 0 to disable"`
+		BatchMaxSize       int `overwrite-flag:"batchMaxSize" flag-usage:"max forwarded messages batch size in bytes, up to 1024"`
+
+		Chat struct {
+			HistorySize   int `overwrite-flag:"chatHistorySize" flag-usage:"max chat messages kept per topic"`
+			HistoryMaxAge int `overwrite-flag:"chatHistoryMaxAge" flag-usage:"max age of the chat messages kept, in minutes"`
+
+			MaxLength        int    `overwrite-flag:"chatMaxLength" flag-usage:"max chat message length, 0 to disable"`
+			BannedWordsPath  string `overwrite-flag:"chatBannedWordsPath" flag-usage:"file with a banned word per line"`
+			RepetitionWindow int    `overwrite-flag:"chatRepetitionWindow" flag-usage:"repetition spam window, in seconds"`
+			MaxRepetitions   int    `overwrite-flag:"chatMaxRepetitions" flag-usage:"times the same text can be sent in the repetition window, 0 to disable"`
+		}
+
+		Profile struct {
+			CacheMaxAge int `overwrite-flag:"profileCacheMaxAge" flag-usage:"how long a profile announcement is cached, in seconds"`
+		}
+
+		Interest struct {
+			Enabled           bool    `overwrite-flag:"interestEnabled" flag-usage:"filter the positions by distance"`
+			NearDistance      float64 `overwrite-flag:"interestNearDistance" flag-usage:"distance up to which positions are delivered at full rate, in meters"`
+			FarDistance       float64 `overwrite-flag:"interestFarDistance" flag-usage:"distance beyond which positions are not delivered, in meters"`
+			FarUpdateInterval int     `overwrite-flag:"interestFarUpdateInterval" flag-usage:"min interval between far positions, in milliseconds"`
+			StalePosition     int     `overwrite-flag:"interestStalePosition" flag-usage:"how long a recipient position is used to filter, in milliseconds, older positions get every update"`
+		}
+
+		CellStats struct {
+			Enabled  bool `overwrite-flag:"cellStatsEnabled" flag-usage:"count the traffic delivered to the local peers by sender cell and category"`
+			MaxCells int  `overwrite-flag:"cellStatsMaxCells" flag-usage:"max cells counted in a report period, the rest are counted as other"`
+			TopK     int  `overwrite-flag:"cellStatsTopK" flag-usage:"number of busiest cells and categories reported as metrics"`
+		}
+
+		Heatmap struct {
+			Enabled        bool `overwrite-flag:"heatmapEnabled" flag-usage:"count the active users per parcel and cell"`
+			Window         int  `overwrite-flag:"heatmapWindow" flag-usage:"how long a user counts as active in its last position, in seconds"`
+			TopCells       int  `overwrite-flag:"heatmapTopCells" flag-usage:"number of busiest cells reported as metrics"`
+			SnapshotPeriod int  `overwrite-flag:"heatmapSnapshotPeriod" flag-usage:"heatmap snapshots period in the stats store, in minutes, 0 to disable"`
+		}
+
 		Metrics struct {
 			Cluster string `overwrite-flag:"cluster"`
 
@@ -53,23 +115,213 @@ type rootConfig struct {
 			StatsDBUser     string `overwrite-flag:"statsDBUser"`
 			StatsDBPassword string `overwrite-flag:"statsDBPassword"`
 
+			StatsStore              string `overwrite-flag:"statsStore" flag-usage:"stats store: postgres, sqlite or jsonl"`
+			StatsMigrate            bool   `overwrite-flag:"statsMigrate" flag-usage:"apply the pending stats db migrations on start"`
+			StatsStorePath          string `overwrite-flag:"statsStorePath" flag-usage:"sqlite or jsonl stats file"`
+			StatsMaxAge             int    `overwrite-flag:"statsMaxAge" flag-usage:"how long the stats are kept, in hours, 0 to keep them"`
+			StatsDownsampleAfter    int    `overwrite-flag:"statsDownsampleAfter" flag-usage:"age after which the stats are downsampled, in hours, 0 to disable"`
+			StatsDownsampleInterval int    `overwrite-flag:"statsDownsampleInterval" flag-usage:"downsampled stats interval, in minutes"`
+			StatsQueueSize          int    `overwrite-flag:"statsQueueSize" flag-usage:"max stats batches waiting to be written"`
+			StatsMaxRetries         int    `overwrite-flag:"statsMaxRetries" flag-usage:"how many times a failed stats write is retried"`
+			StatsSpillPath          string `overwrite-flag:"statsSpillPath" flag-usage:"file where the stats are kept while the store fails, empty to drop them"`
+			StatsSpillMaxSize       int    `overwrite-flag:"statsSpillMaxSize" flag-usage:"size at which the stats spill file is full, in megabytes"`
+
+			SessionEvents     string `overwrite-flag:"sessionEvents" flag-usage:"session events sink: postgres, ndjson or stdout, empty to disable"`
+			SessionEventsPath string `overwrite-flag:"sessionEventsPath" flag-usage:"ndjson session events file"`
+
+			ShortReportPeriod int `overwrite-flag:"shortReportPeriod" flag-usage:"metrics report period, in seconds"`
+			LongReportPeriod  int `overwrite-flag:"longReportPeriod" flag-usage:"peer stats store period, in minutes"`
+
 			DebugEnabled bool `overwrite-flag:"debugMetrics" flag-usage:"enable debug metrics"`
 		}
 	}
 }
 
+func openPostgres(conf *rootConfig) (*sql.DB, error) {
+	metricsConf := conf.CommServer.Metrics
+
+	psqlConn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		metricsConf.StatsDBHost,
+		metricsConf.StatsDBPort,
+		metricsConf.StatsDBUser,
+		metricsConf.StatsDBPassword,
+		metricsConf.StatsDBName)
+	db, err := sql.Open("postgres", psqlConn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func openStatsStore(conf *rootConfig) (statsstore.StatsStore, error) {
+	metricsConf := conf.CommServer.Metrics
+
+	switch metricsConf.StatsStore {
+	case "", "postgres":
+		db, err := openPostgres(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := statsstore.NewPostgresStore(db, metricsConf.StatsMigrate)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		return store, nil
+	case "sqlite":
+		return statsstore.NewSQLiteStore(metricsConf.StatsStorePath, metricsConf.StatsMigrate)
+	case "jsonl":
+		return statsstore.NewJSONLinesStore(metricsConf.StatsStorePath)
+	default:
+		return nil, fmt.Errorf("unknown stats store %s", metricsConf.StatsStore)
+	}
+}
+
+func openSessionSink(conf *rootConfig) (statsstore.SessionSink, error) {
+	metricsConf := conf.CommServer.Metrics
+
+	switch metricsConf.SessionEvents {
+	case "postgres":
+		db, err := openPostgres(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		sink, err := statsstore.NewPostgresSessionSink(db, metricsConf.StatsMigrate)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		return sink, nil
+	case "ndjson":
+		return statsstore.OpenNDJSONSessionSink(metricsConf.SessionEventsPath)
+	case "stdout":
+		return statsstore.NewNDJSONSessionSink(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown session events sink %s", metricsConf.SessionEvents)
+	}
+}
+
+// migrateStatsDB runs the migrate command: up, down <version> or version
+func migrateStatsDB(conf *rootConfig, args []string) error {
+	var db *sql.DB
+	var migrator *statsstore.Migrator
+	var err error
+
+	switch conf.CommServer.Metrics.StatsStore {
+	case "", "postgres":
+		db, err = openPostgres(conf)
+		if err != nil {
+			return err
+		}
+
+		migrator = statsstore.NewPostgresMigrator(db)
+	case "sqlite":
+		db, err = statsstore.OpenSQLite(conf.CommServer.Metrics.StatsStorePath)
+		if err != nil {
+			return err
+		}
+
+		migrator = statsstore.NewSQLiteMigrator(db)
+	default:
+		return fmt.Errorf("the %s stats store has no schema", conf.CommServer.Metrics.StatsStore)
+	}
+	defer db.Close()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		if len(args) != 2 {
+			return errors.New("usage: migrate down <version>")
+		}
+
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %s", args[1])
+		}
+
+		if err := migrator.Down(version); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate command %s, valid commands are up, down <version> and version", command)
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("stats db schema version %d, latest %d\n", version, migrator.Latest())
+
+	return nil
+}
+
 func main() {
 	var conf rootConfig
 	if err := config.ReadConfiguration("config/config", &conf); err != nil {
 		zl.Fatal().Err(err).Msg("invalid config")
 	}
 
-	log, err := logging.New(&logging.LoggerConfig{Level: conf.CommServer.LogLevel})
+	loggerConfig := conf.Log.LoggerConfig("commserver", conf.CommServer.LogLevel)
+	loggerConfig.Cluster = conf.CommServer.Metrics.Cluster
+
+	loggers, err := logging.NewLoggers(loggerConfig)
 	if err != nil {
-		log.Fatal().Msg("error setting log level")
+		zl.Fatal().Err(err).Msg("invalid log config")
 	}
+	defer loggers.Close()
+
+	log := loggers.Root()
 	defer logging.LogPanic(log)
 
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatal().Msgf("unknown command %s", args[0])
+		}
+
+		if err := migrateStatsDB(&conf, args[1:]); err != nil {
+			log.Fatal().Err(err).Msg("stats db migration failed")
+		}
+
+		return
+	}
+
+	// NOTE: the server only stops on a signal or a fatal error, so the deferred calls never run,
+	// closers are called on SIGINT and SIGTERM instead, in reverse order
+	var closers []func()
+
+	if conf.Tracing.Exporter != "" {
+		tracer, err := tracing.NewProvider(&tracing.Config{
+			ServiceName: "commserver",
+			Exporter:    conf.Tracing.Exporter,
+			OTLPAddress: conf.Tracing.OTLPAddress,
+			SampleRatio: conf.Tracing.SampleRatio,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot start tracing")
+		}
+		closers = append(closers, func() { tracer.Close() })
+	}
+
 	var authenticator brokerAuth.ServerAuthenticator
 
 	if conf.CommServer.AuthEnabled {
@@ -77,7 +329,7 @@ func main() {
 			IdentityURL: conf.IdentityURL,
 			Secret:      conf.CommServer.ServerSecret,
 			RequestTTL:  conf.CommServer.AuthTTL,
-			Log:         log,
+			Log:         loggers.Component("auth"),
 		})
 		if err != nil {
 			log.Fatal().Err(err).Msg("cannot build authenticator")
@@ -86,10 +338,107 @@ func main() {
 		authenticator = &brokerAuth.NoopAuthenticator{}
 	}
 
+	var ddClient *metrics.Client
+
+	if conf.CommServer.Metrics.DDEnabled {
+		ddClient, err = metrics.NewClient(conf.CommServer.Metrics.TraceName, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot start metrics agent")
+		}
+		closers = append(closers, ddClient.Close)
+	}
+
+	panicPolicy, err := supervisor.ParsePolicy(conf.Supervisor.Policy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid panic policy")
+	}
+
+	sup := supervisor.New(&supervisor.Config{
+		Policy:       panicPolicy,
+		RestartDelay: time.Duration(conf.Supervisor.RestartDelay) * time.Second,
+		MaxRestarts:  conf.Supervisor.MaxRestarts,
+		DDClient:     ddClient,
+		Tags:         commserver.MetricTags(conf.CommServer.Metrics.Cluster),
+		Log:          log,
+	})
+
+	mutes := commserver.NewMuteList()
+	chatFilters := []commserver.ChatFilter{mutes}
+
+	if conf.CommServer.Chat.MaxLength > 0 {
+		chatFilters = append(chatFilters, &commserver.MaxLengthFilter{MaxLength: conf.CommServer.Chat.MaxLength})
+	}
+
+	if conf.CommServer.Chat.BannedWordsPath != "" {
+		words, err := commserver.ReadWordList(conf.CommServer.Chat.BannedWordsPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot read banned words")
+		}
+
+		chatFilters = append(chatFilters, commserver.NewBannedWordsFilter(words))
+	}
+
+	if conf.CommServer.Chat.MaxRepetitions > 0 {
+		window := time.Duration(conf.CommServer.Chat.RepetitionWindow) * time.Second
+		chatFilters = append(chatFilters, commserver.NewRepetitionFilter(window, conf.CommServer.Chat.MaxRepetitions))
+	}
+
+	pipelineLog := loggers.Component("pipeline")
+
+	moderator := commserver.NewModerator(&commserver.ModeratorConfig{
+		Filters:  chatFilters,
+		DDClient: ddClient,
+		Tags:     commserver.MetricTags(conf.CommServer.Metrics.Cluster),
+		Log:      pipelineLog,
+	})
+
+	pipeline := commserver.NewPipeline(&commserver.PipelineConfig{
+		BatchFlushInterval: time.Duration(conf.CommServer.BatchFlushInterval) * time.Millisecond,
+		BatchMaxSize:       conf.CommServer.BatchMaxSize,
+		Log:                pipelineLog,
+	})
+	pipeline.Use(commserver.NewDirectMessageRouter(pipelineLog))
+	pipeline.Use(moderator)
+	pipeline.Use(commserver.NewChatHistory(&commserver.ChatHistoryConfig{
+		MaxMessages: conf.CommServer.Chat.HistorySize,
+		MaxAge:      time.Duration(conf.CommServer.Chat.HistoryMaxAge) * time.Minute,
+		Log:         pipelineLog,
+	}))
+
+	pipeline.Use(commserver.NewProfileCache(&commserver.ProfileCacheConfig{
+		MaxAge: time.Duration(conf.CommServer.Profile.CacheMaxAge) * time.Second,
+		Log:    pipelineLog,
+	}))
+
+	var interest *commserver.InterestManager
+
+	if conf.CommServer.Interest.Enabled {
+		interest = commserver.NewInterestManager(&commserver.InterestConfig{
+			NearDistance:      conf.CommServer.Interest.NearDistance,
+			FarDistance:       conf.CommServer.Interest.FarDistance,
+			FarUpdateInterval: time.Duration(conf.CommServer.Interest.FarUpdateInterval) * time.Millisecond,
+			StalePosition:     time.Duration(conf.CommServer.Interest.StalePosition) * time.Millisecond,
+			Log:               pipelineLog,
+		})
+		pipeline.Use(interest)
+	}
+
+	var heatmap *commserver.Heatmap
+
+	if conf.CommServer.Heatmap.Enabled {
+		heatmap = commserver.NewHeatmap(&commserver.HeatmapConfig{
+			Window: time.Duration(conf.CommServer.Heatmap.Window) * time.Second,
+			Log:    pipelineLog,
+		})
+		pipeline.Use(heatmap)
+	}
+
+	brokerLog := loggers.Component("broker")
+
 	config := broker.Config{
 		Role: protocol.Role_COMMUNICATION_SERVER,
 		Auth: authenticator,
-		Log:  &log,
+		Log:  &brokerLog,
 		ICEServers: []pion.ICEServer{
 			{
 				URLs: []string{"stun:stun.l.google.com:19302"},
@@ -99,44 +448,116 @@ func main() {
 		MaxPeers:               uint16(conf.CommServer.MaxPeers),
 		ExitOnCoordinatorClose: true,
 		WebRtcLogLevel:         zerolog.WarnLevel,
+
+		ReliableWriterControllerFactory:   pipeline.ReliableWriterControllerFactory,
+		UnreliableWriterControllerFactory: pipeline.UnreliableWriterControllerFactory,
 	}
 
-	reportConfig := commserver.ReporterConfig{
-		LongReportPeriod: 10 * time.Minute,
-		Log:              log,
-		Cluster:          conf.CommServer.Metrics.Cluster,
-		DebugModeEnabled: conf.CommServer.Metrics.DebugEnabled,
+	var cellStats *commserver.CellStats
+
+	// NOTE: registered last, to count and trace only the delivered messages
+	if conf.CommServer.CellStats.Enabled {
+		cellStats = commserver.NewCellStats(&commserver.CellStatsConfig{
+			MaxCells: conf.CommServer.CellStats.MaxCells,
+			Log:      pipelineLog,
+		})
+		pipeline.Use(cellStats)
 	}
 
-	if conf.CommServer.Metrics.DDEnabled {
-		client, err := metrics.NewClient(conf.CommServer.Metrics.TraceName, log)
-		if err != nil {
-			log.Fatal().Err(err).Msg("cannot start metrics agent")
-		}
-		defer client.Close()
+	if conf.Tracing.Exporter != "" && conf.Tracing.ForwardSampleRatio > 0 {
+		pipeline.Use(commserver.NewForwardTracer(&commserver.ForwardTracerConfig{
+			SampleRatio: conf.Tracing.ForwardSampleRatio,
+		}))
+	}
 
-		reportConfig.DDClient = client
+	reportConfig := commserver.ReporterConfig{
+		ShortReportPeriod: time.Duration(conf.CommServer.Metrics.ShortReportPeriod) * time.Second,
+		LongReportPeriod:  time.Duration(conf.CommServer.Metrics.LongReportPeriod) * time.Minute,
+		Log:               loggers.Component("reporter"),
+		Cluster:           conf.CommServer.Metrics.Cluster,
+		DebugModeEnabled:  conf.CommServer.Metrics.DebugEnabled,
+		DDClient:          ddClient,
+		Interest:          interest,
+		Heatmap:           heatmap,
+		HeatmapTopCells:   conf.CommServer.Heatmap.TopCells,
+		CellStats:         cellStats,
+		CellStatsTopK:     conf.CommServer.CellStats.TopK,
 	}
 
 	if conf.CommServer.Metrics.DBEnabled {
-		psqlConn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-			conf.CommServer.Metrics.StatsDBHost,
-			conf.CommServer.Metrics.StatsDBPort,
-			conf.CommServer.Metrics.StatsDBUser,
-			conf.CommServer.Metrics.StatsDBPassword,
-			conf.CommServer.Metrics.StatsDBName)
-		db, err := sql.Open("postgres", psqlConn)
+		store, err := openStatsStore(&conf)
 		if err != nil {
-			log.Fatal().Err(err).Msg("cannot open postgresql connection")
+			log.Fatal().Err(err).Msg("cannot open the stats store")
 		}
-		defer db.Close()
+		closers = append(closers, func() { store.Close() })
 
-		err = db.Ping()
+		retention := statsstore.NewRetention(store, &statsstore.RetentionConfig{
+			MaxAge:             time.Duration(conf.CommServer.Metrics.StatsMaxAge) * time.Hour,
+			DownsampleAfter:    time.Duration(conf.CommServer.Metrics.StatsDownsampleAfter) * time.Hour,
+			DownsampleInterval: time.Duration(conf.CommServer.Metrics.StatsDownsampleInterval) * time.Minute,
+			Log:                loggers.Component("stats"),
+		})
+		sup.Go("stats_retention", supervisor.Restart, retention.Run)
+
+		writer, err := statsstore.NewWriter(store, &statsstore.WriterConfig{
+			QueueSize:    conf.CommServer.Metrics.StatsQueueSize,
+			MaxRetries:   conf.CommServer.Metrics.StatsMaxRetries,
+			SpillPath:    conf.CommServer.Metrics.StatsSpillPath,
+			SpillMaxSize: int64(conf.CommServer.Metrics.StatsSpillMaxSize) * 1024 * 1024,
+			Log:          loggers.Component("stats"),
+		})
 		if err != nil {
-			log.Fatal().Err(err).Msg("cannot open postgresql connection")
+			log.Fatal().Err(err).Msg("cannot create the stats writer")
 		}
+		closers = append(closers, writer.Close)
+		sup.Go("stats_writer", supervisor.Fail, writer.Run)
 
-		reportConfig.DB = db
+		reportConfig.StatsWriter = writer
+
+		if heatmap != nil && conf.CommServer.Heatmap.SnapshotPeriod > 0 {
+			heatmapStore, ok := store.(statsstore.HeatmapStore)
+			if !ok {
+				log.Fatal().Msgf("the %s stats store doesn't keep heatmap snapshots", conf.CommServer.Metrics.StatsStore)
+			}
+
+			period := time.Duration(conf.CommServer.Heatmap.SnapshotPeriod) * time.Minute
+			sup.Go("heatmap_snapshots", supervisor.Restart, func() {
+				heatmap.RunSnapshots(heatmapStore, conf.CommServer.Metrics.Cluster, period)
+			})
+		}
+	}
+
+	var sessions *commserver.SessionTracker
+
+	if conf.CommServer.Metrics.SessionEvents != "" {
+		sink, err := openSessionSink(&conf)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot open the session events sink")
+		}
+		sessionWriter := statsstore.NewSessionWriter(sink, &statsstore.SessionWriterConfig{
+			MaxRetries: conf.CommServer.Metrics.StatsMaxRetries,
+			Log:        loggers.Component("sessions"),
+		})
+		closers = append(closers, func() { sessionWriter.Close() })
+		sup.Go("session_writer", supervisor.Fail, sessionWriter.Run)
+
+		sessions = commserver.NewSessionTracker(&commserver.SessionTrackerConfig{
+			Sink:    sessionWriter,
+			Cluster: conf.CommServer.Metrics.Cluster,
+			Log:     loggers.Component("sessions"),
+		})
+
+		config.ReliableWriterControllerFactory = sessions.WrapWriterControllerFactory(config.ReliableWriterControllerFactory)
+		config.UnreliableWriterControllerFactory = sessions.WrapWriterControllerFactory(config.UnreliableWriterControllerFactory)
+	}
+
+	var negotiations *commserver.NegotiationTracer
+
+	if conf.Tracing.Exporter != "" {
+		negotiations = commserver.NewNegotiationTracer()
+
+		config.ReliableWriterControllerFactory = negotiations.WrapWriterControllerFactory(config.ReliableWriterControllerFactory)
+		config.UnreliableWriterControllerFactory = negotiations.WrapWriterControllerFactory(config.UnreliableWriterControllerFactory)
 	}
 
 	b, err := broker.NewBroker(&config)
@@ -146,17 +567,17 @@ func main() {
 
 	log.Info().Str("version", version.Version()).Msg("starting communication server")
 
-	go func() {
+	// NOTE: the http servers keep no state, they panic so a listen error goes through the policy
+	sup.Go("profiler", supervisor.Restart, func() {
 		addr := fmt.Sprintf("0.0.0.0:9081")
 		log.Info().Str("address", addr).Msg("Starting profiler")
-		log.Error().Err(http.ListenAndServe(addr, nil))
-	}()
+		panic(fmt.Errorf("profiler stopped: %v", http.ListenAndServe(addr, nil)))
+	})
 
-	go func() {
+	sup.Go("api", supervisor.Restart, func() {
 		versionResponse, err := json.Marshal(map[string]string{"version": version.Version()})
 		if err != nil {
-			log.Fatal().Err(err).Msg("invalid version")
-			return
+			panic(err)
 		}
 
 		mux := http.NewServeMux()
@@ -168,29 +589,57 @@ func main() {
 			w.WriteHeader(http.StatusOK)
 			w.Write(versionResponse)
 		})
+		commserver.RegisterModerationAPI(mux, conf.CommServer.ServerSecret, moderator, mutes)
+		if heatmap != nil {
+			commserver.RegisterHeatmapAPI(mux, conf.CommServer.ServerSecret, heatmap)
+		}
+		if cellStats != nil {
+			commserver.RegisterCellStatsAPI(mux, conf.CommServer.ServerSecret, cellStats)
+		}
 		addr := fmt.Sprintf("%s:%d", conf.CommServer.APIHost, conf.CommServer.APIPort)
 		log.Info().Str("address", addr).Msg("Starting HTTP API")
-		log.Fatal().Err(http.ListenAndServe(addr, mux)).Msg("")
-	}()
+		panic(fmt.Errorf("http api stopped: %v", http.ListenAndServe(addr, mux)))
+	})
 
 	if err := b.Connect(); err != nil {
 		log.Fatal().Err(err).Msg("connect coordinator failure")
 	}
 
-	go b.ProcessSubscriptionChannel()
+	// NOTE: the broker and pipeline loops may panic halfway through an update, or holding a lock
+	sup.Go("subscriptions", supervisor.Fail, b.ProcessSubscriptionChannel)
 
-	go b.ProcessMessagesChannel()
+	sup.Go("messages", supervisor.Fail, b.ProcessMessagesChannel)
 
-	go b.ProcessControlMessages()
+	sup.Go("control_messages", supervisor.Fail, b.ProcessControlMessages)
 
-	reporter := commserver.NewReporter(&reportConfig)
-	seconds := uint64(10)
-	reportTicker := time.NewTicker(time.Duration(seconds) * time.Second)
-
-	for {
-		<-reportTicker.C
+	if conf.CommServer.BatchFlushInterval > 0 {
+		sup.Go("batches", supervisor.Fail, pipeline.ProcessBatches)
+	}
 
-		stats := b.GetBrokerStats()
-		reporter.Report(stats)
+	listeners := []func(broker.Stats){pipeline.Prune}
+	if sessions != nil {
+		listeners = append(listeners, sessions.Track)
 	}
+	if negotiations != nil {
+		listeners = append(listeners, negotiations.Track)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		log.Info().Str("signal", sig.String()).Msg("stopping communication server")
+
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+
+		os.Exit(0)
+	}()
+
+	reporter := commserver.NewReporter(&reportConfig)
+	sup.Run("reporter", supervisor.Fail, func() {
+		reporter.Run(b, listeners...)
+	})
 }
@@ -1,14 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
+	"sync"
 	"time"
 
-	"github.com/rs/zerolog"
-
 	"github.com/decentraland/world/internal/commons/config"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/utils"
 	"github.com/decentraland/world/internal/commtest"
 )
 
@@ -19,6 +20,8 @@ type rootConfig struct {
 		NTopics  int `overwrite-flag:"n"`
 		Duration int `overwrite-flag:"duration" flag-usage:"duration in seconds"`
 	}
+
+	Log logging.Config
 }
 
 func main() {
@@ -29,6 +32,22 @@ func main() {
 
 	fmt.Println("starting test: ", conf.CoordinatorURL)
 
+	loggers, err := logging.NewLoggers(conf.Log.LoggerConfig("sparsetest", ""))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer loggers.Close()
+
+	ctx, cancel := utils.SignalContext(context.Background())
+	defer cancel()
+
+	if conf.SparseTest.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(conf.SparseTest.Duration)*time.Second)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+
 	for i := 0; i < conf.SparseTest.NTopics; i++ {
 		for j := 0; j < 2; j++ {
 			topic := fmt.Sprintf("topic-%d", i)
@@ -43,21 +62,24 @@ func main() {
 
 			if j == 0 {
 				name := fmt.Sprintf("client-%d", i)
-				opts.Log = zerolog.New(os.Stdout).Level(zerolog.InfoLevel).With().Str("name", name).Logger()
+				opts.Log = loggers.Component("bot").With().Str("name", name).Logger()
 			} else {
 				name := fmt.Sprintf("client-%d-observer", i)
-				opts.Log = zerolog.New(os.Stdout).Level(zerolog.InfoLevel).With().Str("name", name).Logger()
+				opts.Log = loggers.Component("bot").With().Str("name", name).Logger()
 				opts.TrackStats = true
 			}
 
-			go commtest.StartBot(opts)
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if err := commtest.StartBot(ctx, opts); err != nil {
+					opts.Log.Error().Err(err).Msg("bot failed")
+				}
+			}()
 		}
 	}
 
-	if conf.SparseTest.Duration > 0 {
-		time.Sleep(time.Duration(conf.SparseTest.Duration) * time.Second)
-		os.Exit(0)
-	} else {
-		select {}
-	}
+	wg.Wait()
 }
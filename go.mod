@@ -9,14 +9,22 @@ require (
 	github.com/ethereum/go-ethereum v1.9.3
 	github.com/go-playground/locales v0.13.0
 	github.com/go-playground/universal-translator v0.16.0
-	github.com/golang/protobuf v1.3.2
+	github.com/golang/protobuf v1.3.4
+	github.com/gorilla/websocket v1.4.0
 	github.com/leodido/go-urn v1.2.0 // indirect
 	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v1.11.0
 	github.com/pion/webrtc/v2 v2.1.16
 	github.com/rs/zerolog v1.16.0
 	github.com/segmentio/ksuid v1.0.2
 	github.com/spf13/viper v1.4.0
 	github.com/stretchr/testify v1.4.0
+	github.com/xeipuuv/gojsonschema v1.2.0
+	go.opentelemetry.io/otel v0.4.3
+	go.opentelemetry.io/otel/exporters/otlp v0.4.3
+	golang.org/x/crypto v0.0.0-20191122220453-ac88ee75c92c
+	google.golang.org/grpc v1.27.1
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 	gopkg.in/go-playground/validator.v9 v9.30.0
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 )
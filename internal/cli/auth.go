@@ -2,7 +2,6 @@ package cli
 
 import (
 	"bytes"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -66,7 +65,12 @@ func (a *Auth0) GetUserToken() (string, error) {
 		return "", err
 	}
 
-	return response["access_token"].(string), nil
+	accessToken, ok := response["access_token"].(string)
+	if !ok {
+		return "", fmt.Errorf("missing access_token in the %s response", postTokenURL)
+	}
+
+	return accessToken, nil
 }
 
 type Auth struct {
@@ -83,6 +87,9 @@ func (a *Auth) GetAccessToken(userToken string) (string, error) {
 		"user_token": userToken,
 		"pub_key":    a.PubKey,
 	})
+	if err != nil {
+		return "", err
+	}
 
 	postTokenURL, err := url.Parse(a.IdentityURL)
 	if err != nil {
@@ -116,44 +123,39 @@ func (a *Auth) GetAccessToken(userToken string) (string, error) {
 		return "", err
 	}
 
-	accessToken, ok := response[auth0TokenKey]
+	accessToken, ok := response[auth0TokenKey].(string)
 	if !ok {
 		return "", fmt.Errorf("missing key from response %s", auth0TokenKey)
 	}
-	return accessToken.(string), nil
+	return accessToken, nil
 }
 
+// ExecuteAuthFlow gets an access token for the ephemeral key of auth, signing in with the auth0
+// user credentials
 func ExecuteAuthFlow(auth0 *Auth0, auth *Auth) (string, error) {
 	userToken, err := auth0.GetUserToken()
 	if err != nil {
-		fmt.Println("error getting auth0 token", err)
-		return "", nil
+		return "", fmt.Errorf("cannot get the auth0 token: %v", err)
 	}
 
-	fmt.Println("user token", userToken)
-
 	accessToken, err := auth.GetAccessToken(userToken)
 	if err != nil {
-		fmt.Println("error getting access token", err)
-		return "", nil
+		return "", fmt.Errorf("cannot get the access token: %v", err)
 	}
 
-	fmt.Println("access token", accessToken)
-
 	return accessToken, nil
 }
 
-func ReadEphemeralKeyFromFile(path string) (*ephemeral.EphemeralKey, error) {
-	key, err := ioutil.ReadFile(path)
+// ReadEphemeralKeyFromFile reads a secp256k1 key file, a plaintext hex or pem key, or an encrypted
+// one using passphrase
+func ReadEphemeralKeyFromFile(path string, passphrase *PassphraseConfig) (*ephemeral.EphemeralKey, error) {
+	privateKey, err := ReadPrivateKeyFile(path, passphrase)
 	if err != nil {
-		return nil, fmt.Errorf("cannot load private key from file %s: %v", path, err)
+		return nil, err
 	}
 
-	kbs, err := hex.DecodeString(string(key))
-
-	privateKey, err := crypto.ToECDSA(kbs)
-	if err != nil {
-		return nil, err
+	if curve := KeyCurve(&privateKey.PublicKey); curve != CurveS256 {
+		return nil, fmt.Errorf("invalid key in %s: it's a %s key, ephemeral keys have to be %s", path, curve, CurveS256)
 	}
 
 	config := ephemeral.EphemeralKeyConfig{
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteAuthFlow(t *testing.T) {
+	auth0 := &Auth0{Domain: "127.0.0.1:1"}
+
+	token, err := ExecuteAuthFlow(auth0, &Auth{IdentityURL: "http://127.0.0.1:1"})
+	assert.Error(t, err, "a failed login is an error")
+	assert.Empty(t, token)
+}
+
+func TestGetAccessToken(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/token", r.URL.Path)
+
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{auth0TokenKey: "access", "error": "denied"})
+	}))
+	defer server.Close()
+
+	auth := &Auth{IdentityURL: server.URL, PubKey: "key"}
+
+	token, err := auth.GetAccessToken("user")
+	require.NoError(t, err)
+	assert.Equal(t, "access", token)
+
+	status = http.StatusUnauthorized
+	token, err = auth.GetAccessToken("user")
+	assert.Error(t, err)
+	assert.Empty(t, token)
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"math"
+
+	"github.com/golang/protobuf/proto"
+
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const (
+	// maxBatchSize is the largest frame the simulation clients read, they read the data channels into
+	// 1024 byte buffers and drop the rest of a larger frame
+	maxBatchSize        = 1024
+	defaultBatchMaxSize = maxBatchSize
+
+	// maxIdentitySize is the longest identity the broker is expected to add to the forwarded identity
+	// messages, an address is 42 bytes
+	maxIdentitySize = 128
+)
+
+type batchKey struct {
+	reliable bool
+	identity bool
+	topic    string
+}
+
+type pendingBatch struct {
+	bodies [][]byte
+	size   int
+}
+
+// Batcher packs the data messages sent to the same topic, on the same channel, in a single
+// protocol.Category_BATCH topic message. It's not safe for concurrent use.
+type Batcher struct {
+	send    func(reliable bool, raw []byte)
+	maxSize int
+
+	keys    []batchKey
+	pending map[batchKey]*pendingBatch
+}
+
+// NewBatcher creates a Batcher that writes the topic messages using send, a batch is written before
+// its encoded topic message goes over maxSize bytes, or on Flush. maxSize is capped at 1024 bytes.
+func NewBatcher(send func(reliable bool, raw []byte), maxSize int) *Batcher {
+	if maxSize == 0 {
+		maxSize = defaultBatchMaxSize
+	}
+
+	if maxSize > maxBatchSize {
+		maxSize = maxBatchSize
+	}
+
+	return &Batcher{
+		send:    send,
+		maxSize: maxSize,
+		pending: make(map[batchKey]*pendingBatch),
+	}
+}
+
+// Send adds data to the batch of topic, identity messages are batched apart
+func (b *Batcher) Send(reliable bool, identity bool, topic string, data proto.Message) error {
+	body, err := proto.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	key := batchKey{reliable: reliable, identity: identity, topic: topic}
+
+	batch := b.pending[key]
+	if batch == nil {
+		batch = &pendingBatch{}
+		b.pending[key] = batch
+		b.keys = append(b.keys, key)
+	} else if batchSize(key, batch.size+batchEntrySize(len(body))) > b.maxSize {
+		if err := b.flush(key, batch); err != nil {
+			return err
+		}
+	}
+
+	batch.bodies = append(batch.bodies, body)
+	batch.size += batchEntrySize(len(body))
+
+	return nil
+}
+
+// batchEntrySize is the encoded size of a message of n bytes in a protocol.BatchData
+func batchEntrySize(n int) int {
+	return 1 + proto.SizeVarint(uint64(n)) + n
+}
+
+// batchSize is the size of a batch topic message with entries of entriesSize bytes, either as sent or
+// as forwarded by the broker, whichever is larger
+func batchSize(key batchKey, entriesSize int) int {
+	body := proto.Size(&protocol.BatchData{Category: protocol.Category_BATCH}) + entriesSize
+
+	var header, forwardedHeader int
+	if key.identity {
+		header = proto.Size(&broker.TopicIdentityMessage{Type: broker.MessageType_TOPIC_IDENTITY, Topic: key.topic})
+		forwardedHeader = proto.Size(&broker.TopicIdentityFWMessage{
+			Type:      broker.MessageType_TOPIC_IDENTITY_FW,
+			FromAlias: math.MaxUint64,
+			Identity:  make([]byte, maxIdentitySize),
+			Role:      broker.Role_CLIENT,
+		})
+	} else {
+		header = proto.Size(&broker.TopicMessage{Type: broker.MessageType_TOPIC, Topic: key.topic})
+		forwardedHeader = proto.Size(&broker.TopicFWMessage{Type: broker.MessageType_TOPIC_FW, FromAlias: math.MaxUint64})
+	}
+
+	if forwardedHeader > header {
+		header = forwardedHeader
+	}
+
+	return header + 1 + proto.SizeVarint(uint64(body)) + body
+}
+
+// Flush writes every pending batch, in the order they were started. A batch of a single message is
+// written as a regular topic message.
+func (b *Batcher) Flush() error {
+	keys := b.keys
+	b.keys = nil
+
+	for _, key := range keys {
+		batch := b.pending[key]
+		delete(b.pending, key)
+
+		if err := b.flush(key, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Batcher) flush(key batchKey, batch *pendingBatch) error {
+	bodies := batch.bodies
+	batch.bodies = nil
+	batch.size = 0
+
+	if len(bodies) == 0 {
+		return nil
+	}
+
+	body := bodies[0]
+
+	if len(bodies) > 1 {
+		var err error
+
+		body, err = proto.Marshal(&protocol.BatchData{Category: protocol.Category_BATCH, Messages: bodies})
+		if err != nil {
+			return err
+		}
+	}
+
+	var msg proto.Message
+	if key.identity {
+		msg = &broker.TopicIdentityMessage{Type: broker.MessageType_TOPIC_IDENTITY, Topic: key.topic, Body: body}
+	} else {
+		msg = &broker.TopicMessage{Type: broker.MessageType_TOPIC, Topic: key.topic, Body: body}
+	}
+
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	b.send(key.reliable, raw)
+
+	return nil
+}
+
+// UnbatchMessages wraps a simulation.Config.OnMessageReceived callback, calling it once for each
+// message of the client batches and the server forwarded batches. Any other message is passed as is.
+func UnbatchMessages(onMessageReceived func(bool, broker.MessageType, []byte)) func(bool, broker.MessageType, []byte) {
+	var unbatch func(reliable bool, msgType broker.MessageType, raw []byte)
+
+	unbatch = func(reliable bool, msgType broker.MessageType, raw []byte) {
+		var (
+			alias    uint64
+			identity []byte
+			body     []byte
+		)
+
+		switch msgType {
+		case broker.MessageType_TOPIC_FW:
+			message := broker.TopicFWMessage{}
+			if err := proto.Unmarshal(raw, &message); err != nil {
+				onMessageReceived(reliable, msgType, raw)
+				return
+			}
+
+			alias, body = message.FromAlias, message.Body
+		case broker.MessageType_TOPIC_IDENTITY_FW:
+			message := broker.TopicIdentityFWMessage{}
+			if err := proto.Unmarshal(raw, &message); err != nil {
+				onMessageReceived(reliable, msgType, raw)
+				return
+			}
+
+			alias, identity, body = message.FromAlias, message.Identity, message.Body
+		default:
+			onMessageReceived(reliable, msgType, raw)
+			return
+		}
+
+		dataHeader := protocol.DataHeader{}
+		if err := proto.Unmarshal(body, &dataHeader); err != nil ||
+			(dataHeader.Category != protocol.Category_BATCH && dataHeader.Category != protocol.Category_FORWARDED_BATCH) {
+			onMessageReceived(reliable, msgType, raw)
+			return
+		}
+
+		batch := protocol.BatchData{}
+		if err := proto.Unmarshal(body, &batch); err != nil {
+			onMessageReceived(reliable, msgType, raw)
+			return
+		}
+
+		for _, m := range batch.Messages {
+			if batch.Category == protocol.Category_FORWARDED_BATCH {
+				header := broker.MessageHeader{}
+				if err := proto.Unmarshal(m, &header); err != nil {
+					continue
+				}
+
+				unbatch(reliable, header.Type, m)
+
+				continue
+			}
+
+			var msg proto.Message
+			if msgType == broker.MessageType_TOPIC_FW {
+				msg = &broker.TopicFWMessage{Type: msgType, FromAlias: alias, Body: m}
+			} else {
+				msg = &broker.TopicIdentityFWMessage{Type: msgType, FromAlias: alias, Identity: identity, Body: m}
+			}
+
+			raw, err := proto.Marshal(msg)
+			if err != nil {
+				continue
+			}
+
+			onMessageReceived(reliable, msgType, raw)
+		}
+	}
+
+	return unbatch
+}
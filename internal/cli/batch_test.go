@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+type sentFrame struct {
+	reliable bool
+	raw      []byte
+}
+
+// forward returns the message the broker forwards for a topic message sent by alias
+func forward(t *testing.T, alias uint64, raw []byte) []byte {
+	message := broker.TopicMessage{}
+	require.NoError(t, proto.Unmarshal(raw, &message))
+
+	fw, err := proto.Marshal(&broker.TopicFWMessage{Type: broker.MessageType_TOPIC_FW, FromAlias: alias, Body: message.Body})
+	require.NoError(t, err)
+
+	return fw
+}
+
+func TestBatcher(t *testing.T) {
+	frames := []sentFrame{}
+	batcher := NewBatcher(func(reliable bool, raw []byte) {
+		frames = append(frames, sentFrame{reliable: reliable, raw: raw})
+	}, 0)
+
+	require.NoError(t, batcher.Send(false, false, "a", &protocol.PositionData{Category: protocol.Category_POSITION, PositionX: 1}))
+	require.NoError(t, batcher.Send(true, false, "a", &protocol.ChatData{Category: protocol.Category_CHAT, Text: "hi"}))
+	require.NoError(t, batcher.Send(false, false, "a", &protocol.PositionData{Category: protocol.Category_POSITION, PositionX: 2}))
+	assert.Len(t, frames, 0)
+
+	require.NoError(t, batcher.Flush())
+	require.Len(t, frames, 2)
+	assert.False(t, frames[0].reliable)
+	assert.True(t, frames[1].reliable)
+
+	chat, err := DecodeMessage(true, broker.MessageType_TOPIC_FW, forward(t, 1, frames[1].raw))
+	require.NoError(t, err)
+	assert.Equal(t, protocol.Category_CHAT, chat.Category, "a single message is not batched")
+
+	received := []*SniffedMessage{}
+	unbatch := UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+		msg, err := DecodeMessage(reliable, msgType, raw)
+		require.NoError(t, err)
+		received = append(received, msg)
+	})
+
+	unbatch(false, broker.MessageType_TOPIC_FW, forward(t, 1, frames[0].raw))
+	require.Len(t, received, 2)
+	assert.Equal(t, uint64(1), received[0].Alias)
+	assert.Equal(t, float32(1), received[0].Data.(*protocol.PositionData).PositionX)
+	assert.Equal(t, float32(2), received[1].Data.(*protocol.PositionData).PositionX)
+
+	t.Run("max size", func(t *testing.T) {
+		frames = frames[:0]
+		batcher := NewBatcher(func(reliable bool, raw []byte) {
+			frames = append(frames, sentFrame{reliable: reliable, raw: raw})
+		}, 10)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, batcher.Send(true, false, "a", &protocol.ChatData{Category: protocol.Category_CHAT, Text: "hello"}))
+		}
+
+		assert.Len(t, frames, 2)
+		require.NoError(t, batcher.Flush())
+		assert.Len(t, frames, 3)
+	})
+
+	t.Run("at the limit", func(t *testing.T) {
+		frames = frames[:0]
+		batcher := NewBatcher(func(reliable bool, raw []byte) {
+			frames = append(frames, sentFrame{reliable: reliable, raw: raw})
+		}, 4096)
+
+		sent := 0
+		for len(frames) == 0 {
+			require.NoError(t, batcher.Send(false, false, "a", &protocol.PositionData{Category: protocol.Category_POSITION, PositionX: float32(sent)}))
+			sent++
+		}
+
+		fw := forward(t, math.MaxUint64, frames[0].raw)
+		assert.True(t, len(fw) <= maxBatchSize, "forwarded frame of %d bytes, the max size is capped", len(fw))
+		assert.True(t, len(fw) > maxBatchSize-64, "forwarded frame of %d bytes is not full", len(fw))
+
+		// NOTE: the simulation clients read the frames into 1024 byte buffers
+		buffer := make([]byte, 1024)
+		n := copy(buffer, fw)
+
+		received := 0
+		UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+			_, err := DecodeMessage(reliable, msgType, raw)
+			require.NoError(t, err)
+			received++
+		})(false, broker.MessageType_TOPIC_FW, buffer[:n])
+		assert.Equal(t, sent-1, received, "the last position goes in the next batch")
+	})
+
+	t.Run("framing", func(t *testing.T) {
+		frames = frames[:0]
+		batcher := NewBatcher(func(reliable bool, raw []byte) {
+			frames = append(frames, sentFrame{reliable: reliable, raw: raw})
+		}, 256)
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, batcher.Send(false, false, "a", &protocol.PositionData{Category: protocol.Category_POSITION, PositionX: float32(i)}))
+			require.NoError(t, batcher.Send(true, true, "a", &protocol.ChatData{Category: protocol.Category_CHAT, Text: "hello"}))
+		}
+		require.NoError(t, batcher.Flush())
+
+		for _, frame := range frames {
+			assert.True(t, len(frame.raw) <= 256, "frame of %d bytes", len(frame.raw))
+
+			if !frame.reliable {
+				assert.True(t, len(forward(t, math.MaxUint64, frame.raw)) <= 256)
+			}
+		}
+	})
+}
+
+func TestUnbatchForwardedBatch(t *testing.T) {
+	position, err := proto.Marshal(&protocol.PositionData{Category: protocol.Category_POSITION})
+	require.NoError(t, err)
+
+	fw1, err := proto.Marshal(&broker.TopicFWMessage{Type: broker.MessageType_TOPIC_FW, FromAlias: 1, Body: position})
+	require.NoError(t, err)
+
+	fw2, err := proto.Marshal(&broker.TopicIdentityFWMessage{
+		Type:      broker.MessageType_TOPIC_IDENTITY_FW,
+		FromAlias: 2,
+		Identity:  []byte("id2"),
+		Body:      position,
+	})
+	require.NoError(t, err)
+
+	body, err := proto.Marshal(&protocol.BatchData{Category: protocol.Category_FORWARDED_BATCH, Messages: [][]byte{fw1, fw2}})
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&broker.TopicFWMessage{Type: broker.MessageType_TOPIC_FW, Body: body})
+	require.NoError(t, err)
+
+	received := []*SniffedMessage{}
+	unbatch := UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+		msg, err := DecodeMessage(reliable, msgType, raw)
+		require.NoError(t, err)
+		received = append(received, msg)
+	})
+
+	unbatch(false, broker.MessageType_TOPIC_FW, raw)
+	require.Len(t, received, 2)
+	assert.Equal(t, uint64(1), received[0].Alias)
+	assert.Equal(t, uint64(2), received[1].Alias)
+	assert.Equal(t, "id2", received[1].Identity)
+}
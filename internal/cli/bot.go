@@ -1,10 +1,13 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"net/url"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/decentraland/auth-go/pkg/ephemeral"
@@ -14,17 +17,25 @@ import (
 	"github.com/decentraland/webrtc-broker/pkg/authentication"
 	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
 	"github.com/decentraland/webrtc-broker/pkg/simulation"
+	"github.com/decentraland/world/internal/commons/tracing"
 	"github.com/decentraland/world/pkg/protocol"
 	pion "github.com/pion/webrtc/v2"
 	"github.com/segmentio/ksuid"
+	"go.opentelemetry.io/otel/api/key"
 )
 
 const (
-	parcelSize = 16
-	maxParcel  = 150
-	minParcel  = -150
+	parcelSize = protocol.ParcelSize
+	maxParcel  = protocol.MaxParcel
+	minParcel  = protocol.MinParcel
+
+	subscriptionRadius = 4
+
+	defaultProfileKeepAlive = 30 * time.Second
 )
 
+var defaultBotProfile = map[string]interface{}{"name": "bot"}
+
 func nowMs() float64 {
 	return float64(time.Now().UnixNano() / int64(time.Millisecond))
 }
@@ -193,14 +204,15 @@ func (a *ClientAuthenticator) GenerateClientAuthMessage() (*broker.AuthMessage,
 
 func (a *ClientAuthenticator) GenerateClientConnectURL(coordinatorURL string) (string, error) {
 	u, err := url.Parse(coordinatorURL)
-	u.Path = path.Join(u.Path, "/connect")
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
+	u.Path = path.Join(u.Path, "/connect")
+
 	accessToken, err := a.getAccessToken()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	msg := fmt.Sprintf("GET:%s", u.String())
@@ -219,24 +231,92 @@ func (a *ClientAuthenticator) GenerateClientConnectURL(coordinatorURL string) (s
 	return u.String(), nil
 }
 
+// CellTopic returns the topic of the 4x4 parcels cell containing p
+func CellTopic(p V3) string {
+	return protocol.CellTopic(p.X, p.Z)
+}
+
+// ParcelTopics returns the topics of the cells within radius parcels of p
+func ParcelTopics(p V3, radius int) map[string]bool {
+	parcelX := int(math.Floor(p.X / parcelSize))
+	parcelZ := int(math.Floor(p.Z / parcelSize))
+
+	minX := ((max(minParcel, parcelX-radius) + maxParcel) >> 2) << 2
+	maxX := ((min(maxParcel, parcelX+radius) + maxParcel) >> 2) << 2
+	minZ := ((max(minParcel, parcelZ-radius) + maxParcel) >> 2) << 2
+	maxZ := ((min(maxParcel, parcelZ+radius) + maxParcel) >> 2) << 2
+
+	topics := make(map[string]bool)
+
+	for x := minX; x <= maxX; x += 4 {
+		for z := minZ; z <= maxZ; z += 4 {
+			topics[fmt.Sprintf("%d:%d", x>>2, z>>2)] = true
+		}
+	}
+
+	return topics
+}
+
 type BotOptions struct {
 	Auth           authentication.ClientAuthenticator
 	CoordinatorURL string
-	Checkpoints    []V3
-	DurationMs     uint
+	Avatar         *Avatar
 	Log            zerolog.Logger
 	TrackStats     bool
+
+	// DirectMessages subscribes the bot to the direct messages, it acks and logs every one received
+	DirectMessages bool
+	// DirectMessageRecipient is an identity to send a direct message to on every chat tick, it
+	// enables DirectMessages to receive the acks
+	DirectMessageRecipient string
+
+	// BatchFlushInterval enables the batching of the position, profile and chat messages, the
+	// messages of each topic and channel are sent in a single frame every BatchFlushInterval
+	BatchFlushInterval time.Duration
+	// BatchMaxSize is the max size of a batch in bytes, up to 1024, the batch is sent early once reached
+	BatchMaxSize int
+
+	// Profile is the announced profile, only its hash is sent
+	Profile map[string]interface{}
+	// ProfileSnapshotURL is sent along the profile hash
+	ProfileSnapshotURL string
+	// ProfileUpdates replaces the announced profile, it's announced right away if its hash changed
+	ProfileUpdates <-chan map[string]interface{}
+	// ProfileKeepAlive is the max time between two announcements of the same profile, the profile
+	// is also announced when the bot moves to a new cell
+	ProfileKeepAlive time.Duration
 }
 
-func StartBot(options *BotOptions) {
+// StartBot runs a bot moving its avatar until ctx is done, then it stops the simulation client
+// and, if TrackStats is enabled, reports the final stats
+func StartBot(ctx context.Context, options *BotOptions) error {
 	log := options.Log
+	avatar := options.Avatar
 
-	if len(options.Checkpoints) < 2 {
-		log.Fatal().Msg("invalid path, need at least two checkpoints")
+	if avatar == nil {
+		return errors.New("missing bot avatar")
 	}
 
+	profile := options.Profile
+	if profile == nil {
+		profile = defaultBotProfile
+	}
+
+	profileHash, err := ProfileHash(profile)
+	if err != nil {
+		return fmt.Errorf("invalid profile: %v", err)
+	}
+
+	profileKeepAlive := options.ProfileKeepAlive
+	if profileKeepAlive == 0 {
+		profileKeepAlive = defaultProfileKeepAlive
+	}
+
+	connectCtx, connectSpan := tracing.Start(ctx, "bot.connect", key.String("coordinator", options.CoordinatorURL))
+	auth := &tracedAuthenticator{ctx: connectCtx, auth: options.Auth}
+
 	config := simulation.Config{
-		Auth:           options.Auth,
+		Auth:           auth,
 		CoordinatorURL: options.CoordinatorURL,
 		ICEServers: []pion.ICEServer{
 			{
@@ -246,86 +326,117 @@ func StartBot(options *BotOptions) {
 		Log: log,
 	}
 
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	if options.TrackStats {
-		trackCh := make(chan []byte, 256)
-		config.OnMessageReceived = func(reliable bool, msgType broker.MessageType, raw []byte) {
-			if !reliable && msgType == broker.MessageType_TOPIC_FW {
-				trackCh <- raw
-			}
-		}
+		trackCh, onMessageReceived := NewTrackChannel(ctx)
+		config.OnMessageReceived = onMessageReceived
+
+		wg.Add(1)
 
 		go func() {
-			peers := make(map[uint64]*simulation.Stats)
-			topicFwMessage := broker.TopicFWMessage{}
-			dataHeader := protocol.DataHeader{}
+			defer wg.Done()
 
-			onMessage := func(rawMsg []byte) {
-				if err := proto.Unmarshal(rawMsg, &topicFwMessage); err != nil {
-					log.Error().Err(err).Msg("error unmarshalling data message")
-					return
+			TrackPositionStats(ctx, log, trackCh, 30*time.Second, func(peers map[uint64]*simulation.Stats) {
+				fmt.Println("Avg duration between position messages")
+				for alias, stats := range peers {
+					fmt.Printf("%d: %f ms (%d messages)\n", alias, stats.Avg(), stats.Samples())
 				}
+			})
+		}()
+	}
 
-				if err := proto.Unmarshal(topicFwMessage.Body, &dataHeader); err != nil {
-					log.Error().Err(err).Msg("error unmarshalling data header")
-					return
-				}
+	directMessages := options.DirectMessages || options.DirectMessageRecipient != ""
+	dmCh := make(chan *SniffedMessage, 16)
 
-				if dataHeader.Category != protocol.Category_POSITION {
+	if directMessages {
+		onMessageReceived := config.OnMessageReceived
+
+		config.OnMessageReceived = UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+			if reliable && msgType == broker.MessageType_TOPIC_IDENTITY_FW {
+				msg, err := DecodeMessage(reliable, msgType, raw)
+				if err != nil {
+					log.Error().Err(err).Msg("cannot decode message")
 					return
 				}
 
-				alias := topicFwMessage.FromAlias
-				stats := peers[alias]
+				if msg.Category == protocol.Category_DIRECT_MESSAGE || msg.Category == protocol.Category_DIRECT_MESSAGE_ACK {
+					select {
+					case dmCh <- msg:
+					case <-ctx.Done():
+					}
 
-				if stats == nil {
-					stats = &simulation.Stats{}
-					peers[alias] = stats
+					return
 				}
+			}
 
-				stats.Seen(time.Now())
+			if onMessageReceived != nil {
+				onMessageReceived(reliable, msgType, raw)
 			}
+		})
+	}
 
-			reportTicker := time.NewTicker(30 * time.Second)
-			defer reportTicker.Stop()
+	// NOTE: Start returns once the reliable data channel is open and the auth message is sent
+	client := simulation.Start(&config)
+	defer StopClient(client)
 
-			for {
-				select {
-				case rawMsg := <-trackCh:
-					onMessage(rawMsg)
+	auth.endNegotiation()
+	connectSpan.End()
 
-					n := len(trackCh)
-					for i := 0; i < n; i++ {
-						rawMsg = <-trackCh
-						onMessage(rawMsg)
-					}
-				case <-reportTicker.C:
-					fmt.Println("Avg duration between position messages")
-					for alias, stats := range peers {
-						fmt.Printf("%d: %f ms (%d messages)\n", alias, stats.Avg(), stats.Samples())
-
-						if time.Since(stats.LastSeen).Seconds() > 1 {
-							delete(peers, alias)
-						}
-					}
-				}
+	batcher := NewBatcher(func(reliable bool, raw []byte) {
+		if reliable {
+			client.SendReliable <- raw
+		} else {
+			client.SendUnreliable <- raw
+		}
+	}, options.BatchMaxSize)
 
-			}
-		}()
+	var flushCh <-chan time.Time
+
+	if options.BatchFlushInterval > 0 {
+		flushTicker := time.NewTicker(options.BatchFlushInterval)
+		defer flushTicker.Stop()
+		flushCh = flushTicker.C
 	}
 
-	client := simulation.Start(&config)
-	checkpoints := options.Checkpoints
+	send := func(reliable bool, identity bool, topic string, data proto.Message) error {
+		if err := batcher.Send(reliable, identity, topic, data); err != nil {
+			return err
+		}
+
+		if flushCh == nil {
+			return batcher.Flush()
+		}
 
-	totalDistance := 0.0
-	for i := 1; i < len(checkpoints); i++ {
-		totalDistance += checkpoints[i].Sub(checkpoints[i-1]).Length()
+		return nil
 	}
 
-	// NOTE: velocity in ms
-	vMs := totalDistance / float64(options.DurationMs)
+	pendingDMs := make(map[string]time.Time)
+
+	p := avatar.Position()
+
+	var lastProfile time.Time
 
-	p := checkpoints[0]
-	nextCheckpointIndex := 1
+	lastProfileTopic := ""
+
+	announceProfile := func() error {
+		lastProfile = time.Now()
+		lastProfileTopic = CellTopic(p)
+
+		return send(true, true, lastProfileTopic, &protocol.ProfileData{
+			Category:       protocol.Category_PROFILE,
+			Time:           nowMs(),
+			ProfileVersion: "1",
+			Hash:           profileHash,
+			SnapshotUrl:    options.ProfileSnapshotURL,
+		})
+	}
+
+	topics := make(map[string]bool)
 	lastPositionMsg := time.Now()
 
 	profileTicker := time.NewTicker(1 * time.Second)
@@ -335,102 +446,121 @@ func StartBot(options *BotOptions) {
 	defer positionTicker.Stop()
 	defer chatTicker.Stop()
 
-	hashLocation := func() string {
-		parcelX := (int(p.X/parcelSize) + maxParcel) >> 2
-		parcelZ := (int(p.Z/parcelSize) + maxParcel) >> 2
-		hash := fmt.Sprintf("%d:%d", parcelX, parcelZ)
-		return hash
-	}
-
 	for {
 		select {
+		case <-ctx.Done():
+			return nil
+		case <-flushCh:
+			if err := batcher.Flush(); err != nil {
+				return fmt.Errorf("flush batch failed: %v", err)
+			}
 		case <-profileTicker.C:
-			ms := nowMs()
-			bytes, err := EncodeTopicIdentityMessage(hashLocation(), &protocol.ProfileData{
-				Category:       protocol.Category_PROFILE,
-				Time:           ms,
-				ProfileVersion: "1",
-			})
+			if time.Since(lastProfile) < profileKeepAlive && CellTopic(p) == lastProfileTopic {
+				break
+			}
+
+			if err := announceProfile(); err != nil {
+				return fmt.Errorf("encode profile failed: %v", err)
+			}
+		case profile := <-options.ProfileUpdates:
+			hash, err := ProfileHash(profile)
 			if err != nil {
-				log.Fatal().Err(err).Msg("encode profile failed")
+				return fmt.Errorf("invalid profile: %v", err)
+			}
+
+			if hash == profileHash {
+				break
+			}
+
+			profileHash = hash
+
+			if err := announceProfile(); err != nil {
+				return fmt.Errorf("encode profile failed: %v", err)
 			}
-			client.SendReliable <- bytes
 		case <-chatTicker.C:
 			ms := nowMs()
-			bytes, err := EncodeTopicMessage(hashLocation(), &protocol.ChatData{
+			err := send(true, true, CellTopic(p), &protocol.ChatData{
 				Category:  protocol.Category_CHAT,
 				Time:      ms,
 				MessageId: ksuid.New().String(),
 				Text:      "hi",
 			})
 			if err != nil {
-				log.Fatal().Err(err).Msg("encode chat failed")
+				return fmt.Errorf("encode chat failed: %v", err)
 			}
-			client.SendReliable <- bytes
-		case <-positionTicker.C:
-			nextCheckpoint := checkpoints[nextCheckpointIndex]
-			v := nextCheckpoint.Sub(p)
-			tMax := float64(v.Length()) / vMs
-			dt := float64(time.Since(lastPositionMsg).Nanoseconds() / int64(time.Millisecond))
-
-			if dt < tMax {
-				dir := v.Normalize()
-				p = p.Add(dir.ScalarProd(dt * vMs))
-			} else {
-				if nextCheckpointIndex == len(checkpoints)-1 {
-					nextCheckpointIndex = 0
-				} else {
-					nextCheckpointIndex++
+
+			if options.DirectMessageRecipient != "" {
+				for _, id := range expireDirectMessages(pendingDMs, time.Now()) {
+					log.Info().Str("id", id).Msg("direct message not acknowledged")
+				}
+
+				id, bytes, err := EncodeDirectMessage(options.DirectMessageRecipient, "hi")
+				if err != nil {
+					return fmt.Errorf("encode direct message failed: %v", err)
+				}
+
+				pendingDMs[id] = time.Now()
+				client.SendReliable <- bytes
+			}
+		case msg := <-dmCh:
+			switch data := msg.Data.(type) {
+			case *protocol.DirectMessageData:
+				log.Info().Str("from", msg.Identity).Str("id", data.MessageId).Str("text", data.Text).
+					Msg("direct message received")
+
+				bytes, err := EncodeDirectMessageAck(msg.Identity, data)
+				if err != nil {
+					return fmt.Errorf("encode direct message ack failed: %v", err)
+				}
+				client.SendReliable <- bytes
+			case *protocol.DirectMessageAckData:
+				if sent, ok := pendingDMs[data.MessageId]; ok {
+					delete(pendingDMs, data.MessageId)
+					log.Info().Str("from", msg.Identity).Str("id", data.MessageId).
+						Dur("latency", time.Since(sent)).Msg("direct message delivered")
 				}
-				p = nextCheckpoint
 			}
+		case <-positionTicker.C:
+			now := time.Now()
+			avatar.Update(now.Sub(lastPositionMsg))
+			lastPositionMsg = now
 
-			topics := make(map[string]bool)
-			radius := 4
-			parcelX := int(p.X / parcelSize)
-			parcelZ := int(p.Z / parcelSize)
+			p = avatar.Position()
+			rotation := avatar.Rotation()
 
-			minX := ((max(minParcel, parcelX-radius) + maxParcel) >> 2) << 2
-			maxX := ((min(maxParcel, parcelX+radius) + maxParcel) >> 2) << 2
-			minZ := ((max(minParcel, parcelZ-radius) + maxParcel) >> 2) << 2
-			maxZ := ((min(maxParcel, parcelZ+radius) + maxParcel) >> 2) << 2
+			newTopics := ParcelTopics(p, subscriptionRadius)
+			if directMessages {
+				newTopics[protocol.DirectMessageTopic] = true
+			}
 
-			newTopics := make(map[string]bool)
 			topicsChanged := false
 
-			for x := minX; x <= maxX; x += 4 {
-				for z := minZ; z <= maxZ; z += 4 {
-					hash := fmt.Sprintf("%d:%d", x>>2, z>>2)
-					newTopics[hash] = true
-					if !topics[hash] {
-						topicsChanged = true
-					}
+			for topic := range newTopics {
+				if !topics[topic] {
+					topicsChanged = true
 				}
 			}
 
-			if topicsChanged {
+			if topicsChanged || len(newTopics) != len(topics) {
 				topics = newTopics
 				client.SendTopicSubscriptionMessage(newTopics)
 			}
 
 			ms := nowMs()
-			bytes, err := EncodeTopicMessage(hashLocation(), &protocol.PositionData{
+			err := send(false, false, CellTopic(p), &protocol.PositionData{
 				Category:  protocol.Category_POSITION,
 				Time:      ms,
 				PositionX: float32(p.X),
 				PositionY: float32(p.Y),
 				PositionZ: float32(p.Z),
-				RotationX: 0,
-				RotationY: 0,
-				RotationZ: 0,
-				RotationW: 0,
+				RotationX: float32(rotation.X),
+				RotationY: float32(rotation.Y),
+				RotationZ: float32(rotation.Z),
+				RotationW: float32(rotation.W),
 			})
 			if err != nil {
-				log.Fatal().Err(err).Msg("encode position failed")
+				return fmt.Errorf("encode position failed: %v", err)
 			}
-
-			client.SendUnreliable <- bytes
-			lastPositionMsg = time.Now()
 		}
 	}
 }
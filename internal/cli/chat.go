@@ -0,0 +1,361 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/segmentio/ksuid"
+
+	"github.com/decentraland/webrtc-broker/pkg/authentication"
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/webrtc-broker/pkg/simulation"
+	"github.com/decentraland/world/pkg/protocol"
+	pion "github.com/pion/webrtc/v2"
+)
+
+const (
+	chatHelp = `commands:
+  /tp <x>,<y>  teleport to the parcel x,y
+  /peers       list the nearby peers
+  /history     show the chat messages received, in the order they were sent
+  /dm <identity> <text>
+               send a direct message
+  /whoami      show your parcel and position
+  /help        show this help
+  /quit        exit
+anything else is sent as a chat message`
+
+	peerTimeout = 5 * time.Second
+
+	chatLogSize = 100
+)
+
+// ParcelCenter returns the position at the center of the parcel x,y, at eye height
+func ParcelCenter(x int, y int) V3 {
+	return V3{
+		X: float64(x*parcelSize + parcelSize/2),
+		Y: defaultEyeHeight,
+		Z: float64(y*parcelSize + parcelSize/2),
+	}
+}
+
+// ParseParcel parses a parcel in the "x,y" or "x y" formats
+func ParseParcel(s string) (int, int, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("invalid parcel %q, expected x,y", s)
+	}
+
+	x, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid parcel x %q", fields[0])
+	}
+
+	y, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid parcel y %q", fields[1])
+	}
+
+	return x, y, nil
+}
+
+type chatPeer struct {
+	alias    uint64
+	identity string
+	position V3
+	lastSeen time.Time
+}
+
+// ChatOptions is the interactive chat client configuration
+type ChatOptions struct {
+	Auth           authentication.ClientAuthenticator
+	CoordinatorURL string
+	ParcelX        int
+	ParcelY        int
+	Input          io.Reader
+	Output         io.Writer
+	Log            zerolog.Logger
+}
+
+// StartChat joins at the given parcel, prints the chat messages received and sends every line read
+// from opts.Input, until ctx is done or /quit is typed. Lines starting with / are commands.
+func StartChat(ctx context.Context, opts ChatOptions) error {
+	log := opts.Log
+	out := opts.Output
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	messagesCh := make(chan *SniffedMessage, 256)
+
+	config := simulation.Config{
+		Auth:           opts.Auth,
+		CoordinatorURL: opts.CoordinatorURL,
+		ICEServers: []pion.ICEServer{
+			{
+				URLs: []string{"stun:stun.l.google.com:19302"},
+			},
+		},
+		Log: log,
+		OnMessageReceived: UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+			msg, err := DecodeMessage(reliable, msgType, raw)
+			if err != nil {
+				log.Error().Err(err).Msg("cannot decode message")
+				return
+			}
+
+			if msg == nil {
+				return
+			}
+
+			select {
+			case messagesCh <- msg:
+			case <-ctx.Done():
+			}
+		}),
+	}
+
+	client := simulation.Start(&config)
+	defer StopClient(client)
+
+	linesCh := make(chan string)
+
+	go func() {
+		scanner := bufio.NewScanner(opts.Input)
+		for scanner.Scan() {
+			select {
+			case linesCh <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Error().Err(err).Msg("error reading input")
+		}
+
+		close(linesCh)
+	}()
+
+	parcelX, parcelY := opts.ParcelX, opts.ParcelY
+	p := ParcelCenter(parcelX, parcelY)
+	peers := make(map[uint64]*chatPeer)
+	chatLog := NewChatLog(chatLogSize)
+	pendingDMs := make(map[string]string)
+
+	printEntry := func(entry *ChatEntry) {
+		fmt.Fprintf(out, "[%s] %d %s: %s\n", entry.Time().Format("15:04:05"), entry.Alias, entry.Identity, entry.Text)
+	}
+
+	sendPosition := func() error {
+		bytes, err := EncodeTopicMessage(CellTopic(p), &protocol.PositionData{
+			Category:  protocol.Category_POSITION,
+			Time:      nowMs(),
+			PositionX: float32(p.X),
+			PositionY: float32(p.Y),
+			PositionZ: float32(p.Z),
+			RotationW: 1,
+		})
+		if err != nil {
+			return fmt.Errorf("encode position failed: %v", err)
+		}
+
+		client.SendUnreliable <- bytes
+		return nil
+	}
+
+	teleport := func(x int, y int) error {
+		parcelX, parcelY = x, y
+		p = ParcelCenter(x, y)
+
+		topics := ParcelTopics(p, subscriptionRadius)
+		topics[protocol.DirectMessageTopic] = true
+
+		if err := client.SendTopicSubscriptionMessage(topics); err != nil {
+			return fmt.Errorf("subscription failed: %v", err)
+		}
+
+		return sendPosition()
+	}
+
+	if err := teleport(parcelX, parcelY); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "joined at %d,%d, type /help for the commands\n", parcelX, parcelY)
+
+	onCommand := func(line string) (bool, error) {
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case "/quit":
+			return true, nil
+		case "/help":
+			fmt.Fprintln(out, chatHelp)
+		case "/whoami":
+			fmt.Fprintf(out, "parcel %d,%d position (%.2f, %.2f, %.2f)\n", parcelX, parcelY, p.X, p.Y, p.Z)
+		case "/tp":
+			x, y, err := ParseParcel(strings.TrimSpace(strings.TrimPrefix(line, "/tp")))
+			if err != nil {
+				fmt.Fprintln(out, err)
+				return false, nil
+			}
+
+			if err := teleport(x, y); err != nil {
+				return false, err
+			}
+
+			fmt.Fprintf(out, "teleported to %d,%d\n", x, y)
+		case "/history":
+			for _, entry := range chatLog.Entries() {
+				printEntry(entry)
+			}
+		case "/dm":
+			if len(fields) < 3 {
+				fmt.Fprintln(out, "usage: /dm <identity> <text>")
+				return false, nil
+			}
+
+			recipient := fields[1]
+
+			id, bytes, err := EncodeDirectMessage(recipient, strings.Join(fields[2:], " "))
+			if err != nil {
+				return false, fmt.Errorf("encode direct message failed: %v", err)
+			}
+
+			pendingDMs[id] = recipient
+			client.SendReliable <- bytes
+		case "/peers":
+			nearby := make([]*chatPeer, 0, len(peers))
+			for _, peer := range peers {
+				nearby = append(nearby, peer)
+			}
+
+			sort.Slice(nearby, func(i, j int) bool {
+				return nearby[i].position.Sub(p).Length() < nearby[j].position.Sub(p).Length()
+			})
+
+			fmt.Fprintf(out, "%d peers nearby\n", len(nearby))
+
+			for _, peer := range nearby {
+				fmt.Fprintf(out, "  %d %s at (%.2f, %.2f, %.2f), %.1fm away\n", peer.alias, peer.identity,
+					peer.position.X, peer.position.Y, peer.position.Z, peer.position.Sub(p).Length())
+			}
+		default:
+			fmt.Fprintf(out, "unknown command %s\n", fields[0])
+		}
+
+		return false, nil
+	}
+
+	positionTicker := time.NewTicker(1 * time.Second)
+	profileTicker := time.NewTicker(1 * time.Second)
+	defer positionTicker.Stop()
+	defer profileTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-linesCh:
+			if !ok {
+				return nil
+			}
+
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if strings.HasPrefix(line, "/") {
+				quit, err := onCommand(line)
+				if err != nil || quit {
+					return err
+				}
+
+				continue
+			}
+
+			bytes, err := EncodeTopicIdentityMessage(CellTopic(p), &protocol.ChatData{
+				Category:  protocol.Category_CHAT,
+				Time:      nowMs(),
+				MessageId: ksuid.New().String(),
+				Text:      line,
+			})
+			if err != nil {
+				return fmt.Errorf("encode chat failed: %v", err)
+			}
+
+			client.SendReliable <- bytes
+		case msg := <-messagesCh:
+			switch data := msg.Data.(type) {
+			case *protocol.DirectMessageData:
+				fmt.Fprintf(out, "[%s] dm from %d %s: %s\n", msg.Time.Format("15:04:05"), msg.Alias, msg.Identity, data.Text)
+
+				bytes, err := EncodeDirectMessageAck(msg.Identity, data)
+				if err != nil {
+					return fmt.Errorf("encode direct message ack failed: %v", err)
+				}
+
+				client.SendReliable <- bytes
+				continue
+			case *protocol.DirectMessageAckData:
+				if recipient, ok := pendingDMs[data.MessageId]; ok {
+					delete(pendingDMs, data.MessageId)
+					fmt.Fprintf(out, "dm delivered to %s\n", recipient)
+				}
+
+				continue
+			}
+
+			peer := peers[msg.Alias]
+			if peer == nil {
+				peer = &chatPeer{alias: msg.Alias}
+				peers[msg.Alias] = peer
+			}
+
+			peer.lastSeen = msg.Time
+
+			if msg.Identity != "" {
+				peer.identity = msg.Identity
+			}
+
+			switch data := msg.Data.(type) {
+			case *protocol.PositionData:
+				peer.position = V3{float64(data.PositionX), float64(data.PositionY), float64(data.PositionZ)}
+			case *protocol.ChatData:
+				if entry := chatLog.Add(peer.alias, peer.identity, data); entry != nil {
+					printEntry(entry)
+				}
+			}
+		case <-positionTicker.C:
+			if err := sendPosition(); err != nil {
+				return err
+			}
+
+			for alias, peer := range peers {
+				if time.Since(peer.lastSeen) > peerTimeout {
+					delete(peers, alias)
+				}
+			}
+		case <-profileTicker.C:
+			bytes, err := EncodeTopicIdentityMessage(CellTopic(p), &protocol.ProfileData{
+				Category:       protocol.Category_PROFILE,
+				Time:           nowMs(),
+				ProfileVersion: "1",
+			})
+			if err != nil {
+				return fmt.Errorf("encode profile failed: %v", err)
+			}
+
+			client.SendReliable <- bytes
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParcel(t *testing.T) {
+	for _, s := range []string{"-3,10", "-3 10", " -3, 10 "} {
+		x, y, err := ParseParcel(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, -3, x)
+		assert.Equal(t, 10, y)
+	}
+
+	for _, s := range []string{"", "3", "a,1", "1,b", "1,2,3"} {
+		_, _, err := ParseParcel(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParcelCenter(t *testing.T) {
+	p := ParcelCenter(-1, 2)
+	assert.Equal(t, V3{X: -8, Y: defaultEyeHeight, Z: 40}, p)
+}
+
+func TestParcelTopics(t *testing.T) {
+	for _, parcel := range [][2]int{{-3, -3}, {-1, 2}, {0, 0}, {-150, 150}} {
+		p := ParcelCenter(parcel[0], parcel[1])
+
+		topics := ParcelTopics(p, 0)
+		assert.Equal(t, map[string]bool{CellTopic(p): true}, topics, "%v", parcel)
+	}
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"sort"
+	"time"
+
+	"github.com/segmentio/ksuid"
+
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+// ChatEntry is a chat message received from a peer
+type ChatEntry struct {
+	ID       ksuid.KSUID
+	Alias    uint64
+	Identity string
+	Text     string
+}
+
+// Time returns the time the message was created, taken from its id
+func (e *ChatEntry) Time() time.Time {
+	return e.ID.Time()
+}
+
+// ChatLog keeps the last chat messages ordered by id, since ids are ksuids that's the order in which
+// they were sent, no matter the order they were received. Repeated messages are discarded.
+type ChatLog struct {
+	maxEntries int
+	entries    []*ChatEntry
+	ids        map[ksuid.KSUID]bool
+}
+
+// NewChatLog creates a chat log that keeps up to maxEntries messages
+func NewChatLog(maxEntries int) *ChatLog {
+	return &ChatLog{
+		maxEntries: maxEntries,
+		ids:        make(map[ksuid.KSUID]bool),
+	}
+}
+
+// Add inserts the message in order, it returns nil if the message is repeated, has an invalid id
+// or is older than every message in a full log
+func (l *ChatLog) Add(alias uint64, identity string, chat *protocol.ChatData) *ChatEntry {
+	id, err := ksuid.Parse(chat.MessageId)
+	if err != nil || l.ids[id] {
+		return nil
+	}
+
+	i := sort.Search(len(l.entries), func(i int) bool {
+		return ksuid.Compare(l.entries[i].ID, id) > 0
+	})
+
+	if len(l.entries) >= l.maxEntries {
+		if i == 0 {
+			return nil
+		}
+
+		delete(l.ids, l.entries[0].ID)
+		copy(l.entries, l.entries[1:i])
+		i--
+	} else {
+		l.entries = append(l.entries, nil)
+		copy(l.entries[i+1:], l.entries[i:])
+	}
+
+	entry := &ChatEntry{ID: id, Alias: alias, Identity: identity, Text: chat.Text}
+	l.entries[i] = entry
+	l.ids[id] = true
+
+	return entry
+}
+
+// Entries returns the messages, oldest first
+func (l *ChatLog) Entries() []*ChatEntry {
+	return l.entries
+}
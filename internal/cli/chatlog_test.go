@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func TestChatLog(t *testing.T) {
+	now := time.Now()
+	ids := make([]string, 4)
+
+	for i := range ids {
+		id, err := ksuid.NewRandomWithTime(now.Add(time.Duration(i) * time.Second))
+		require.NoError(t, err)
+		ids[i] = id.String()
+	}
+
+	l := NewChatLog(3)
+
+	for _, i := range []int{2, 0, 1} {
+		assert.NotNil(t, l.Add(1, "", &protocol.ChatData{MessageId: ids[i], Text: ids[i]}))
+	}
+
+	assert.Nil(t, l.Add(1, "", &protocol.ChatData{MessageId: ids[0]}), "repeated message")
+	assert.Nil(t, l.Add(1, "", &protocol.ChatData{MessageId: "invalid"}), "invalid id")
+
+	assert.NotNil(t, l.Add(1, "", &protocol.ChatData{MessageId: ids[3], Text: ids[3]}))
+
+	entries := l.Entries()
+	require.Len(t, entries, 3)
+
+	for i, entry := range entries {
+		assert.Equal(t, ids[i+1], entry.Text)
+	}
+}
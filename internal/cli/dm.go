@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/segmentio/ksuid"
+
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+// directMessageAckTimeout is how long a direct message waits for its ack, the server holds the
+// messages to an unknown recipient for 30 seconds and drops them after that
+const directMessageAckTimeout = 30 * time.Second
+
+// EncodeDirectMessage encodes a direct message to the recipient identity, it returns the message
+// id, used to match the ack
+func EncodeDirectMessage(recipient string, text string) (string, []byte, error) {
+	id := ksuid.New().String()
+
+	bytes, err := EncodeTopicIdentityMessage(protocol.DirectMessageTopic, &protocol.DirectMessageData{
+		Category:  protocol.Category_DIRECT_MESSAGE,
+		Time:      nowMs(),
+		MessageId: id,
+		Recipient: recipient,
+		Text:      text,
+	})
+
+	return id, bytes, err
+}
+
+// EncodeDirectMessageAck encodes the ack of a direct message received from the sender identity
+func EncodeDirectMessageAck(sender string, dm *protocol.DirectMessageData) ([]byte, error) {
+	return EncodeTopicIdentityMessage(protocol.DirectMessageTopic, &protocol.DirectMessageAckData{
+		Category:  protocol.Category_DIRECT_MESSAGE_ACK,
+		Time:      nowMs(),
+		MessageId: dm.MessageId,
+		Recipient: sender,
+	})
+}
+
+// expireDirectMessages removes from pending, the send time of the direct messages by id, the ones
+// that timed out waiting for their ack, it returns their ids
+func expireDirectMessages(pending map[string]time.Time, now time.Time) []string {
+	expired := []string{}
+
+	for id, sent := range pending {
+		if now.Sub(sent) >= directMessageAckTimeout {
+			delete(pending, id)
+			expired = append(expired, id)
+		}
+	}
+
+	return expired
+}
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpireDirectMessages(t *testing.T) {
+	now := time.Now()
+	pending := map[string]time.Time{
+		"old":    now.Add(-directMessageAckTimeout),
+		"recent": now.Add(-time.Second),
+	}
+
+	assert.Equal(t, []string{"old"}, expireDirectMessages(pending, now))
+	assert.Equal(t, map[string]time.Time{"recent": now.Add(-time.Second)}, pending)
+	assert.Empty(t, expireDirectMessages(pending, now))
+}
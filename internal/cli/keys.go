@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Supported key curves, s256 is the secp256k1 curve used by the ephemeral keys
+const (
+	CurveS256 = "s256"
+	CurveP256 = "p256"
+)
+
+// Supported private key file formats. Hex keys are always secp256k1, it's the format of the
+// keys/client.key file used by the cli tools.
+const (
+	KeyFormatHex = "hex"
+	KeyFormatPEM = "pem"
+)
+
+const ecPrivateKeyVersion = 1
+
+// oidS256 is the secp256k1 named curve, x509 only knows the NIST curves
+var oidS256 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+
+// ecPrivateKey is the SEC 1 (RFC 5915) private key structure
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// GenerateKey creates a new private key in the given curve
+func GenerateKey(curve string) (*ecdsa.PrivateKey, error) {
+	switch curve {
+	case CurveS256:
+		return crypto.GenerateKey()
+	case CurveP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unknown curve %s, valid curves are %s and %s", curve, CurveS256, CurveP256)
+	}
+}
+
+// KeyCurve returns the curve name of a key
+func KeyCurve(key *ecdsa.PublicKey) string {
+	switch key.Curve {
+	case crypto.S256():
+		return CurveS256
+	case elliptic.P256():
+		return CurveP256
+	default:
+		return key.Curve.Params().Name
+	}
+}
+
+// KeyAddress returns the ethereum address of a secp256k1 key
+func KeyAddress(key *ecdsa.PublicKey) (string, error) {
+	if KeyCurve(key) != CurveS256 {
+		return "", errors.New("only secp256k1 keys have an address")
+	}
+
+	return crypto.PubkeyToAddress(*key).Hex(), nil
+}
+
+// CompressPublicKey returns the hex compressed public key of any curve, for secp256k1 keys it's the
+// same as EncodePublicKey
+func CompressPublicKey(key *ecdsa.PublicKey) string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	compressed := make([]byte, 1+size)
+	compressed[0] = byte(2 + key.Y.Bit(0))
+
+	x := key.X.Bytes()
+	copy(compressed[1+size-len(x):], x)
+
+	return hexutil.Encode(compressed)
+}
+
+// EncodePrivateKey encodes a key in the given format
+func EncodePrivateKey(key *ecdsa.PrivateKey, format string) ([]byte, error) {
+	switch format {
+	case KeyFormatHex:
+		if KeyCurve(&key.PublicKey) != CurveS256 {
+			return nil, errors.New("only secp256k1 keys can be hex encoded, use pem")
+		}
+
+		return []byte(hex.EncodeToString(crypto.FromECDSA(key))), nil
+	case KeyFormatPEM:
+		var der []byte
+		var err error
+
+		if KeyCurve(&key.PublicKey) == CurveS256 {
+			der, err = asn1.Marshal(ecPrivateKey{
+				Version:       ecPrivateKeyVersion,
+				PrivateKey:    crypto.FromECDSA(key),
+				NamedCurveOID: oidS256,
+				PublicKey:     asn1.BitString{Bytes: crypto.FromECDSAPub(&key.PublicKey)},
+			})
+		} else {
+			der, err = x509.MarshalECPrivateKey(key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	default:
+		return nil, fmt.Errorf("unknown key format %s, valid formats are %s and %s", format, KeyFormatHex, KeyFormatPEM)
+	}
+}
+
+// DecodePrivateKey decodes a hex or pem key, the format is detected from the content. It also returns
+// the detected format.
+func DecodePrivateKey(content []byte) (*ecdsa.PrivateKey, string, error) {
+	content = bytes.TrimSpace(content)
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		kbs, err := hex.DecodeString(string(content))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid key, it's not pem or hex encoded: %v", err)
+		}
+
+		key, err := crypto.ToECDSA(kbs)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid secp256k1 key: %v", err)
+		}
+
+		return key, KeyFormatHex, nil
+	}
+
+	var sec1 ecPrivateKey
+	if _, err := asn1.Unmarshal(block.Bytes, &sec1); err == nil && sec1.NamedCurveOID.Equal(oidS256) {
+		key, err := crypto.ToECDSA(sec1.PrivateKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid secp256k1 key: %v", err)
+		}
+
+		return key, KeyFormatPEM, nil
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid pem key: %v", err)
+	}
+
+	return key, KeyFormatPEM, nil
+}
+
+// decodeRawKey decodes the private scalar of a key in the given curve
+func decodeRawKey(curve string, d []byte) (*ecdsa.PrivateKey, error) {
+	switch curve {
+	case CurveS256:
+		return crypto.ToECDSA(d)
+	case CurveP256:
+		key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+		key.Curve = elliptic.P256()
+
+		if key.D.Sign() <= 0 || key.D.Cmp(key.Curve.Params().N) >= 0 {
+			return nil, errors.New("invalid private key, out of range")
+		}
+
+		key.X, key.Y = key.Curve.ScalarBaseMult(d)
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unknown curve %s", curve)
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/decentraland/auth-go/pkg/ephemeral"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodePrivateKey(t *testing.T) {
+	for _, curve := range []string{CurveS256, CurveP256} {
+		for _, format := range []string{KeyFormatHex, KeyFormatPEM} {
+			key, err := GenerateKey(curve)
+			require.NoError(t, err)
+
+			encoded, err := EncodePrivateKey(key, format)
+			if curve == CurveP256 && format == KeyFormatHex {
+				assert.Error(t, err)
+				continue
+			}
+			require.NoError(t, err)
+
+			decoded, decodedFormat, err := DecodePrivateKey(encoded)
+			require.NoError(t, err, "%s %s", curve, format)
+			assert.Equal(t, format, decodedFormat)
+			assert.Equal(t, curve, KeyCurve(&decoded.PublicKey))
+			assert.Equal(t, key.D, decoded.D)
+			assert.Equal(t, key.X, decoded.X)
+		}
+	}
+
+	_, _, err := DecodePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestKeyAddress(t *testing.T) {
+	key, err := crypto.HexToECDSA("01ccab5b71968f014c0ecd795319dd2f596310be6b1041fec9f7d6db0b48de4d")
+	require.NoError(t, err)
+
+	ephemeralKey, err := ephemeral.NewEphemeralKey(&ephemeral.EphemeralKeyConfig{PrivateKey: key})
+	require.NoError(t, err)
+	assert.Equal(t, EncodePublicKey(ephemeralKey), CompressPublicKey(&key.PublicKey))
+
+	address, err := KeyAddress(&key.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey).Hex(), address)
+
+	p256, err := GenerateKey(CurveP256)
+	require.NoError(t, err)
+
+	_, err = KeyAddress(&p256.PublicKey)
+	assert.Error(t, err)
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt parameters of the encrypted keys, StandardScryptN is the go-ethereum keystore default
+const (
+	StandardScryptN = 1 << 18
+	LightScryptN    = 1 << 12
+
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltSize     = 32
+
+	encryptedKeyVersion = 1
+	encryptedKeyKDF     = "scrypt"
+	encryptedKeyCipher  = "aes-256-gcm"
+)
+
+// ErrWrongPassphrase is returned when an encrypted key can't be decrypted with the given passphrase
+var ErrWrongPassphrase = errors.New("cannot decrypt key, wrong passphrase")
+
+type scryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+type encryptedKeyCrypto struct {
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdfparams"`
+	Cipher     string       `json:"cipher"`
+	Nonce      string       `json:"nonce"`
+	Ciphertext string       `json:"ciphertext"`
+}
+
+// encryptedKey is the keystore style encrypted key file, the ciphertext is the private key scalar
+type encryptedKey struct {
+	Version int                `json:"version"`
+	Curve   string             `json:"curve"`
+	Address string             `json:"address,omitempty"`
+	Crypto  encryptedKeyCrypto `json:"crypto"`
+}
+
+// IsEncryptedKey reports if content is an encrypted key file
+func IsEncryptedKey(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte("{"))
+}
+
+// EncryptKey encrypts a private key with a passphrase, the key is derived with scrypt using the cost
+// scryptN and the private key is sealed with AES-GCM
+func EncryptKey(key *ecdsa.PrivateKey, passphrase string, scryptN int) ([]byte, error) {
+	curve := KeyCurve(&key.PublicKey)
+	if curve != CurveS256 && curve != CurveP256 {
+		return nil, fmt.Errorf("unsupported curve %s", curve)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newKeyCipher(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := aead.Seal(nil, nonce, crypto.FromECDSA(key), []byte(curve))
+
+	encrypted := encryptedKey{
+		Version: encryptedKeyVersion,
+		Curve:   curve,
+		Crypto: encryptedKeyCrypto{
+			KDF:        encryptedKeyKDF,
+			KDFParams:  scryptParams{N: scryptN, R: scryptR, P: scryptP, Salt: hex.EncodeToString(salt)},
+			Cipher:     encryptedKeyCipher,
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(ciphertext),
+		},
+	}
+
+	if address, err := KeyAddress(&key.PublicKey); err == nil {
+		encrypted.Address = address
+	}
+
+	return json.MarshalIndent(encrypted, "", "  ")
+}
+
+// DecryptKey decrypts an encrypted key file, it returns ErrWrongPassphrase if the passphrase doesn't
+// match
+func DecryptKey(content []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	var encrypted encryptedKey
+	if err := json.Unmarshal(content, &encrypted); err != nil {
+		return nil, fmt.Errorf("invalid encrypted key: %v", err)
+	}
+
+	c := encrypted.Crypto
+
+	if encrypted.Version != encryptedKeyVersion {
+		return nil, fmt.Errorf("unsupported encrypted key version %d", encrypted.Version)
+	}
+
+	if c.KDF != encryptedKeyKDF || c.Cipher != encryptedKeyCipher {
+		return nil, fmt.Errorf("unsupported encrypted key, kdf %s, cipher %s", c.KDF, c.Cipher)
+	}
+
+	salt, err := hex.DecodeString(c.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted key salt: %v", err)
+	}
+
+	nonce, err := hex.DecodeString(c.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted key nonce: %v", err)
+	}
+
+	ciphertext, err := hex.DecodeString(c.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted key ciphertext: %v", err)
+	}
+
+	aead, err := newKeyCipher(passphrase, salt, c.KDFParams.N, c.KDFParams.R, c.KDFParams.P)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid encrypted key nonce size")
+	}
+
+	d, err := aead.Open(nil, nonce, ciphertext, []byte(encrypted.Curve))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	key, err := decodeRawKey(encrypted.Curve, d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted key: %v", err)
+	}
+
+	return key, nil
+}
+
+// ReadPrivateKeyFile reads a plaintext hex or pem key file, or an encrypted one using passphrase
+func ReadPrivateKeyFile(path string, passphrase *PassphraseConfig) (*ecdsa.PrivateKey, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load private key from file %s: %v", path, err)
+	}
+
+	if !IsEncryptedKey(content) {
+		key, _, err := DecodePrivateKey(content)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load private key from file %s: %v", path, err)
+		}
+
+		return key, nil
+	}
+
+	if passphrase == nil {
+		return nil, fmt.Errorf("cannot load private key from file %s: the key is encrypted", path)
+	}
+
+	p, err := passphrase.Read(fmt.Sprintf("Passphrase for %s: ", path), false)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load private key from file %s: %v", path, err)
+	}
+
+	key, err := DecryptKey(content, p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load private key from file %s: %v", path, err)
+	}
+
+	return key, nil
+}
+
+func newKeyCipher(passphrase string, salt []byte, n int, r int, p int) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted key kdf params: %v", err)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
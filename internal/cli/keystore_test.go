@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptKey(t *testing.T) {
+	for _, curve := range []string{CurveS256, CurveP256} {
+		key, err := GenerateKey(curve)
+		require.NoError(t, err)
+
+		encrypted, err := EncryptKey(key, "secret", LightScryptN)
+		require.NoError(t, err)
+		assert.True(t, IsEncryptedKey(encrypted))
+
+		decrypted, err := DecryptKey(encrypted, "secret")
+		require.NoError(t, err)
+		assert.Equal(t, key.D, decrypted.D)
+		assert.Equal(t, key.Y, decrypted.Y)
+		assert.Equal(t, curve, KeyCurve(&decrypted.PublicKey))
+
+		_, err = DecryptKey(encrypted, "wrong")
+		assert.Equal(t, ErrWrongPassphrase, err)
+	}
+}
+
+func TestReadEphemeralKeyFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, content, 0600))
+		return path
+	}
+
+	key, err := GenerateKey(CurveS256)
+	require.NoError(t, err)
+
+	encoded, err := EncodePrivateKey(key, KeyFormatHex)
+	require.NoError(t, err)
+
+	ephemeralKey, err := ReadEphemeralKeyFromFile(write("plain.key", append(encoded, '\n')), nil)
+	require.NoError(t, err)
+	assert.Equal(t, key.D, ephemeralKey.PrivateKey.D, "plaintext hex keys are still readable")
+
+	_, err = ReadEphemeralKeyFromFile(write("invalid.key", []byte("01zz")), nil)
+	assert.Contains(t, err.Error(), "invalid key, it's not pem or hex encoded")
+
+	p256, err := GenerateKey(CurveP256)
+	require.NoError(t, err)
+
+	encoded, err = EncodePrivateKey(p256, KeyFormatPEM)
+	require.NoError(t, err)
+
+	_, err = ReadEphemeralKeyFromFile(write("p256.key", encoded), nil)
+	assert.Contains(t, err.Error(), "ephemeral keys have to be s256")
+
+	encrypted, err := EncryptKey(key, "secret", LightScryptN)
+	require.NoError(t, err)
+
+	path := write("encrypted.key", encrypted)
+
+	_, err = ReadEphemeralKeyFromFile(path, nil)
+	assert.Contains(t, err.Error(), "the key is encrypted")
+
+	t.Run("passphrase file", func(t *testing.T) {
+		passphraseFile := write("passphrase", []byte("secret\n"))
+
+		ephemeralKey, err := ReadEphemeralKeyFromFile(path, &PassphraseConfig{File: passphraseFile})
+		require.NoError(t, err)
+		assert.Equal(t, key.D, ephemeralKey.PrivateKey.D)
+	})
+
+	t.Run("passphrase env", func(t *testing.T) {
+		require.NoError(t, os.Setenv("TEST_KEY_PASSPHRASE", "secret"))
+		defer os.Unsetenv("TEST_KEY_PASSPHRASE")
+
+		ephemeralKey, err := ReadEphemeralKeyFromFile(path, &PassphraseConfig{Env: "TEST_KEY_PASSPHRASE"})
+		require.NoError(t, err)
+		assert.Equal(t, key.D, ephemeralKey.PrivateKey.D)
+
+		require.NoError(t, os.Setenv("TEST_KEY_PASSPHRASE", "wrong"))
+		_, err = ReadEphemeralKeyFromFile(path, &PassphraseConfig{Env: "TEST_KEY_PASSPHRASE"})
+		assert.Contains(t, err.Error(), ErrWrongPassphrase.Error())
+	})
+
+	t.Run("missing passphrase", func(t *testing.T) {
+		_, err := ReadEphemeralKeyFromFile(path, &PassphraseConfig{Env: "TEST_MISSING_PASSPHRASE"})
+		assert.Contains(t, err.Error(), "missing passphrase, set TEST_MISSING_PASSPHRASE")
+	})
+}
@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	gravity          = 9.8
+	defaultEyeHeight = 1.6
+)
+
+// Quaternion represents a rotation, using the same conventions as the explorer: Y is up and an
+// identity rotation faces +Z
+type Quaternion struct {
+	X float64
+	Y float64
+	Z float64
+	W float64
+}
+
+// IdentityQuaternion returns the no rotation quaternion
+func IdentityQuaternion() Quaternion {
+	return Quaternion{W: 1}
+}
+
+// LookRotation returns the rotation around the Y axis that faces dir, dir Y component is ignored
+func LookRotation(dir V3) Quaternion {
+	if dir.X == 0 && dir.Z == 0 {
+		return IdentityQuaternion()
+	}
+
+	halfYaw := math.Atan2(dir.X, dir.Z) / 2
+	return Quaternion{Y: math.Sin(halfYaw), W: math.Cos(halfYaw)}
+}
+
+// MovementMode defines how an avatar picks its next target
+type MovementMode int
+
+const (
+	// CheckpointsMovement loops through a fixed list of checkpoints
+	CheckpointsMovement MovementMode = iota
+	// RandomWalkMovement walks to random targets inside a circle
+	RandomWalkMovement
+	// FollowMovement follows a leader, keeping a distance
+	FollowMovement
+)
+
+// ParseMovementMode parses the movement mode name used in the cli flags
+func ParseMovementMode(mode string) (MovementMode, error) {
+	switch mode {
+	case "", "checkpoints":
+		return CheckpointsMovement, nil
+	case "randomwalk":
+		return RandomWalkMovement, nil
+	case "follow":
+		return FollowMovement, nil
+	default:
+		return CheckpointsMovement, fmt.Errorf("invalid movement mode %s", mode)
+	}
+}
+
+// PositionProvider is anything with a position, used as leader in FollowMovement
+type PositionProvider interface {
+	Position() V3
+}
+
+// MovementOptions configures the avatar movement, distances are in meters and speeds in m/s
+type MovementOptions struct {
+	Mode MovementMode
+
+	// Checkpoints is the path for CheckpointsMovement, Y is the height of the avatar
+	Checkpoints []V3
+
+	// Center and Radius define the area of RandomWalkMovement
+	Center V3
+	Radius float64
+
+	// HeightVariation is the max height above EyeHeight of the RandomWalkMovement targets
+	HeightVariation float64
+	EyeHeight       float64
+
+	Leader         PositionProvider
+	FollowDistance float64
+
+	Speed float64
+
+	// IdleProbability is the chance of pausing when a target is reached, for up to MaxIdle
+	IdleProbability float64
+	MaxIdle         time.Duration
+
+	// JumpProbability is the chance of jumping in a second of movement
+	JumpProbability float64
+	JumpHeight      float64
+
+	// Rand is the source of randomness, a new one is seeded with the current time if nil
+	Rand *rand.Rand
+}
+
+// Avatar simulates the movement of a bot, it's safe to read its position from other goroutines
+type Avatar struct {
+	mux sync.RWMutex
+
+	options MovementOptions
+	rand    *rand.Rand
+
+	ground   V3
+	rotation Quaternion
+	target   V3
+
+	nextCheckpointIndex int
+	idle                time.Duration
+
+	jumpOffset   float64
+	jumpVelocity float64
+}
+
+// NewAvatar creates an avatar placed at the start of its movement
+func NewAvatar(options *MovementOptions) (*Avatar, error) {
+	a := &Avatar{
+		options:  *options,
+		rand:     options.Rand,
+		rotation: IdentityQuaternion(),
+	}
+
+	if a.rand == nil {
+		a.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if a.options.EyeHeight == 0 {
+		a.options.EyeHeight = defaultEyeHeight
+	}
+
+	if a.options.Speed <= 0 {
+		return nil, errors.New("invalid speed, need to be positive")
+	}
+
+	switch a.options.Mode {
+	case CheckpointsMovement:
+		if len(a.options.Checkpoints) < 2 {
+			return nil, errors.New("invalid path, need at least two checkpoints")
+		}
+
+		a.ground = a.options.Checkpoints[0]
+		a.target = a.options.Checkpoints[1]
+		a.nextCheckpointIndex = 1
+	case RandomWalkMovement:
+		a.ground = a.randomTarget()
+		a.target = a.randomTarget()
+	case FollowMovement:
+		if a.options.Leader == nil {
+			return nil, errors.New("follow mode needs a leader")
+		}
+
+		a.target = a.options.Leader.Position()
+		a.ground = a.target.Add(a.randomOffset(a.options.FollowDistance))
+	default:
+		return nil, fmt.Errorf("invalid movement mode %d", a.options.Mode)
+	}
+
+	return a, nil
+}
+
+// Position returns the current position of the avatar, jumps included
+func (a *Avatar) Position() V3 {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	return V3{a.ground.X, a.ground.Y + a.jumpOffset, a.ground.Z}
+}
+
+// Rotation returns the current rotation of the avatar
+func (a *Avatar) Rotation() Quaternion {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	return a.rotation
+}
+
+// Update advances the simulation dt
+func (a *Avatar) Update(dt time.Duration) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	moving := a.move(dt)
+
+	seconds := dt.Seconds()
+
+	if moving && a.jumpOffset == 0 && a.jumpVelocity == 0 && a.options.JumpHeight > 0 &&
+		a.rand.Float64() < a.options.JumpProbability*seconds {
+		a.jumpVelocity = math.Sqrt(2 * gravity * a.options.JumpHeight)
+	}
+
+	if a.jumpVelocity != 0 || a.jumpOffset > 0 {
+		a.jumpOffset += a.jumpVelocity*seconds - gravity*seconds*seconds/2
+		a.jumpVelocity -= gravity * seconds
+
+		if a.jumpOffset <= 0 {
+			a.jumpOffset = 0
+			a.jumpVelocity = 0
+		}
+	}
+}
+
+func (a *Avatar) move(dt time.Duration) bool {
+	if a.idle > 0 {
+		a.idle -= dt
+		return false
+	}
+
+	if a.options.Mode == FollowMovement {
+		a.target = a.options.Leader.Position()
+	}
+
+	v := a.target.Sub(a.ground)
+	distance := v.Length()
+
+	if a.options.Mode == FollowMovement {
+		if distance <= a.options.FollowDistance {
+			return false
+		}
+
+		distance -= a.options.FollowDistance
+	}
+
+	if distance > 0 {
+		a.rotation = LookRotation(v)
+	}
+
+	step := a.options.Speed * dt.Seconds()
+
+	if step < distance {
+		a.ground = a.ground.Add(v.Normalize().ScalarProd(step))
+		return true
+	}
+
+	if distance > 0 {
+		a.ground = a.ground.Add(v.Normalize().ScalarProd(distance))
+	}
+
+	a.onTargetReached()
+
+	return true
+}
+
+func (a *Avatar) onTargetReached() {
+	switch a.options.Mode {
+	case CheckpointsMovement:
+		a.nextCheckpointIndex = (a.nextCheckpointIndex + 1) % len(a.options.Checkpoints)
+		a.target = a.options.Checkpoints[a.nextCheckpointIndex]
+	case RandomWalkMovement:
+		a.target = a.randomTarget()
+	}
+
+	if a.options.MaxIdle > 0 && a.rand.Float64() < a.options.IdleProbability {
+		a.idle = time.Duration(a.rand.Int63n(int64(a.options.MaxIdle)))
+	}
+}
+
+func (a *Avatar) randomTarget() V3 {
+	p := a.options.Center.Add(a.randomOffset(a.options.Radius))
+	p.Y = a.options.EyeHeight + a.rand.Float64()*a.options.HeightVariation
+
+	return p
+}
+
+func (a *Avatar) randomOffset(radius float64) V3 {
+	angle := a.rand.Float64() * 2 * math.Pi
+	r := radius * math.Sqrt(a.rand.Float64())
+
+	return V3{r * math.Sin(angle), 0, r * math.Cos(angle)}
+}
+
+// RandomCheckpoints returns n checkpoints inside the RandomWalkMovement area, at the eye height
+func (o *MovementOptions) RandomCheckpoints(n int) []V3 {
+	a := &Avatar{options: *o, rand: o.Rand}
+	if a.rand == nil {
+		a.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if a.options.EyeHeight == 0 {
+		a.options.EyeHeight = defaultEyeHeight
+	}
+
+	checkpoints := make([]V3, n)
+	for i := range checkpoints {
+		checkpoints[i] = a.options.Center.Add(a.randomOffset(a.options.Radius))
+		checkpoints[i].Y = a.options.EyeHeight
+	}
+
+	return checkpoints
+}
+
+// MovementConfig is the movement configuration shared by the bot commands
+type MovementConfig struct {
+	Mode            string  `overwrite-flag:"movement" flag-usage:"checkpoints, randomwalk or follow, follow is only supported by realistictest"`
+	Speed           float64 `overwrite-flag:"speed" flag-usage:"speed in m/s"`
+	HeightVariation float64 `overwrite-flag:"heightVariation" flag-usage:"max height variation in meters"`
+	IdleProbability float64 `overwrite-flag:"idleProbability" flag-usage:"chance of pausing on each target"`
+	MaxIdle         int     `overwrite-flag:"maxIdle" flag-usage:"max pause duration in ms"`
+	JumpProbability float64 `overwrite-flag:"jumpProbability" flag-usage:"chance of jumping per second"`
+	JumpHeight      float64 `overwrite-flag:"jumpHeight" flag-usage:"jump height in meters"`
+	FollowDistance  float64 `overwrite-flag:"followDistance" flag-usage:"distance to the leader in meters"`
+}
+
+// Options returns the movement options for an area centered in center, with a radius in parcels
+func (c *MovementConfig) Options(center V3, radius int) (*MovementOptions, error) {
+	mode, err := ParseMovementMode(c.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &MovementOptions{
+		Mode:            mode,
+		Center:          center,
+		Radius:          float64(radius * parcelSize),
+		HeightVariation: c.HeightVariation,
+		Speed:           c.Speed,
+		IdleProbability: c.IdleProbability,
+		MaxIdle:         time.Duration(c.MaxIdle) * time.Millisecond,
+		JumpProbability: c.JumpProbability,
+		JumpHeight:      c.JumpHeight,
+		FollowDistance:  c.FollowDistance,
+	}
+
+	return options, nil
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookRotation(t *testing.T) {
+	q := LookRotation(V3{0, 0, 1})
+	assert.Equal(t, IdentityQuaternion(), q)
+
+	q = LookRotation(V3{1, 5, 0})
+	assert.InDelta(t, math.Sin(math.Pi/4), q.Y, 1e-9)
+	assert.InDelta(t, math.Cos(math.Pi/4), q.W, 1e-9)
+
+	// NOTE: unit quaternion
+	assert.InDelta(t, 1, q.X*q.X+q.Y*q.Y+q.Z*q.Z+q.W*q.W, 1e-9)
+}
+
+func TestCheckpointsMovement(t *testing.T) {
+	avatar, err := NewAvatar(&MovementOptions{
+		Mode:        CheckpointsMovement,
+		Checkpoints: []V3{{0, 1.6, 0}, {10, 1.6, 0}, {10, 3.6, 10}},
+		Speed:       5,
+	})
+	require.NoError(t, err)
+
+	avatar.Update(time.Second)
+	assert.Equal(t, V3{5, 1.6, 0}, avatar.Position())
+	assert.Equal(t, LookRotation(V3{1, 0, 0}), avatar.Rotation())
+
+	avatar.Update(time.Second)
+	assert.Equal(t, V3{10, 1.6, 0}, avatar.Position())
+
+	avatar.Update(time.Second)
+	p := avatar.Position()
+	assert.True(t, p.Y > 1.6 && p.Y < 3.6, "height changes towards the next checkpoint")
+	assert.Equal(t, LookRotation(V3{0, 0, 1}), avatar.Rotation())
+}
+
+func TestCheckpointsMovementNeedsAPath(t *testing.T) {
+	_, err := NewAvatar(&MovementOptions{Checkpoints: []V3{{0, 0, 0}}, Speed: 1})
+	assert.Error(t, err)
+}
+
+func TestRandomWalkStaysInArea(t *testing.T) {
+	avatar, err := NewAvatar(&MovementOptions{
+		Mode:            RandomWalkMovement,
+		Center:          V3{100, 0, 100},
+		Radius:          20,
+		HeightVariation: 2,
+		Speed:           10,
+		IdleProbability: 0.5,
+		MaxIdle:         time.Second,
+		Rand:            rand.New(rand.NewSource(1)),
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		avatar.Update(100 * time.Millisecond)
+
+		p := avatar.Position()
+		assert.True(t, V3{p.X, 0, p.Z}.Sub(V3{100, 0, 100}).Length() <= 20.000001)
+		assert.True(t, p.Y >= defaultEyeHeight && p.Y <= defaultEyeHeight+2)
+	}
+}
+
+func TestRandomCheckpointsStayInArea(t *testing.T) {
+	options := &MovementOptions{Center: V3{100, 0, 100}, Radius: 20, Rand: rand.New(rand.NewSource(1))}
+
+	checkpoints := options.RandomCheckpoints(100)
+	require.Len(t, checkpoints, 100)
+
+	for _, p := range checkpoints {
+		assert.True(t, V3{p.X, 0, p.Z}.Sub(V3{100, 0, 100}).Length() <= 20.000001)
+		assert.Equal(t, defaultEyeHeight, p.Y)
+	}
+}
+
+func TestJumpReturnsToTheGround(t *testing.T) {
+	avatar, err := NewAvatar(&MovementOptions{
+		Mode:            CheckpointsMovement,
+		Checkpoints:     []V3{{0, 1.6, 0}, {1000, 1.6, 0}},
+		Speed:           1,
+		JumpProbability: 1000,
+		JumpHeight:      1,
+	})
+	require.NoError(t, err)
+
+	avatar.Update(100 * time.Millisecond)
+	assert.True(t, avatar.Position().Y > 1.6)
+
+	maxY := 0.0
+	for i := 0; i < 10; i++ {
+		avatar.Update(100 * time.Millisecond)
+		maxY = math.Max(maxY, avatar.Position().Y)
+	}
+
+	assert.InDelta(t, 2.6, maxY, 0.1)
+
+	avatar.options.JumpProbability = 0
+	for i := 0; i < 10; i++ {
+		avatar.Update(100 * time.Millisecond)
+	}
+
+	assert.Equal(t, 1.6, avatar.Position().Y)
+}
+
+type fixedPosition struct {
+	p V3
+}
+
+func (l *fixedPosition) Position() V3 {
+	return l.p
+}
+
+func TestFollowMovement(t *testing.T) {
+	leader := &fixedPosition{V3{0, 1.6, 0}}
+
+	avatar, err := NewAvatar(&MovementOptions{
+		Mode:           FollowMovement,
+		Leader:         leader,
+		FollowDistance: 2,
+		Speed:          10,
+	})
+	require.NoError(t, err)
+	assert.True(t, leader.p.Sub(avatar.Position()).Length() <= 2)
+
+	leader.p = V3{50, 1.6, 0}
+
+	for i := 0; i < 100; i++ {
+		avatar.Update(100 * time.Millisecond)
+	}
+
+	assert.InDelta(t, 2, leader.p.Sub(avatar.Position()).Length(), 1e-6)
+	assert.Equal(t, LookRotation(leader.p.Sub(avatar.Position())), avatar.Rotation())
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// DefaultPassphraseEnv is the env var the passphrase of an encrypted key is read from, unless
+// PassphraseConfig.Env is set
+const DefaultPassphraseEnv = "DCL_KEY_PASSPHRASE"
+
+// PassphraseConfig is where the passphrase of an encrypted key is read from, the first available of
+// File, the Env var and a prompt, if Prompt is set
+type PassphraseConfig struct {
+	File   string
+	Env    string
+	Prompt bool
+}
+
+// Read returns the passphrase, the prompt is only used if there is no file or env var
+func (c *PassphraseConfig) Read(prompt string, confirm bool) (string, error) {
+	if c.File != "" {
+		content, err := ioutil.ReadFile(c.File)
+		if err != nil {
+			return "", fmt.Errorf("cannot read passphrase file: %v", err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	env := c.Env
+	if env == "" {
+		env = DefaultPassphraseEnv
+	}
+
+	if passphrase, ok := os.LookupEnv(env); ok {
+		return passphrase, nil
+	}
+
+	if !c.Prompt {
+		return "", fmt.Errorf("missing passphrase, set %s or use a passphrase file", env)
+	}
+
+	return PromptPassphrase(prompt, confirm)
+}
+
+// PromptPassphrase reads a passphrase from the terminal without echo, or a line from stdin if it's
+// not a terminal. With confirm it's read twice and both have to match.
+func PromptPassphrase(prompt string, confirm bool) (string, error) {
+	passphrase, err := readPassphrase(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		repeated, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+
+		if repeated != passphrase {
+			return "", errors.New("passphrases don't match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+func readPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+
+	if !terminal.IsTerminal(fd) {
+		line, err := stdinReader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("cannot read passphrase: %v", err)
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		return "", fmt.Errorf("cannot read passphrase: %v", err)
+	}
+
+	return string(passphrase), nil
+}
@@ -1,6 +1,9 @@
 package cli
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,75 +11,142 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/decentraland/auth-go/pkg/ephemeral"
+	"github.com/xeipuuv/gojsonschema"
 )
 
-type ProfileClient struct {
-	ProfileURL   string
-	EphemeralKey *ephemeral.EphemeralKey
-}
+// maxErrorBodySize is the max size of a non json error response body included in an HTTPError
+const maxErrorBodySize = 512
 
-func (pc *ProfileClient) StoreProfile(accessToken string, body io.Reader) error {
-	u, err := url.Parse(pc.ProfileURL)
+// ProfileHash returns the content hash of a profile, the hex sha256 of its json encoding, which has
+// the keys sorted
+func ProfileHash(profile map[string]interface{}) (string, error) {
+	encoded, err := json.Marshal(profile)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	u.Path = path.Join(u.Path, "/profile")
-	req, err := http.NewRequest("POST", u.String(), body)
-	if err != nil {
-		return err
+	hash := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// HTTPError is a non successful response of the profile service
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Message is the errors field of a json response, or the response body otherwise
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("http error %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
 	}
 
-	if err = pc.EphemeralKey.AddRequestHeaders(req, accessToken); err != nil {
-		return err
+	return fmt.Sprintf("http error %s %s: %d %s, %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode),
+		e.Message)
+}
+
+func newHTTPError(req *http.Request, resp *http.Response, body []byte) *HTTPError {
+	err := &HTTPError{Method: req.Method, URL: req.URL.String(), StatusCode: resp.StatusCode}
+
+	response := make(map[string]interface{})
+	if json.Unmarshal(body, &response) == nil {
+		for _, key := range []string{"errors", "error", "message"} {
+			if v, ok := response[key]; ok {
+				err.Message = fmt.Sprintf("%v", v)
+				return err
+			}
+		}
 	}
 
-	c := http.Client{
-		Timeout: time.Second * 10,
+	message := strings.TrimSpace(string(body))
+	if len(message) > maxErrorBodySize {
+		message = message[:maxErrorBodySize] + "..."
 	}
 
-	resp, err := c.Do(req)
+	err.Message = message
+
+	return err
+}
+
+// ProfileValidationError lists the schema violations of a profile
+type ProfileValidationError struct {
+	Errors []string
+}
+
+func (e *ProfileValidationError) Error() string {
+	return fmt.Sprintf("invalid profile: %s", strings.Join(e.Errors, "; "))
+}
+
+// LoadProfileSchema reads a profile json schema file
+func LoadProfileSchema(path string) (*gojsonschema.Schema, error) {
+	content, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer resp.Body.Close()
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile schema %s: %v", path, err)
+	}
 
-	respBuff, err := ioutil.ReadAll(resp.Body)
+	return schema, nil
+}
+
+// ValidateProfile checks profile against schema, it returns a ProfileValidationError if it doesn't
+// conform
+func ValidateProfile(schema *gojsonschema.Schema, profile []byte) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(profile))
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid profile json: %v", err)
 	}
 
-	if resp.StatusCode != http.StatusNoContent {
-		response := make(map[string]interface{})
-		if err := json.Unmarshal(respBuff, &response); err != nil {
-			return err
-		}
+	if result.Valid() {
+		return nil
+	}
 
-		err := fmt.Errorf("http error %s %s %d, %v", u, resp.Status, resp.StatusCode, response["errors"])
-		return err
+	validationErr := &ProfileValidationError{}
+	for _, e := range result.Errors() {
+		validationErr.Errors = append(validationErr.Errors, e.String())
 	}
 
-	return nil
+	return validationErr
 }
 
-func (pc *ProfileClient) RetrieveProfile(accessToken string) (map[string]interface{}, error) {
+type ProfileClient struct {
+	ProfileURL   string
+	EphemeralKey *ephemeral.EphemeralKey
+
+	// Schema validates the profiles before storing them, if set
+	Schema *gojsonschema.Schema
+}
+
+func (pc *ProfileClient) do(method string, accessToken string, body []byte) (*http.Request, *http.Response, []byte, error) {
 	u, err := url.Parse(pc.ProfileURL)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	u.Path = path.Join(u.Path, "/profile")
-	req, err := http.NewRequest("GET", u.String(), nil)
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, u.String(), reqBody)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	if err = pc.EphemeralKey.AddRequestHeaders(req, accessToken); err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	c := http.Client{
@@ -85,24 +155,60 @@ func (pc *ProfileClient) RetrieveProfile(accessToken string) (map[string]interfa
 
 	resp, err := c.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	defer resp.Body.Close()
 
 	respBuff, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	response := make(map[string]interface{})
-	if err := json.Unmarshal(respBuff, &response); err != nil {
+	return req, resp, respBuff, nil
+}
+
+// StoreProfile validates the profile, if there is a Schema, and stores it. A non successful response
+// is returned as an HTTPError.
+func (pc *ProfileClient) StoreProfile(accessToken string, body io.Reader) error {
+	profile, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	if pc.Schema != nil {
+		if err := ValidateProfile(pc.Schema, profile); err != nil {
+			return err
+		}
+	}
+
+	req, resp, respBuff, err := pc.do("POST", accessToken, profile)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return newHTTPError(req, resp, respBuff)
+	}
+
+	return nil
+}
+
+// RetrieveProfile returns the stored profile. A non successful response is returned as an
+// HTTPError.
+func (pc *ProfileClient) RetrieveProfile(accessToken string) (map[string]interface{}, error) {
+	req, resp, respBuff, err := pc.do("GET", accessToken, nil)
+	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("http error %s %s %d", u, resp.Status, resp.StatusCode)
-		return nil, err
+		return nil, newHTTPError(req, resp, respBuff)
+	}
+
+	response := make(map[string]interface{})
+	if err := json.Unmarshal(respBuff, &response); err != nil {
+		return nil, fmt.Errorf("invalid profile response: %v", err)
 	}
 
 	return response, nil
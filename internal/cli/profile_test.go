@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/decentraland/auth-go/pkg/ephemeral"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileHash(t *testing.T) {
+	a, err := ProfileHash(map[string]interface{}{"name": "bot", "avatar": map[string]interface{}{"x": 1, "y": 2}})
+	require.NoError(t, err)
+
+	b, err := ProfileHash(map[string]interface{}{"avatar": map[string]interface{}{"y": 2, "x": 1}, "name": "bot"})
+	require.NoError(t, err)
+
+	c, err := ProfileHash(map[string]interface{}{"name": "other"})
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b, "the hash doesn't depend on the keys order")
+	assert.NotEqual(t, a, c)
+	assert.Len(t, a, 64)
+}
+
+func TestValidateProfile(t *testing.T) {
+	schema, err := LoadProfileSchema("../../config/profile/schema.json")
+	require.NoError(t, err)
+
+	valid := `{"name": "bot", "avatar": {"bodyShape": "dcl://base-avatars/BaseMale", "wearables": []}}`
+	assert.NoError(t, ValidateProfile(schema, []byte(valid)))
+
+	err = ValidateProfile(schema, []byte(`{"name": "", "avatar": {"wearables": []}}`))
+	require.IsType(t, &ProfileValidationError{}, err)
+	assert.Len(t, err.(*ProfileValidationError).Errors, 2)
+
+	assert.Error(t, ValidateProfile(schema, []byte(`{`)))
+}
+
+func TestProfileClient(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	key, err := ephemeral.NewEphemeralKey(&ephemeral.EphemeralKeyConfig{PrivateKey: privateKey})
+	require.NoError(t, err)
+
+	status := http.StatusOK
+	body := `{"name": "bot"}`
+	posted := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/profile", r.URL.Path)
+		if r.Method == "POST" {
+			posted++
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	schema, err := LoadProfileSchema("../../config/profile/schema.json")
+	require.NoError(t, err)
+
+	client := ProfileClient{ProfileURL: server.URL, EphemeralKey: key, Schema: schema}
+
+	profile, err := client.RetrieveProfile("token")
+	require.NoError(t, err)
+	assert.Equal(t, "bot", profile["name"])
+
+	err = client.StoreProfile("token", strings.NewReader(`{"name": "bot"}`))
+	require.IsType(t, &ProfileValidationError{}, err)
+	assert.Equal(t, 0, posted, "invalid profiles are not posted")
+
+	status = http.StatusBadRequest
+	body = `{"errors": "invalid avatar"}`
+	err = client.StoreProfile("token", strings.NewReader(`{"name": "bot", "avatar": {"bodyShape": "a", "wearables": []}}`))
+	require.IsType(t, &HTTPError{}, err)
+	assert.Equal(t, 1, posted)
+	assert.Equal(t, http.StatusBadRequest, err.(*HTTPError).StatusCode)
+	assert.Equal(t, "invalid avatar", err.(*HTTPError).Message)
+
+	status = http.StatusInternalServerError
+	body = "internal error\n"
+	_, err = client.RetrieveProfile("token")
+	require.IsType(t, &HTTPError{}, err)
+	assert.Equal(t, "internal error", err.(*HTTPError).Message)
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GetProfileField returns the value of a profile field, the path is a dot separated list of object
+// keys and array indexes, e.g. avatar.wearables.0
+func GetProfileField(profile map[string]interface{}, fieldPath string) (interface{}, error) {
+	var value interface{} = profile
+
+	for _, key := range strings.Split(fieldPath, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			field, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("field not found: %s", fieldPath)
+			}
+
+			value = field
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("invalid index %s in %s", key, fieldPath)
+			}
+
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("field not found: %s", fieldPath)
+		}
+	}
+
+	return value, nil
+}
+
+// SetProfileField sets the value of a profile field, creating the missing objects in the path
+func SetProfileField(profile map[string]interface{}, fieldPath string, value interface{}) error {
+	keys := strings.Split(fieldPath, ".")
+	last := len(keys) - 1
+
+	var parent interface{} = profile
+
+	for i, key := range keys {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if i == last {
+				p[key] = value
+				return nil
+			}
+
+			child, ok := p[key]
+			if !ok {
+				child = make(map[string]interface{})
+				p[key] = child
+			}
+
+			parent = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(p) {
+				return fmt.Errorf("invalid index %s in %s", key, fieldPath)
+			}
+
+			if i == last {
+				p[index] = value
+				return nil
+			}
+
+			parent = p[index]
+		default:
+			return fmt.Errorf("%s is not an object or array", strings.Join(keys[:i], "."))
+		}
+	}
+
+	return nil
+}
+
+// ParseFieldValue parses a field value as json, falling back to a plain string
+func ParseFieldValue(s string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return s
+	}
+
+	return value
+}
+
+// ProfileDiff is a field that differs between two profiles, Old is missing if the field was added
+// and New is missing if it was removed
+type ProfileDiff struct {
+	Path   string
+	Old    interface{}
+	New    interface{}
+	HasOld bool
+	HasNew bool
+}
+
+func (d ProfileDiff) String() string {
+	format := func(v interface{}) string {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+
+		return string(encoded)
+	}
+
+	switch {
+	case !d.HasOld:
+		return fmt.Sprintf("+ %s: %s", d.Path, format(d.New))
+	case !d.HasNew:
+		return fmt.Sprintf("- %s: %s", d.Path, format(d.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", d.Path, format(d.Old), format(d.New))
+	}
+}
+
+// DiffProfiles returns the fields that differ from a to b, sorted by path
+func DiffProfiles(a map[string]interface{}, b map[string]interface{}) []ProfileDiff {
+	diffs := []ProfileDiff{}
+	diffValues("", a, b, &diffs)
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+
+	return diffs
+}
+
+func diffValues(fieldPath string, a interface{}, b interface{}, diffs *[]ProfileDiff) {
+	join := func(key string) string {
+		if fieldPath == "" {
+			return key
+		}
+
+		return fieldPath + "." + key
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		for key, value := range av {
+			if other, ok := bv[key]; ok {
+				diffValues(join(key), value, other, diffs)
+			} else {
+				*diffs = append(*diffs, ProfileDiff{Path: join(key), Old: value, HasOld: true})
+			}
+		}
+
+		for key, value := range bv {
+			if _, ok := av[key]; !ok {
+				*diffs = append(*diffs, ProfileDiff{Path: join(key), New: value, HasNew: true})
+			}
+		}
+
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			break
+		}
+
+		for i := range av {
+			diffValues(join(strconv.Itoa(i)), av[i], bv[i], diffs)
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, ProfileDiff{Path: fieldPath, Old: a, New: b, HasOld: true, HasNew: true})
+	}
+}
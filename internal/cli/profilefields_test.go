@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileFields(t *testing.T) {
+	profile := map[string]interface{}{
+		"name":   "bot",
+		"avatar": map[string]interface{}{"wearables": []interface{}{"a", "b"}},
+	}
+
+	value, err := GetProfileField(profile, "avatar.wearables.1")
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	_, err = GetProfileField(profile, "avatar.wearables.2")
+	assert.Error(t, err)
+
+	_, err = GetProfileField(profile, "name.first")
+	assert.Error(t, err)
+
+	require.NoError(t, SetProfileField(profile, "avatar.hair.color", ParseFieldValue(`{"r": 1}`)))
+	require.NoError(t, SetProfileField(profile, "avatar.wearables.0", ParseFieldValue("c")))
+	assert.Error(t, SetProfileField(profile, "name.first", "x"))
+
+	value, err = GetProfileField(profile, "avatar.hair.color.r")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), value)
+	assert.Equal(t, []interface{}{"c", "b"}, profile["avatar"].(map[string]interface{})["wearables"])
+}
+
+func TestDiffProfiles(t *testing.T) {
+	a := map[string]interface{}{
+		"name":   "bot",
+		"email":  "bot@example.com",
+		"avatar": map[string]interface{}{"bodyShape": "male", "wearables": []interface{}{"a"}},
+	}
+
+	b := map[string]interface{}{
+		"name":        "bot",
+		"description": "hi",
+		"avatar":      map[string]interface{}{"bodyShape": "female", "wearables": []interface{}{"a", "b"}},
+	}
+
+	diffs := DiffProfiles(a, b)
+	require.Len(t, diffs, 4)
+	assert.Equal(t, `~ avatar.bodyShape: "male" -> "female"`, diffs[0].String())
+	assert.Equal(t, `~ avatar.wearables: ["a"] -> ["a","b"]`, diffs[1].String())
+	assert.Equal(t, `+ description: "hi"`, diffs[2].String())
+	assert.Equal(t, `- email: "bot@example.com"`, diffs[3].String())
+
+	assert.Len(t, DiffProfiles(a, a), 0)
+}
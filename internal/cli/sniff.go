@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+
+	"github.com/decentraland/webrtc-broker/pkg/authentication"
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/webrtc-broker/pkg/simulation"
+	"github.com/decentraland/world/pkg/protocol"
+	pion "github.com/pion/webrtc/v2"
+)
+
+// SniffedMessage is a decoded topic message
+type SniffedMessage struct {
+	Time     time.Time
+	Alias    uint64
+	Identity string
+	Reliable bool
+	Category protocol.Category
+	Data     proto.Message
+}
+
+// DecodeMessage decodes a TOPIC_FW or TOPIC_IDENTITY_FW message and its data, it returns nil for
+// any other message type
+func DecodeMessage(reliable bool, msgType broker.MessageType, raw []byte) (*SniffedMessage, error) {
+	msg := &SniffedMessage{Time: time.Now(), Reliable: reliable}
+
+	var body []byte
+
+	switch msgType {
+	case broker.MessageType_TOPIC_FW:
+		message := broker.TopicFWMessage{}
+		if err := proto.Unmarshal(raw, &message); err != nil {
+			return nil, fmt.Errorf("error unmarshalling topic fw message: %v", err)
+		}
+
+		msg.Alias = message.FromAlias
+		body = message.Body
+	case broker.MessageType_TOPIC_IDENTITY_FW:
+		message := broker.TopicIdentityFWMessage{}
+		if err := proto.Unmarshal(raw, &message); err != nil {
+			return nil, fmt.Errorf("error unmarshalling topic identity fw message: %v", err)
+		}
+
+		msg.Alias = message.FromAlias
+		msg.Identity = string(message.Identity)
+		body = message.Body
+	default:
+		return nil, nil
+	}
+
+	dataHeader := protocol.DataHeader{}
+	if err := proto.Unmarshal(body, &dataHeader); err != nil {
+		return nil, fmt.Errorf("error unmarshalling data header: %v", err)
+	}
+
+	msg.Category = dataHeader.Category
+
+	switch dataHeader.Category {
+	case protocol.Category_POSITION:
+		msg.Data = &protocol.PositionData{}
+	case protocol.Category_COMPACT_POSITION:
+		msg.Data = &protocol.CompactPositionData{}
+	case protocol.Category_PROFILE:
+		msg.Data = &protocol.ProfileData{}
+	case protocol.Category_CHAT:
+		msg.Data = &protocol.ChatData{}
+	case protocol.Category_DIRECT_MESSAGE:
+		msg.Data = &protocol.DirectMessageData{}
+	case protocol.Category_DIRECT_MESSAGE_ACK:
+		msg.Data = &protocol.DirectMessageAckData{}
+	default:
+		msg.Data = &dataHeader
+		return msg, nil
+	}
+
+	if err := proto.Unmarshal(body, msg.Data); err != nil {
+		return nil, fmt.Errorf("error unmarshalling %s data: %v", dataHeader.Category, err)
+	}
+
+	return msg, nil
+}
+
+// String returns a human readable representation of the message
+func (m *SniffedMessage) String() string {
+	channel := "unreliable"
+	if m.Reliable {
+		channel = "reliable"
+	}
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%s [%s] alias=%d", m.Time.Format("15:04:05.000"), channel, m.Alias)
+
+	if m.Identity != "" {
+		fmt.Fprintf(&b, " identity=%s", m.Identity)
+	}
+
+	fmt.Fprintf(&b, " %s", m.Category)
+
+	switch data := m.Data.(type) {
+	case *protocol.PositionData:
+		fmt.Fprintf(&b, " position=(%.2f, %.2f, %.2f) rotation=(%.2f, %.2f, %.2f, %.2f)",
+			data.PositionX, data.PositionY, data.PositionZ,
+			data.RotationX, data.RotationY, data.RotationZ, data.RotationW)
+	case *protocol.CompactPositionData:
+		fmt.Fprintf(&b, " sequence=%d baseline=%d cell=(%d, %d) position=(%d, %d, %d) rotation=%08x",
+			data.Sequence, data.Baseline, data.CellX, data.CellZ,
+			data.PositionX, data.PositionY, data.PositionZ, data.Rotation)
+	case *protocol.ProfileData:
+		fmt.Fprintf(&b, " version=%s hash=%s", data.ProfileVersion, data.Hash)
+
+		if data.SnapshotUrl != "" {
+			fmt.Fprintf(&b, " snapshot=%s", data.SnapshotUrl)
+		}
+	case *protocol.ChatData:
+		fmt.Fprintf(&b, " id=%s text=%q", data.MessageId, data.Text)
+	case *protocol.DirectMessageData:
+		fmt.Fprintf(&b, " id=%s recipient=%s text=%q", data.MessageId, data.Recipient, data.Text)
+	case *protocol.DirectMessageAckData:
+		fmt.Fprintf(&b, " id=%s recipient=%s", data.MessageId, data.Recipient)
+	}
+
+	return b.String()
+}
+
+// MarshalJSON encodes the message as a single json object, the data is encoded using the proto
+// json mapping
+func (m *SniffedMessage) MarshalJSON() ([]byte, error) {
+	marshaler := jsonpb.Marshaler{OrigName: true}
+
+	data := bytes.Buffer{}
+	if err := marshaler.Marshal(&data, m.Data); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Time     time.Time       `json:"time"`
+		Alias    uint64          `json:"alias"`
+		Identity string          `json:"identity,omitempty"`
+		Reliable bool            `json:"reliable"`
+		Category string          `json:"category"`
+		Data     json.RawMessage `json:"data"`
+	}{
+		Time:     m.Time,
+		Alias:    m.Alias,
+		Identity: m.Identity,
+		Reliable: m.Reliable,
+		Category: m.Category.String(),
+		Data:     data.Bytes(),
+	})
+}
+
+// SniffFilter selects the messages to print, empty fields match everything
+type SniffFilter struct {
+	Categories map[protocol.Category]bool
+	Aliases    map[uint64]bool
+
+	// Text is matched, case insensitive, against the chat text, any other category is discarded
+	Text string
+}
+
+// Match returns true if the message passes the filter
+func (f *SniffFilter) Match(m *SniffedMessage) bool {
+	if len(f.Categories) > 0 && !f.Categories[m.Category] {
+		return false
+	}
+
+	if len(f.Aliases) > 0 && !f.Aliases[m.Alias] {
+		return false
+	}
+
+	if f.Text != "" {
+		chat, ok := m.Data.(*protocol.ChatData)
+		if !ok {
+			return false
+		}
+
+		return strings.Contains(strings.ToLower(chat.Text), strings.ToLower(f.Text))
+	}
+
+	return true
+}
+
+// SnifferOptions is the sniffer configuration
+type SnifferOptions struct {
+	Auth           authentication.ClientAuthenticator
+	CoordinatorURL string
+	Subscription   map[string]bool
+	Filter         SniffFilter
+	NDJSON         bool
+	Output         io.Writer
+	Log            zerolog.Logger
+}
+
+// StartSniffer subscribes to the given topics and prints every message that passes the filter to
+// opts.Output, one per line, until ctx is done
+func StartSniffer(ctx context.Context, opts SnifferOptions) error {
+	log := opts.Log
+	messagesCh := make(chan *SniffedMessage, 256)
+
+	config := simulation.Config{
+		Auth:           opts.Auth,
+		CoordinatorURL: opts.CoordinatorURL,
+		ICEServers: []pion.ICEServer{
+			{
+				URLs: []string{"stun:stun.l.google.com:19302"},
+			},
+		},
+		Log: log,
+		OnMessageReceived: UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+			msg, err := DecodeMessage(reliable, msgType, raw)
+			if err != nil {
+				log.Error().Err(err).Msg("cannot decode message")
+				return
+			}
+
+			if msg == nil || !opts.Filter.Match(msg) {
+				return
+			}
+
+			select {
+			case messagesCh <- msg:
+			case <-ctx.Done():
+			}
+		}),
+	}
+
+	client := simulation.Start(&config)
+	defer StopClient(client)
+
+	if err := client.SendTopicSubscriptionMessage(opts.Subscription); err != nil {
+		return fmt.Errorf("subscription failed: %v", err)
+	}
+
+	encoder := json.NewEncoder(opts.Output)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-messagesCh:
+			var err error
+			if opts.NDJSON {
+				err = encoder.Encode(msg)
+			} else {
+				_, err = fmt.Fprintln(opts.Output, msg)
+			}
+
+			if err != nil {
+				return fmt.Errorf("cannot write message: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func encodeFW(t *testing.T, alias uint64, data proto.Message) []byte {
+	body, err := proto.Marshal(data)
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&broker.TopicFWMessage{Type: broker.MessageType_TOPIC_FW, FromAlias: alias, Body: body})
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestDecodeMessage(t *testing.T) {
+	raw := encodeFW(t, 3, &protocol.ChatData{Category: protocol.Category_CHAT, MessageId: "1", Text: "Hello there"})
+
+	msg, err := DecodeMessage(true, broker.MessageType_TOPIC_FW, raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), msg.Alias)
+	assert.Equal(t, protocol.Category_CHAT, msg.Category)
+	assert.Equal(t, "Hello there", msg.Data.(*protocol.ChatData).Text)
+	assert.Contains(t, msg.String(), `alias=3 CHAT id=1 text="Hello there"`)
+
+	encoded, err := json.Marshal(msg)
+	require.NoError(t, err)
+
+	decoded := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, "CHAT", decoded["category"])
+	assert.Equal(t, "Hello there", decoded["data"].(map[string]interface{})["text"])
+
+	body, err := proto.Marshal(&protocol.ProfileData{Category: protocol.Category_PROFILE, ProfileVersion: "2"})
+	require.NoError(t, err)
+
+	raw, err = proto.Marshal(&broker.TopicIdentityFWMessage{
+		Type:      broker.MessageType_TOPIC_IDENTITY_FW,
+		FromAlias: 4,
+		Identity:  []byte("user|1"),
+		Body:      body,
+	})
+	require.NoError(t, err)
+
+	msg, err = DecodeMessage(true, broker.MessageType_TOPIC_IDENTITY_FW, raw)
+	require.NoError(t, err)
+	assert.Equal(t, "user|1", msg.Identity)
+	assert.Equal(t, "2", msg.Data.(*protocol.ProfileData).ProfileVersion)
+
+	msg, err = DecodeMessage(true, broker.MessageType_PING, raw)
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+}
+
+func TestSniffFilter(t *testing.T) {
+	chat := &SniffedMessage{Alias: 1, Category: protocol.Category_CHAT, Data: &protocol.ChatData{Text: "Hello"}}
+	position := &SniffedMessage{Alias: 2, Category: protocol.Category_POSITION, Data: &protocol.PositionData{}}
+
+	filter := SniffFilter{}
+	assert.True(t, filter.Match(chat))
+	assert.True(t, filter.Match(position))
+
+	filter = SniffFilter{Categories: map[protocol.Category]bool{protocol.Category_POSITION: true}}
+	assert.False(t, filter.Match(chat))
+	assert.True(t, filter.Match(position))
+
+	filter = SniffFilter{Aliases: map[uint64]bool{1: true}}
+	assert.True(t, filter.Match(chat))
+	assert.False(t, filter.Match(position))
+
+	filter = SniffFilter{Text: "hell"}
+	assert.True(t, filter.Match(chat))
+	assert.False(t, filter.Match(position))
+
+	filter = SniffFilter{Text: "bye"}
+	assert.False(t, filter.Match(chat))
+}
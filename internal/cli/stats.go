@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/golang/protobuf/proto"
+
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/webrtc-broker/pkg/simulation"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+// PositionStatsReporter is called periodically with the stats of every peer seen so far
+type PositionStatsReporter = func(peers map[uint64]*simulation.Stats)
+
+// NewTrackChannel returns a buffered channel and a simulation.Config.OnMessageReceived callback that
+// feeds it with every unreliable TOPIC_FW message, giving up once ctx is done
+func NewTrackChannel(ctx context.Context) (chan []byte, func(bool, broker.MessageType, []byte)) {
+	trackCh := make(chan []byte, 256)
+
+	onMessageReceived := func(reliable bool, msgType broker.MessageType, raw []byte) {
+		if reliable || msgType != broker.MessageType_TOPIC_FW {
+			return
+		}
+
+		select {
+		case trackCh <- raw:
+		case <-ctx.Done():
+		}
+	}
+
+	return trackCh, UnbatchMessages(onMessageReceived)
+}
+
+// TrackPositionStats consumes trackCh computing the avg frequency of the position messages of each
+// peer. It calls report every reportPeriod and one last time when ctx is done.
+func TrackPositionStats(ctx context.Context, log logging.Logger, trackCh chan []byte,
+	reportPeriod time.Duration, report PositionStatsReporter) {
+	peers := make(map[uint64]*simulation.Stats)
+	topicFwMessage := broker.TopicFWMessage{}
+	dataHeader := protocol.DataHeader{}
+
+	onMessage := func(rawMsg []byte) {
+		if err := proto.Unmarshal(rawMsg, &topicFwMessage); err != nil {
+			log.Error().Err(err).Msg("error unmarshalling data message")
+			return
+		}
+
+		if err := proto.Unmarshal(topicFwMessage.Body, &dataHeader); err != nil {
+			log.Error().Err(err).Msg("error unmarshalling data header")
+			return
+		}
+
+		if dataHeader.Category != protocol.Category_POSITION {
+			return
+		}
+
+		alias := topicFwMessage.FromAlias
+		stats := peers[alias]
+
+		if stats == nil {
+			stats = &simulation.Stats{}
+			peers[alias] = stats
+		}
+
+		stats.Seen(time.Now())
+	}
+
+	reportTicker := time.NewTicker(reportPeriod)
+	defer reportTicker.Stop()
+
+	for {
+		select {
+		case rawMsg := <-trackCh:
+			onMessage(rawMsg)
+
+			n := len(trackCh)
+			for i := 0; i < n; i++ {
+				rawMsg = <-trackCh
+				onMessage(rawMsg)
+			}
+		case <-reportTicker.C:
+			report(peers)
+
+			for alias, stats := range peers {
+				if time.Since(stats.LastSeen).Seconds() > 1 {
+					delete(peers, alias)
+				}
+			}
+		case <-ctx.Done():
+			report(peers)
+			return
+		}
+	}
+}
+
+// StopClient stops the write pumps of a simulation client, and closes its peer connection and its
+// coordinator websocket, which stops the read pumps too
+func StopClient(client *simulation.Client) {
+	close(client.StopReliableQueue)
+	close(client.StopUnreliableQueue)
+
+	// NOTE: the simulation client doesn't expose its connections, simulation.Start sets both before
+	// returning, so they are read once it's done
+	for _, name := range []string{"conn", "coordinator"} {
+		if closer := clientConnection(client, name); closer != nil {
+			closer.Close()
+		}
+	}
+}
+
+// clientConnection returns the connection in the unexported field name of client, nil if it's not
+// set
+func clientConnection(client *simulation.Client, name string) io.Closer {
+	field := reflect.ValueOf(client).Elem().FieldByName(name)
+	if !field.IsValid() || field.IsNil() {
+		return nil
+	}
+
+	closer, _ := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface().(io.Closer)
+
+	return closer
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	pion "github.com/pion/webrtc/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	brokerAuth "github.com/decentraland/webrtc-broker/pkg/authentication"
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/webrtc-broker/pkg/simulation"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func TestTrackPositionStatsReportsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	trackCh, onMessageReceived := NewTrackChannel(ctx)
+
+	body, err := proto.Marshal(&protocol.PositionData{Category: protocol.Category_POSITION})
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&broker.TopicFWMessage{
+		Type:      broker.MessageType_TOPIC_FW,
+		FromAlias: 7,
+		Body:      body,
+	})
+	require.NoError(t, err)
+
+	onMessageReceived(false, broker.MessageType_TOPIC_FW, raw)
+	onMessageReceived(false, broker.MessageType_TOPIC_FW, raw)
+	onMessageReceived(true, broker.MessageType_TOPIC_FW, raw)
+
+	reported := make(chan []uint64, 1)
+	done := make(chan bool)
+
+	go func() {
+		TrackPositionStats(ctx, zerolog.Nop(), trackCh, time.Hour, func(peers map[uint64]*simulation.Stats) {
+			aliases := []uint64{}
+			for alias := range peers {
+				aliases = append(aliases, alias)
+			}
+			reported <- aliases
+		})
+		close(done)
+	}()
+
+	for len(trackCh) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("tracker didn't stop on cancel")
+	}
+
+	assert.Equal(t, []uint64{7}, <-reported)
+
+	// NOTE: once ctx is done the callback must not block the simulation read pump
+	for i := 0; i < 300; i++ {
+		onMessageReceived(false, broker.MessageType_TOPIC_FW, raw)
+	}
+}
+
+func setClientField(client *simulation.Client, name string, value interface{}) {
+	field := reflect.ValueOf(client).Elem().FieldByName(name)
+	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(value))
+}
+
+func TestStopClient(t *testing.T) {
+	closed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := simulation.MakeClient(&simulation.Config{Auth: &brokerAuth.NoopAuthenticator{}, Log: zerolog.Nop()})
+	StopClient(client)
+
+	client = simulation.MakeClient(&simulation.Config{Auth: &brokerAuth.NoopAuthenticator{}, Log: zerolog.Nop()})
+
+	coordinator, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	setClientField(client, "coordinator", coordinator)
+
+	conn, err := pion.NewPeerConnection(pion.Configuration{})
+	require.NoError(t, err)
+	setClientField(client, "conn", conn)
+
+	StopClient(client)
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the coordinator websocket is still open")
+	}
+
+	assert.Equal(t, pion.PeerConnectionStateClosed, conn.ConnectionState())
+}
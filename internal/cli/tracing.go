@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"sync"
+
+	"github.com/decentraland/webrtc-broker/pkg/authentication"
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"go.opentelemetry.io/otel/api/trace"
+
+	"github.com/decentraland/world/internal/commons/tracing"
+)
+
+// tracedAuthenticator traces the steps of a simulation client connection as children of ctx. The
+// client asks for the auth message once the coordinator assigned it a server, so the WebRTC
+// negotiation span starts then, and it ends once the data channels are open.
+type tracedAuthenticator struct {
+	ctx  context.Context
+	auth authentication.ClientAuthenticator
+
+	mutex       sync.Mutex
+	negotiation trace.Span
+}
+
+// GenerateClientConnectURL adds the trace context to the connect URL, the coordinator takes it from
+// the query string since the websocket dial sets no custom headers
+func (a *tracedAuthenticator) GenerateClientConnectURL(coordinatorURL string) (string, error) {
+	ctx, span := tracing.Start(a.ctx, "auth.connect_url")
+
+	u, err := a.auth.GenerateClientConnectURL(coordinatorURL)
+	if err == nil {
+		u, err = tracing.InjectURL(a.ctx, u)
+	}
+
+	tracing.EndWithError(ctx, span, err)
+
+	return u, err
+}
+
+// GenerateClientAuthMessage ...
+func (a *tracedAuthenticator) GenerateClientAuthMessage() (*broker.AuthMessage, error) {
+	ctx, span := tracing.Start(a.ctx, "auth.message")
+	m, err := a.auth.GenerateClientAuthMessage()
+	tracing.EndWithError(ctx, span, err)
+
+	_, negotiation := tracing.Start(a.ctx, "webrtc.negotiation")
+
+	a.mutex.Lock()
+	a.negotiation = negotiation
+	a.mutex.Unlock()
+
+	return m, err
+}
+
+func (a *tracedAuthenticator) endNegotiation() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.negotiation != nil {
+		a.negotiation.End()
+		a.negotiation = nil
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -9,9 +10,11 @@ import (
 	auth2 "github.com/decentraland/auth-go/pkg/auth"
 	brokerProtocol "github.com/decentraland/webrtc-broker/pkg/protocol"
 	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/tracing"
 	"github.com/decentraland/world/internal/commons/utils"
 	protocol "github.com/decentraland/world/pkg/protocol"
 	"github.com/golang/protobuf/proto"
+	"go.opentelemetry.io/otel/api/key"
 )
 
 // AuthenticatorConfig is the authenticator configuration
@@ -47,7 +50,9 @@ func MakeAuthenticator(config *AuthenticatorConfig) (*Authenticator, error) {
 	if err != nil {
 		return nil, err
 	}
+	ctx, span := tracing.Start(context.Background(), "identity.public_key", key.String("http.url", pubKeyURL))
 	pubKey, err := utils.ReadRemotePublicKey(pubKeyURL)
+	tracing.EndWithError(ctx, span, err)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read public key from '%s': %v", pubKeyURL, err)
 	}
@@ -74,8 +79,24 @@ func MakeAuthenticator(config *AuthenticatorConfig) (*Authenticator, error) {
 	return a, nil
 }
 
-// AuthenticateFromMessage validates an auth message
-func (a *Authenticator) AuthenticateFromMessage(role brokerProtocol.Role, body []byte) (bool, []byte, error) {
+// approveRequest validates the credentials of a client with the identity provider
+func (a *Authenticator) approveRequest(ctx context.Context, req *auth2.AuthRequest) (auth2.Result, error) {
+	ctx, span := tracing.Start(ctx, "identity.approve_request")
+	result, err := a.provider.ApproveRequest(req)
+	tracing.EndWithError(ctx, span, err)
+
+	return result, err
+}
+
+// AuthenticateFromMessage validates an auth message. The message comes from the broker data
+// channel, which carries no trace context, so it starts a new trace.
+func (a *Authenticator) AuthenticateFromMessage(role brokerProtocol.Role, body []byte) (ok bool, _ []byte, err error) {
+	ctx, span := tracing.Start(context.Background(), "auth.message", key.String("role", role.String()))
+	defer func() {
+		span.SetAttributes(key.Bool("auth.ok", ok))
+		tracing.EndWithError(ctx, span, err)
+	}()
+
 	identity := []byte{}
 	if role == brokerProtocol.Role_COMMUNICATION_SERVER {
 		return a.secret == string(body), identity, nil
@@ -93,7 +114,7 @@ func (a *Authenticator) AuthenticateFromMessage(role brokerProtocol.Role, body [
 		credentials["x-access-token"] = authData.AccessToken
 
 		req := auth2.AuthRequest{Credentials: credentials, Content: []byte{}}
-		result, err := a.provider.ApproveRequest(&req)
+		result, err := a.approveRequest(ctx, &req)
 
 		if err == nil {
 			return true, []byte(result.GetUserID()), nil
@@ -116,7 +137,13 @@ func (a *Authenticator) AuthenticateFromMessage(role brokerProtocol.Role, body [
 }
 
 // AuthenticateFromURL validates an a coordinator request using the endpoint url
-func (a *Authenticator) AuthenticateFromURL(role brokerProtocol.Role, r *http.Request) (bool, error) {
+func (a *Authenticator) AuthenticateFromURL(role brokerProtocol.Role, r *http.Request) (ok bool, err error) {
+	ctx, span := tracing.Start(r.Context(), "auth.url", key.String("role", role.String()))
+	defer func() {
+		span.SetAttributes(key.Bool("auth.ok", ok))
+		tracing.EndWithError(ctx, span, err)
+	}()
+
 	qs := r.URL.Query()
 
 	if role == brokerProtocol.Role_COMMUNICATION_SERVER {
@@ -131,7 +158,7 @@ func (a *Authenticator) AuthenticateFromURL(role brokerProtocol.Role, r *http.Re
 
 		content := fmt.Sprintf("GET:%s", a.connectURL)
 		req := auth2.AuthRequest{Credentials: credentials, Content: []byte(content)}
-		_, err := a.provider.ApproveRequest(&req)
+		_, err := a.approveRequest(ctx, &req)
 		if err == nil {
 			return true, nil
 		}
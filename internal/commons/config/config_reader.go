@@ -91,6 +91,10 @@ func overwriteFields(parent string, f reflect.StructField, v *viper.Viper) error
 		if err := overwriteValue(prefix, f, v, setBoolFlag); err != nil {
 			return err
 		}
+	case reflect.Float64:
+		if err := overwriteValue(prefix, f, v, setFloat64Flag); err != nil {
+			return err
+		}
 
 	default:
 		return fmt.Errorf("invalid field[%s] type[%s]", f.Name, f.Type.Name())
@@ -133,6 +137,10 @@ func setInt64Flag(v *viper.Viper, flagVal string, key string, usage string) {
 	viper.Set(key, flag.Int64(flagVal, viper.GetInt64(key), usage))
 }
 
+func setFloat64Flag(v *viper.Viper, flagVal string, key string, usage string) {
+	viper.Set(key, flag.Float64(flagVal, viper.GetFloat64(key), usage))
+}
+
 func getUsage(tag reflect.StructTag) string {
 	val, ok := tag.Lookup(flagUsage)
 	if ok {
@@ -17,8 +17,9 @@ type parentConfig struct {
 }
 
 type childConfig struct {
-	IntField     int `overwrite-env:"int-env"`
-	IntFlagField int `overwrite-arg:"int-flag"`
+	IntField     int     `overwrite-env:"int-env"`
+	IntFlagField int     `overwrite-arg:"int-flag"`
+	FloatField   float64 `overwrite-env:"float-env"`
 	Child        thirdLevelConfing
 }
 
@@ -37,6 +38,7 @@ func TestReadConfigFromFile(t *testing.T) {
 
 	assert.Equal(t, 1, config.Child.IntField)
 	assert.Equal(t, 0, config.Child.IntFlagField)
+	assert.Equal(t, 1.5, config.Child.FloatField)
 
 	assert.Equal(t, true, config.Child.Child.BoolField)
 	assert.Equal(t, false, config.Child.Child.BoolFlagField)
@@ -1,45 +1,270 @@
 package logging
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/decentraland/world/internal/commons/version"
+)
+
+const (
+	// FormatJSON writes a json object per line
+	FormatJSON = "json"
+	// FormatConsole writes a human readable line, for local runs
+	FormatConsole = "console"
 )
 
 // Logger ...
 type Logger = zerolog.Logger
 
-// LogPanic can be used deferred in the main function to capture panic logs
+// LogPanic can be used deferred in the main function to log a panic of any type, along with its
+// stack trace. The process then exits with status 2, as it would without the recover.
 func LogPanic(log Logger) {
 	if r := recover(); r != nil {
-		err, ok := r.(error)
-		if ok {
-			debug.PrintStack()
-			log.Error().Err(err).Msg("panic")
-		}
+		PanicEvent(log, r, debug.Stack()).Msg("panic")
+		os.Exit(2)
+	}
+}
+
+// PanicEvent returns an error event with the value returned by recover and the stack trace
+func PanicEvent(log Logger, r interface{}, stack []byte) *zerolog.Event {
+	e := log.Error().Str("stack", string(stack))
+
+	if err, ok := r.(error); ok {
+		return e.Err(err)
+	}
+
+	return e.Str("panic", fmt.Sprint(r))
+}
+
+// Config is the log section of the configuration file, shared by every command. The level is
+// set by each command.
+type Config struct {
+	Format string `overwrite-flag:"logFormat" flag-usage:"log format: json or console"`
+
+	File           string `overwrite-flag:"logFile" flag-usage:"log file, empty to log to stdout"`
+	FileMaxSize    int    `overwrite-flag:"logFileMaxSize" flag-usage:"size at which the log file is rotated, in megabytes"`
+	FileMaxBackups int    `overwrite-flag:"logFileMaxBackups" flag-usage:"rotated log files kept, 0 to keep them all"`
+	FileMaxAge     int    `overwrite-flag:"logFileMaxAge" flag-usage:"how long the rotated log files are kept, in days, 0 to keep them"`
+
+	Components string `overwrite-flag:"logComponents" flag-usage:"component levels, as component=level pairs separated by commas"`
+
+	SampleBurst  int `overwrite-flag:"logSampleBurst" flag-usage:"debug and info messages logged per sample period by each component, 0 to disable the sampling"`
+	SamplePeriod int `overwrite-flag:"logSamplePeriod" flag-usage:"log sample period, in seconds"`
+	SampleN      int `overwrite-flag:"logSampleN" flag-usage:"once the burst is reached, 1 in n messages is logged, 0 to drop them"`
+
+	Instance string `overwrite-flag:"instance" flag-usage:"instance id added to every message, the host name by default"`
+}
+
+// LoggerConfig returns the logger configuration of a service
+func (c *Config) LoggerConfig(service string, level string) *LoggerConfig {
+	return &LoggerConfig{
+		Level:           level,
+		Format:          c.Format,
+		File:            c.File,
+		FileMaxSize:     c.FileMaxSize,
+		FileMaxBackups:  c.FileMaxBackups,
+		FileMaxAge:      c.FileMaxAge,
+		ComponentLevels: c.Components,
+		SampleBurst:     c.SampleBurst,
+		SamplePeriod:    time.Duration(c.SamplePeriod) * time.Second,
+		SampleN:         c.SampleN,
+		Service:         service,
+		Instance:        c.Instance,
 	}
 }
 
 // LoggerConfig represents the logger config
 type LoggerConfig struct {
+	// Level is the level of the root logger and the default one of the components, info if empty
 	Level string
+
+	// Format is either FormatJSON, the default, or FormatConsole
+	Format string
+
+	// File is rotated once it reaches FileMaxSize megabytes, the messages go to stdout if it's empty
+	File           string
+	FileMaxSize    int
+	FileMaxBackups int
+	FileMaxAge     int
+
+	// ComponentLevels overrides the level of the component loggers, e.g. "pipeline=debug,broker=warn"
+	ComponentLevels string
+
+	// SampleBurst enables the sampling of the debug and info messages of each logger: only the
+	// first SampleBurst messages of each SamplePeriod are logged, then 1 in SampleN
+	SampleBurst  int
+	SamplePeriod time.Duration
+	SampleN      int
+
+	// Service, Cluster and Instance are added to every message, along with the version. Instance is
+	// the host name if empty.
+	Service  string
+	Cluster  string
+	Instance string
+}
+
+// Loggers creates the loggers of a process, they share the output and the common fields
+type Loggers struct {
+	root   Logger
+	base   Logger
+	level  zerolog.Level
+	levels map[string]zerolog.Level
+	config *LoggerConfig
+	closer io.Closer
+}
+
+func parseComponentLevels(s string) (map[string]zerolog.Level, error) {
+	levels := make(map[string]zerolog.Level)
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid component level '%s'", pair)
+		}
+
+		lvl, err := zerolog.ParseLevel(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+
+		levels[strings.TrimSpace(parts[0])] = lvl
+	}
+
+	return levels, nil
 }
 
-// New returns a new logger
-func New(config *LoggerConfig) (Logger, error) {
-	logger := zerolog.New(os.Stdout)
+// NewLoggers creates the loggers of config
+func NewLoggers(config *LoggerConfig) (*Loggers, error) {
+	lvl := zerolog.InfoLevel
+
+	if config.Level != "" {
+		var err error
+		if lvl, err = zerolog.ParseLevel(config.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	levels, err := parseComponentLevels(config.ComponentLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Loggers{level: lvl, levels: levels, config: config}
+
+	var out io.Writer = os.Stdout
+
+	if config.File != "" {
+		file := &lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.FileMaxSize,
+			MaxBackups: config.FileMaxBackups,
+			MaxAge:     config.FileMaxAge,
+		}
+		out = file
+		l.closer = file
+	}
+
+	switch config.Format {
+	case "", FormatJSON:
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: out, NoColor: config.File != "", TimeFormat: time.RFC3339}
+	default:
+		return nil, fmt.Errorf("unknown log format '%s'", config.Format)
+	}
+
+	instance := config.Instance
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+
+	fields := zerolog.New(out).With().Timestamp().Str("version", version.Version())
+
+	if config.Service != "" {
+		fields = fields.Str("service", config.Service)
+	}
+
+	if config.Cluster != "" {
+		fields = fields.Str("cluster", config.Cluster)
+	}
+
+	if instance != "" {
+		fields = fields.Str("instance", instance)
+	}
+
+	l.base = fields.Logger()
+	l.root = l.sample(l.base.Level(lvl))
+
+	return l, nil
+}
+
+func (l *Loggers) sample(log Logger) Logger {
+	if l.config.SampleBurst <= 0 {
+		return log
+	}
+
+	var next zerolog.Sampler
+	if l.config.SampleN > 0 {
+		next = &zerolog.BasicSampler{N: uint32(l.config.SampleN)}
+	}
+
+	sampler := &zerolog.BurstSampler{
+		Burst:       uint32(l.config.SampleBurst),
+		Period:      l.config.SamplePeriod,
+		NextSampler: next,
+	}
+
+	return log.Sample(zerolog.LevelSampler{DebugSampler: sampler, InfoSampler: sampler})
+}
+
+// Root returns the process logger
+func (l *Loggers) Root() Logger {
+	return l.root
+}
+
+// Component returns the logger of a component, with its own level and sampling
+func (l *Loggers) Component(name string) Logger {
+	lvl, ok := l.levels[name]
+	if !ok {
+		lvl = l.level
+	}
+
+	return l.sample(l.base.With().Str("component", name).Logger().Level(lvl))
+}
+
+// Close closes the log file, if any
+func (l *Loggers) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
 
-	lvl, err := zerolog.ParseLevel(config.Level)
+// New returns the root logger of config and the function that closes its log file, or a stdout
+// logger along with the error if config is invalid
+func New(config *LoggerConfig) (Logger, func() error, error) {
+	l, err := NewLoggers(config)
 	if err != nil {
-		return logger, err
+		return zerolog.New(os.Stdout).With().Timestamp().Logger(), func() error { return nil }, err
 	}
 
-	logger = logger.Level(lvl).With().Timestamp().Logger()
-	return logger, nil
+	return l.Root(), l.Close, nil
 }
 
 func init() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	zerolog.TimestampFieldName = "@timestmap"
+	zerolog.TimestampFieldName = "@timestamp"
 }
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLines(t *testing.T, path string) []map[string]interface{} {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	lines := []map[string]interface{}{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func TestLoggers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	loggers, err := NewLoggers(&LoggerConfig{
+		Level:           "info",
+		File:            path,
+		ComponentLevels: "pipeline=debug, broker=error",
+		SampleBurst:     2,
+		SamplePeriod:    time.Hour,
+		Service:         "commserver",
+		Cluster:         "local",
+		Instance:        "server-1",
+	})
+	require.NoError(t, err)
+
+	root := loggers.Root()
+	root.Debug().Msg("root debug")
+	root.Info().Msg("root info")
+
+	pipeline := loggers.Component("pipeline")
+	pipeline.Debug().Msg("pipeline 1")
+	pipeline.Debug().Msg("pipeline 2")
+	pipeline.Debug().Msg("pipeline 3")
+	pipeline.Error().Msg("pipeline error")
+
+	broker := loggers.Component("broker")
+	broker.Warn().Msg("broker warn")
+
+	require.NoError(t, loggers.Close())
+
+	lines := readLines(t, path)
+
+	messages := []string{}
+	for _, line := range lines {
+		messages = append(messages, line["message"].(string))
+	}
+
+	assert.Equal(t, []string{"root info", "pipeline 1", "pipeline 2", "pipeline error"}, messages,
+		"the components have their own levels, and the sampling never drops errors")
+
+	line := lines[1]
+	assert.Equal(t, "pipeline", line["component"])
+	assert.Equal(t, "commserver", line["service"])
+	assert.Equal(t, "local", line["cluster"])
+	assert.Equal(t, "server-1", line["instance"])
+	assert.Contains(t, line, "version")
+	assert.Contains(t, line, "@timestamp")
+}
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	log, closeLog, err := New(&LoggerConfig{Service: "bot", File: path})
+	require.NoError(t, err)
+
+	log.Info().Msg("bot info")
+	require.NoError(t, closeLog())
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "bot info", lines[0]["message"])
+
+	_, closeLog, err = New(&LoggerConfig{Format: "xml"})
+	assert.Error(t, err)
+	assert.NoError(t, closeLog(), "the stdout logger has nothing to close")
+}
+
+func TestInvalidLoggerConfig(t *testing.T) {
+	_, err := NewLoggers(&LoggerConfig{Format: "xml"})
+	assert.Error(t, err)
+
+	_, err = NewLoggers(&LoggerConfig{ComponentLevels: "pipeline"})
+	assert.Error(t, err)
+}
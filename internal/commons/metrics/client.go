@@ -38,6 +38,30 @@ func (c *Client) GaugeUint64(metric string, value uint64, tags []string) {
 	c.gauge(metric, float64(value), tags)
 }
 
+func (c *Client) GaugeFloat64(metric string, value float64, tags []string) {
+	c.gauge(metric, value, tags)
+}
+
+// Count adds value to a counter, DD sums the values sent in each interval, so unlike a gauge it
+// survives the process restarts
+func (c *Client) Count(metric string, value int64, tags []string) {
+	if err := c.client.Count(metric, value, tags, 1); err != nil {
+		c.log.Error().Err(err).Str("name", metric).Msg("error sending metric")
+	}
+}
+
+func (c *Client) Incr(metric string, tags []string) {
+	if err := c.client.Incr(metric, tags, 1); err != nil {
+		c.log.Error().Err(err).Str("name", metric).Msg("error sending metric")
+	}
+}
+
+func (c *Client) Flush() {
+	if err := c.client.Flush(); err != nil {
+		c.log.Error().Err(err).Msg("error flushing DD client")
+	}
+}
+
 func (c *Client) Close() {
 	if err := c.client.Flush(); err != nil {
 		c.log.Error().Err(err).Msg("error flushing DD client")
@@ -0,0 +1,168 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/metrics"
+)
+
+// Policy is what a Supervisor does when one of its goroutines panics
+type Policy string
+
+const (
+	// Restart runs the goroutine again, after a delay. It's only safe for the goroutines that keep
+	// no state, or a panic may leave it half updated or a lock held.
+	Restart Policy = "restart"
+	// Fail exits the process
+	Fail Policy = "fail"
+)
+
+const (
+	defaultRestartDelay    = time.Second
+	defaultMaxRestartDelay = time.Minute
+
+	// healthyRun is how long a goroutine has to run before a panic to reset its restarts and delay
+	healthyRun = 5 * time.Minute
+
+	// exitCode is the status of an unrecovered panic
+	exitCode = 2
+)
+
+// ParsePolicy parses a policy, empty is Restart
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(s) {
+	case "", Restart:
+		return Restart, nil
+	case Fail:
+		return Fail, nil
+	default:
+		return "", fmt.Errorf("unknown panic policy '%s'", s)
+	}
+}
+
+// Config is the supervisor configuration
+type Config struct {
+	// Policy is Restart by default, so the Restart goroutines are restarted. Fail exits the process
+	// on any panic.
+	Policy Policy
+
+	// RestartDelay is the delay before the first restart, it doubles with each consecutive panic up
+	// to MaxRestartDelay
+	RestartDelay    time.Duration
+	MaxRestartDelay time.Duration
+
+	// MaxRestarts is the number of consecutive restarts after which the next panic exits the process,
+	// 0 for no limit
+	MaxRestarts int
+
+	// DDClient is optional, it counts the panics as goroutine.crash
+	DDClient *metrics.Client
+	Tags     []string
+	Log      logging.Logger
+}
+
+// Supervisor runs the long lived goroutines of a process, so a panic in any of them is logged and
+// counted instead of silently killing the process
+type Supervisor struct {
+	config Config
+	exit   func(int)
+}
+
+// New creates a Supervisor
+func New(config *Config) *Supervisor {
+	s := &Supervisor{config: *config, exit: os.Exit}
+
+	if s.config.Policy == "" {
+		s.config.Policy = Restart
+	}
+
+	if s.config.RestartDelay == 0 {
+		s.config.RestartDelay = defaultRestartDelay
+	}
+
+	if s.config.MaxRestartDelay == 0 {
+		s.config.MaxRestartDelay = defaultMaxRestartDelay
+	}
+
+	if s.config.MaxRestartDelay < s.config.RestartDelay {
+		s.config.MaxRestartDelay = s.config.RestartDelay
+	}
+
+	return s
+}
+
+// Go runs fn in a new goroutine, see Run
+func (s *Supervisor) Go(name string, policy Policy, fn func()) {
+	go s.Run(name, policy, fn)
+}
+
+// Run runs fn in the calling goroutine until it returns. If fn panics, the panic is logged with its
+// stack trace and counted, then fn runs again or the process exits, depending on policy and the
+// supervisor policy.
+func (s *Supervisor) Run(name string, policy Policy, fn func()) {
+	log := s.config.Log.With().Str("goroutine", name).Logger()
+
+	if s.config.Policy == Fail {
+		policy = Fail
+	}
+
+	delay := s.config.RestartDelay
+	restarts := 0
+
+	for {
+		start := time.Now()
+
+		if !s.runOnce(log, name, fn) {
+			return
+		}
+
+		if time.Since(start) >= healthyRun {
+			delay = s.config.RestartDelay
+			restarts = 0
+		}
+
+		if policy == Fail || (s.config.MaxRestarts > 0 && restarts >= s.config.MaxRestarts) {
+			log.Error().Int("restarts", restarts).Msg("goroutine failed, exiting")
+
+			if s.config.DDClient != nil {
+				s.config.DDClient.Flush()
+			}
+
+			s.exit(exitCode)
+			return
+		}
+
+		restarts++
+		log.Warn().Int("restarts", restarts).Dur("delay", delay).Msg("restarting goroutine")
+
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > s.config.MaxRestartDelay {
+			delay = s.config.MaxRestartDelay
+		}
+	}
+}
+
+func (s *Supervisor) runOnce(log logging.Logger, name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+
+			logging.PanicEvent(log, r, debug.Stack()).Msg("goroutine panic")
+
+			if s.config.DDClient != nil {
+				tags := append([]string{"goroutine:" + name}, s.config.Tags...)
+				s.config.DDClient.Incr("goroutine.crash", tags)
+			}
+		}
+	}()
+
+	fn()
+
+	return false
+}
@@ -0,0 +1,85 @@
+package supervisor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestart(t *testing.T) {
+	var out bytes.Buffer
+
+	s := New(&Config{
+		RestartDelay: time.Millisecond,
+		MaxRestarts:  3,
+		Log:          zerolog.New(&out),
+	})
+	s.exit = func(code int) { t.Fatalf("unexpected exit %d", code) }
+
+	runs := 0
+	s.Run("worker", Restart, func() {
+		runs++
+
+		switch runs {
+		case 1:
+			panic("not an error")
+		case 2:
+			panic(errors.New("an error"))
+		case 3:
+			var m map[string]int
+			m["nil"] = 1
+		}
+	})
+
+	assert.Equal(t, 4, runs, "fn is not restarted once it returns")
+	assert.Contains(t, out.String(), `"panic":"not an error"`)
+	assert.Contains(t, out.String(), `"error":"an error"`)
+	assert.Contains(t, out.String(), "assignment to entry in nil map")
+	assert.Contains(t, out.String(), "supervisor.TestRestart")
+}
+
+func TestFail(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		config *Config
+		policy Policy
+		runs   int
+	}{
+		{"fail goroutine", &Config{}, Fail, 1},
+		{"fail supervisor", &Config{Policy: Fail}, Restart, 1},
+		{"max restarts", &Config{RestartDelay: time.Millisecond, MaxRestarts: 2}, Restart, 3},
+	} {
+		c.config.Log = zerolog.Nop()
+		s := New(c.config)
+
+		exitCode := 0
+		s.exit = func(code int) { exitCode = code }
+
+		runs := 0
+		s.Run("worker", c.policy, func() {
+			runs++
+			panic("crash")
+		})
+
+		require.Equal(t, 2, exitCode, c.name)
+		assert.Equal(t, c.runs, runs, c.name)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, Restart, policy)
+
+	policy, err = ParsePolicy("fail")
+	require.NoError(t, err)
+	assert.Equal(t, Fail, policy)
+
+	_, err = ParsePolicy("ignore")
+	assert.Error(t, err)
+}
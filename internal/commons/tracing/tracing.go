@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel/api/core"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/propagation"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/trace/stdout"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// ExporterOTLP exports the spans to an OpenTelemetry collector
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes the spans to stdout, for debugging
+	ExporterStdout = "stdout"
+
+	// DefaultOTLPAddress is the address of a local collector
+	DefaultOTLPAddress = "localhost:55680"
+
+	tracerName = "github.com/decentraland/world"
+)
+
+// Config is the tracing configuration
+type Config struct {
+	ServiceName string
+
+	// Exporter is either ExporterOTLP or ExporterStdout
+	Exporter string
+	// OTLPAddress is the collector address, DefaultOTLPAddress if empty
+	OTLPAddress string
+
+	// SampleRatio is the fraction of the traces started by this process that are sampled, the
+	// traces started by a remote parent follow the parent decision
+	SampleRatio float64
+}
+
+// Provider is the global trace provider, spans are no-ops until it's created
+type Provider struct {
+	provider  *sdktrace.Provider
+	processor sdktrace.SpanProcessor
+	stop      func() error
+}
+
+// NewProvider creates the exporter and installs the global trace provider
+func NewProvider(config *Config) (*Provider, error) {
+	p := &Provider{stop: func() error { return nil }}
+
+	switch config.Exporter {
+	case ExporterOTLP:
+		address := config.OTLPAddress
+		if address == "" {
+			address = DefaultOTLPAddress
+		}
+
+		exporter, err := otlp.NewExporter(otlp.WithInsecure(), otlp.WithAddress(address))
+		if err != nil {
+			return nil, err
+		}
+
+		processor, err := sdktrace.NewBatchSpanProcessor(exporter)
+		if err != nil {
+			exporter.Stop() //nolint:errcheck
+			return nil, err
+		}
+
+		p.processor = processor
+		p.stop = exporter.Stop
+	case ExporterStdout:
+		exporter, err := stdout.NewExporter(stdout.Options{})
+		if err != nil {
+			return nil, err
+		}
+
+		p.processor = sdktrace.NewSimpleSpanProcessor(exporter)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter '%s'", config.Exporter)
+	}
+
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.ProbabilitySampler(config.SampleRatio)}),
+		sdktrace.WithResourceAttributes(key.String("service.name", config.ServiceName)),
+	)
+	if err != nil {
+		p.stop() //nolint:errcheck
+		return nil, err
+	}
+
+	provider.RegisterSpanProcessor(p.processor)
+	p.provider = provider
+
+	global.SetTraceProvider(provider)
+
+	return p, nil
+}
+
+// Close flushes the pending spans and stops the exporter
+func (p *Provider) Close() error {
+	p.provider.UnregisterSpanProcessor(p.processor)
+	return p.stop()
+}
+
+// Tracer returns the tracer of the global provider
+func Tracer() trace.Tracer {
+	return global.Tracer(tracerName)
+}
+
+// Start starts a span from ctx
+func Start(ctx context.Context, name string, attrs ...core.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndWithError ends span, marking it as failed if err is not nil
+func EndWithError(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Unknown))
+	}
+
+	span.End()
+}
+
+// Handler starts a server span for each request to h, its parent is the trace context of the
+// request headers or, since the websocket clients cannot always set headers, of the query string
+func Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.ExtractHTTP(r.Context(), global.Propagators(), r.Header)
+		if !trace.RemoteSpanContextFromContext(ctx).IsValid() {
+			ctx = propagation.ExtractHTTP(ctx, global.Propagators(), r.URL.Query())
+		}
+
+		ctx, span := Tracer().Start(ctx, r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(key.String("http.method", r.Method), key.String("http.path", r.URL.Path)),
+		)
+		defer span.End()
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// InjectHeader adds the trace context of ctx to the request headers
+func InjectHeader(ctx context.Context, r *http.Request) {
+	propagation.InjectHTTP(ctx, global.Propagators(), r.Header)
+}
+
+// InjectURL adds the trace context of ctx to the query string of rawURL
+func InjectURL(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	qs := u.Query()
+	propagation.InjectHTTP(ctx, global.Propagators(), qs)
+	u.RawQuery = qs.Encode()
+
+	return u.String(), nil
+}
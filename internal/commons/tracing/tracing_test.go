@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestURLPropagation(t *testing.T) {
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+	)
+	require.NoError(t, err)
+
+	defer global.SetTraceProvider(global.TraceProvider())
+	global.SetTraceProvider(provider)
+
+	ctx, span := Start(context.Background(), "connect")
+	defer span.End()
+
+	u, err := InjectURL(ctx, "ws://coordinator/connect?identity=1")
+	require.NoError(t, err)
+
+	var parent trace.Span
+
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent = trace.SpanFromContext(r.Context())
+		assert.Equal(t, "1", r.URL.Query().Get("identity"))
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, u, nil))
+
+	require.NotNil(t, parent)
+	assert.Equal(t, span.SpanContext().TraceID, parent.SpanContext().TraceID, "the trace continues from the query string")
+	assert.NotEqual(t, span.SpanContext().SpanID, parent.SpanContext().SpanID)
+}
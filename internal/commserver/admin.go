@@ -0,0 +1,23 @@
+package commserver
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireSecret only lets through the requests with an "Authorization: Bearer <secret>" header. If
+// secret is empty every request is rejected, the admin endpoints are never open.
+func requireSecret(secret string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h(w, r)
+	}
+}
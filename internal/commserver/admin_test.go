@@ -0,0 +1,29 @@
+package commserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireSecret(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	do := func(secret string, header string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		requireSecret(secret, ok)(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("secret", "Bearer secret"))
+	assert.Equal(t, http.StatusUnauthorized, do("secret", "Bearer other"))
+	assert.Equal(t, http.StatusUnauthorized, do("secret", ""))
+	assert.Equal(t, http.StatusUnauthorized, do("", ""), "an empty secret closes the endpoints")
+	assert.Equal(t, http.StatusUnauthorized, do("", "Bearer "))
+}
@@ -0,0 +1,227 @@
+package commserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+)
+
+const (
+	defaultMaxCells = 1000
+
+	// OtherCell is the cell the traffic is counted in once MaxCells is reached
+	OtherCell = "other"
+	// UnknownCell is the cell of the messages from peers with no known position
+	UnknownCell = "unknown"
+)
+
+// CellStatsConfig is the cell stats configuration
+type CellStatsConfig struct {
+	// MaxCells caps the cells counted in a period, the traffic of the rest is counted in
+	// OtherCell
+	MaxCells int
+	Log      logging.Logger
+}
+
+// CellTraffic is the traffic of a sender cell and category delivered to the local peers in a
+// period. Messages are the distinct messages delivered to at least one local peer, Deliveries their
+// writes to each local peer, Recipients the distinct local peers that got them, and LocalFanOut the
+// deliveries by message. The messages forwarded to other servers are not counted.
+type CellTraffic struct {
+	Cell           string  `json:"cell"`
+	Category       string  `json:"category"`
+	Messages       uint64  `json:"messages"`
+	Bytes          uint64  `json:"bytes"`
+	Deliveries     uint64  `json:"deliveries"`
+	DeliveredBytes uint64  `json:"deliveredBytes"`
+	Recipients     int     `json:"recipients"`
+	LocalFanOut    float64 `json:"localFanOut"`
+}
+
+// CellStatsPeriod is the local traffic by sender cell and category in a period, sorted by delivered
+// bytes
+type CellStatsPeriod struct {
+	Start time.Time     `json:"start"`
+	End   time.Time     `json:"end"`
+	Cells []CellTraffic `json:"cells"`
+}
+
+type cellKey struct {
+	cell     string
+	category string
+}
+
+type cellCounters struct {
+	messages       uint64
+	bytes          uint64
+	deliveries     uint64
+	deliveredBytes uint64
+	recipients     map[uint64]struct{}
+}
+
+// CellStats is a pipeline handler that counts the traffic delivered to the local peers by sender
+// cell and category. It's not the traffic of the broker topics:
+//
+//   - the forwarded messages don't carry their broker topic, so the cell is the topic of the sender
+//     last position, DirectMessageTopic for the direct messages, and the traffic of a sender is
+//     counted in its cell even if it's sent to another topic
+//   - the pipeline only runs for the writes to the local peers, so the messages forwarded to other
+//     servers, and the ones with no local recipient, are not counted
+//
+// It has to be registered after the handlers that drop messages, so it only counts the delivered
+// ones.
+type CellStats struct {
+	maxCells int
+	log      logging.Logger
+
+	mutex    sync.Mutex
+	start    time.Time
+	cells    map[string]struct{}
+	counters map[cellKey]*cellCounters
+	last     CellStatsPeriod
+}
+
+// NewCellStats creates a CellStats, it has to be registered in a Pipeline
+func NewCellStats(config *CellStatsConfig) *CellStats {
+	maxCells := config.MaxCells
+	if maxCells == 0 {
+		maxCells = defaultMaxCells
+	}
+
+	return &CellStats{
+		maxCells: maxCells,
+		log:      config.Log,
+		start:    time.Now(),
+		cells:    make(map[string]struct{}),
+		counters: make(map[cellKey]*cellCounters),
+		last:     CellStatsPeriod{Cells: []CellTraffic{}},
+	}
+}
+
+func (s *CellStats) getCounters(m *Message) *cellCounters {
+	cell := m.Topic
+	if cell == "" {
+		cell = UnknownCell
+	}
+
+	if _, ok := s.cells[cell]; !ok {
+		if len(s.cells) >= s.maxCells {
+			cell = OtherCell
+		}
+
+		s.cells[cell] = struct{}{}
+	}
+
+	key := cellKey{cell: cell, category: m.Category.String()}
+
+	counters := s.counters[key]
+	if counters == nil {
+		counters = &cellCounters{recipients: make(map[uint64]struct{})}
+		s.counters[key] = counters
+	}
+
+	return counters
+}
+
+// OnMessage ...
+func (s *CellStats) OnMessage(m *Message) bool {
+	s.mutex.Lock()
+	counters := s.getCounters(m)
+	counters.messages++
+	counters.bytes += uint64(len(m.Raw))
+	s.mutex.Unlock()
+
+	return true
+}
+
+// OnDelivery ...
+func (s *CellStats) OnDelivery(m *Message, to *Peer) bool {
+	s.mutex.Lock()
+	counters := s.getCounters(m)
+	counters.deliveries++
+	counters.deliveredBytes += uint64(len(m.Raw))
+	counters.recipients[to.Alias] = struct{}{}
+	s.mutex.Unlock()
+
+	return true
+}
+
+// Stats closes the current period and returns its traffic, it's safe to call from any goroutine
+func (s *CellStats) Stats() CellStatsPeriod {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	period := CellStatsPeriod{
+		Start: s.start,
+		End:   now,
+		Cells: make([]CellTraffic, 0, len(s.counters)),
+	}
+
+	for key, counters := range s.counters {
+		traffic := CellTraffic{
+			Cell:           key.cell,
+			Category:       key.category,
+			Messages:       counters.messages,
+			Bytes:          counters.bytes,
+			Deliveries:     counters.deliveries,
+			DeliveredBytes: counters.deliveredBytes,
+			Recipients:     len(counters.recipients),
+		}
+
+		if counters.messages > 0 {
+			traffic.LocalFanOut = float64(counters.deliveries) / float64(counters.messages)
+		}
+
+		period.Cells = append(period.Cells, traffic)
+	}
+
+	sort.Slice(period.Cells, func(i, j int) bool {
+		a, b := period.Cells[i], period.Cells[j]
+		if a.DeliveredBytes != b.DeliveredBytes {
+			return a.DeliveredBytes > b.DeliveredBytes
+		}
+
+		if a.Cell != b.Cell {
+			return a.Cell < b.Cell
+		}
+
+		return a.Category < b.Category
+	})
+
+	s.start = now
+	s.cells = make(map[string]struct{})
+	s.counters = make(map[cellKey]*cellCounters)
+	s.last = period
+
+	return period
+}
+
+// Last returns the traffic of the last closed period, it's safe to call from any goroutine
+func (s *CellStats) Last() CellStatsPeriod {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.last
+}
+
+// RegisterCellStatsAPI adds the cell stats admin endpoint to mux, it requires the server secret:
+//
+//	GET /cells/stats returns the local traffic by sender cell and category of the last report period
+func RegisterCellStatsAPI(mux *http.ServeMux, secret string, s *CellStats) {
+	mux.HandleFunc("/cells/stats", requireSecret(secret, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(s.Last()) //nolint:errcheck
+	}))
+}
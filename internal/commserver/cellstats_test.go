@@ -0,0 +1,79 @@
+package commserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decentraland/world/pkg/protocol"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCellStats(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(&dropHandler{})
+	s := NewCellStats(&CellStatsConfig{MaxCells: 2, Log: zerolog.Nop()})
+	p.Use(s)
+
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+	peer4 := newTestPeer(p, 4)
+
+	position := encodePosition(t, 1, 0, 0)
+	peer2.unreliableWriter.Write(position)
+	peer3.unreliableWriter.Write(position)
+	peer4.unreliableWriter.Write(position)
+
+	peer2.reliableWriter.Write(encodeChat(t, 1, "1", "hi"))
+	peer2.reliableWriter.Write(encodeFW(t, 1, &protocol.ProfileData{Category: protocol.Category_PROFILE}))
+
+	// the cell of a peer with no known position is unknown, and a third cell is over the cap
+	peer2.reliableWriter.Write(encodeFW(t, 5, &protocol.ProfileData{Category: protocol.Category_PROFILE}))
+	peer2.unreliableWriter.Write(encodePosition(t, 6, 1000, 1000))
+
+	period := s.Stats()
+
+	traffic := map[string]CellTraffic{}
+	for _, cell := range period.Cells {
+		traffic[cell.Cell+"/"+cell.Category] = cell
+	}
+
+	cell := protocol.CellTopic(0, 0)
+	require.Contains(t, traffic, cell+"/POSITION")
+
+	positions := traffic[cell+"/POSITION"]
+	assert.Equal(t, uint64(1), positions.Messages)
+	assert.Equal(t, uint64(2), positions.Deliveries, "the drop handler blocks the delivery to peer 3")
+	assert.Equal(t, 2*uint64(len(position)), positions.DeliveredBytes)
+	assert.Equal(t, 2, positions.Recipients)
+	assert.Equal(t, 2.0, positions.LocalFanOut)
+
+	assert.NotContains(t, traffic, cell+"/CHAT", "dropped messages are not counted")
+	assert.Contains(t, traffic, cell+"/PROFILE")
+	assert.Contains(t, traffic, UnknownCell+"/PROFILE")
+	assert.Contains(t, traffic, OtherCell+"/POSITION")
+
+	t.Run("api", func(t *testing.T) {
+		mux := http.NewServeMux()
+		RegisterCellStatsAPI(mux, "secret", s)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cells/stats", nil))
+		require.Equal(t, http.StatusUnauthorized, w.Code, "the stats require the server secret")
+
+		r := httptest.NewRequest(http.MethodGet, "/cells/stats", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		response := CellStatsPeriod{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Len(t, response.Cells, len(period.Cells))
+	})
+
+	assert.Len(t, s.Stats().Cells, 0, "the counters are reset every period")
+}
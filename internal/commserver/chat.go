@@ -0,0 +1,194 @@
+package commserver
+
+import (
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const (
+	defaultChatHistorySize   = 50
+	defaultChatHistoryMaxAge = 10 * time.Minute
+
+	// topicIdleTimeout is how long a peer has to go without receiving messages from a topic to be
+	// considered a new subscriber when it receives one again
+	topicIdleTimeout = 10 * time.Second
+)
+
+// ChatHistoryConfig is the chat history configuration, zero values use the defaults
+type ChatHistoryConfig struct {
+	// MaxMessages is the max number of messages kept per topic
+	MaxMessages int
+	// MaxAge is how long messages are kept, and how long a message id is remembered for dedup
+	MaxAge time.Duration
+	Log    logging.Logger
+}
+
+type chatEntry struct {
+	id        string
+	time      time.Time
+	fromAlias uint64
+	raw       []byte
+}
+
+type chatRecipient struct {
+	topics    map[string]time.Time
+	delivered map[string]bool
+}
+
+// ChatHistory is a pipeline handler that drops repeated chat messages by message id, and keeps the
+// last chat messages of each topic, replaying them to the peers that start receiving the topic
+type ChatHistory struct {
+	maxMessages int
+	maxAge      time.Duration
+	log         logging.Logger
+
+	seen      map[string]bool
+	seenQueue []chatEntry
+
+	topics     map[string][]chatEntry
+	recipients map[uint64]*chatRecipient
+}
+
+// NewChatHistory creates a ChatHistory, it has to be registered in a Pipeline
+func NewChatHistory(config *ChatHistoryConfig) *ChatHistory {
+	h := &ChatHistory{
+		maxMessages: config.MaxMessages,
+		maxAge:      config.MaxAge,
+		log:         config.Log,
+		seen:        make(map[string]bool),
+		topics:      make(map[string][]chatEntry),
+		recipients:  make(map[uint64]*chatRecipient),
+	}
+
+	if h.maxMessages <= 0 {
+		h.maxMessages = defaultChatHistorySize
+	}
+
+	if h.maxAge <= 0 {
+		h.maxAge = defaultChatHistoryMaxAge
+	}
+
+	return h
+}
+
+// OnMessage drops the chat messages already seen and adds the new ones to the topic history
+func (h *ChatHistory) OnMessage(m *Message) bool {
+	chat, ok := m.Data.(*protocol.ChatData)
+	if !ok || chat.MessageId == "" {
+		return true
+	}
+
+	now := time.Now()
+	h.expire(now)
+
+	if h.seen[chat.MessageId] {
+		h.log.Debug().Str("id", chat.MessageId).Uint64("alias", m.FromAlias).Msg("duplicated chat message dropped")
+		return false
+	}
+
+	entry := chatEntry{id: chat.MessageId, time: now, fromAlias: m.FromAlias, raw: m.copyRaw()}
+
+	h.seen[chat.MessageId] = true
+	h.seenQueue = append(h.seenQueue, entry)
+
+	if m.Topic != "" {
+		history := append(h.topics[m.Topic], entry)
+		if len(history) > h.maxMessages {
+			history = history[len(history)-h.maxMessages:]
+		}
+
+		h.topics[m.Topic] = history
+	}
+
+	return true
+}
+
+// OnDelivery replays the topic history to the peers that start receiving the topic, and makes sure
+// each peer gets a chat message only once
+func (h *ChatHistory) OnDelivery(m *Message, to *Peer) bool {
+	if m.Topic == "" {
+		return true
+	}
+
+	recipient := h.recipients[to.Alias]
+	if recipient == nil {
+		recipient = &chatRecipient{
+			topics:    make(map[string]time.Time),
+			delivered: make(map[string]bool),
+		}
+		h.recipients[to.Alias] = recipient
+	}
+
+	now := time.Now()
+
+	lastReceived, ok := recipient.topics[m.Topic]
+	if !ok || now.Sub(lastReceived) > topicIdleTimeout {
+		for _, entry := range h.topics[m.Topic] {
+			if entry.fromAlias == to.Alias || recipient.delivered[entry.id] {
+				continue
+			}
+
+			recipient.delivered[entry.id] = true
+			to.WriteReliable(entry.raw)
+		}
+	}
+
+	recipient.topics[m.Topic] = now
+
+	chat, ok := m.Data.(*protocol.ChatData)
+	if !ok || chat.MessageId == "" {
+		return true
+	}
+
+	if recipient.delivered[chat.MessageId] {
+		return false
+	}
+
+	recipient.delivered[chat.MessageId] = true
+
+	return true
+}
+
+// OnPeerRemoved forgets the peer delivery state
+func (h *ChatHistory) OnPeerRemoved(p *Peer) {
+	delete(h.recipients, p.Alias)
+}
+
+func (h *ChatHistory) expire(now time.Time) {
+	n := 0
+
+	for _, entry := range h.seenQueue {
+		if now.Sub(entry.time) <= h.maxAge {
+			break
+		}
+
+		delete(h.seen, entry.id)
+
+		for _, recipient := range h.recipients {
+			delete(recipient.delivered, entry.id)
+		}
+
+		n++
+	}
+
+	if n == 0 {
+		return
+	}
+
+	h.seenQueue = h.seenQueue[n:]
+
+	for topic, history := range h.topics {
+		i := 0
+		for i < len(history) && now.Sub(history[i].time) > h.maxAge {
+			i++
+		}
+
+		if i == len(history) {
+			delete(h.topics, topic)
+		} else {
+			h.topics[topic] = history[i:]
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package commserver
+
+import (
+	"strings"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const (
+	// directMessageMaxAge is how long a direct message waits for its recipient identity to be known,
+	// longer than the report period, since most identities are learned from the broker stats
+	directMessageMaxAge = 30 * time.Second
+	// directMessageMaxPending is the max number of direct messages waiting, the oldest are dropped
+	directMessageMaxPending = 1000
+)
+
+type pendingDirectMessage struct {
+	raw       []byte
+	reliable  bool
+	recipient string
+	time      time.Time
+}
+
+// DirectMessageRouter is a pipeline handler that delivers the direct messages, and their acks, only
+// to the peers of the recipient identity. Every client subscribed to the direct messages topic gets
+// them from the broker, the router drops them for everyone else, including the peers whose identity
+// is not known yet. The messages to an identity no local peer has are held for a while instead, and
+// written to the first peer identified with it.
+type DirectMessageRouter struct {
+	log logging.Logger
+
+	// identities are the aliases of the identified local peers by lower case identity
+	identities map[string]map[uint64]bool
+	pending    []pendingDirectMessage
+}
+
+// NewDirectMessageRouter creates a DirectMessageRouter, it has to be registered in a Pipeline
+func NewDirectMessageRouter(log logging.Logger) *DirectMessageRouter {
+	return &DirectMessageRouter{log: log, identities: make(map[string]map[uint64]bool)}
+}
+
+// OnMessage drops the direct messages without recipient or sender identity, the recipient needs
+// it to reply and ack. The messages to an unknown recipient are held until it's identified.
+func (r *DirectMessageRouter) OnMessage(m *Message) bool {
+	recipient, ok := directMessageRecipient(m)
+	if !ok {
+		return true
+	}
+
+	if recipient == "" || m.Identity == "" {
+		r.log.Debug().Uint64("alias", m.FromAlias).Msg("invalid direct message dropped")
+		return false
+	}
+
+	recipient = strings.ToLower(recipient)
+	if len(r.identities[recipient]) > 0 {
+		return true
+	}
+
+	r.hold(m, recipient, time.Now())
+	return false
+}
+
+// OnDelivery ...
+func (r *DirectMessageRouter) OnDelivery(m *Message, to *Peer) bool {
+	recipient, ok := directMessageRecipient(m)
+	if !ok {
+		return true
+	}
+
+	return to.Identity != "" && strings.EqualFold(to.Identity, recipient)
+}
+
+// OnPeerIdentified writes the held messages to the peer identity
+func (r *DirectMessageRouter) OnPeerIdentified(p *Peer) {
+	r.forget(p.Alias)
+
+	identity := strings.ToLower(p.Identity)
+
+	aliases := r.identities[identity]
+	if aliases == nil {
+		aliases = make(map[uint64]bool)
+		r.identities[identity] = aliases
+	}
+
+	aliases[p.Alias] = true
+
+	now := time.Now()
+	kept := r.pending[:0]
+
+	for _, dm := range r.pending {
+		if now.Sub(dm.time) >= directMessageMaxAge {
+			continue
+		}
+
+		if dm.recipient != identity {
+			kept = append(kept, dm)
+			continue
+		}
+
+		if dm.reliable {
+			p.WriteReliable(dm.raw)
+		} else {
+			p.WriteUnreliable(dm.raw)
+		}
+	}
+
+	r.pending = kept
+}
+
+// OnPeerRemoved ...
+func (r *DirectMessageRouter) OnPeerRemoved(p *Peer) {
+	r.forget(p.Alias)
+}
+
+func (r *DirectMessageRouter) forget(alias uint64) {
+	for identity, aliases := range r.identities {
+		if aliases[alias] {
+			delete(aliases, alias)
+
+			if len(aliases) == 0 {
+				delete(r.identities, identity)
+			}
+		}
+	}
+}
+
+func (r *DirectMessageRouter) hold(m *Message, recipient string, now time.Time) {
+	expired := 0
+	for expired < len(r.pending) && now.Sub(r.pending[expired].time) >= directMessageMaxAge {
+		expired++
+	}
+
+	if len(r.pending)-expired >= directMessageMaxPending {
+		r.log.Debug().Str("recipient", r.pending[expired].recipient).Msg("pending direct message dropped")
+		expired++
+	}
+
+	r.pending = r.pending[expired:]
+
+	r.pending = append(r.pending, pendingDirectMessage{
+		raw:       m.copyRaw(),
+		reliable:  m.Reliable,
+		recipient: recipient,
+		time:      now,
+	})
+}
+
+func directMessageRecipient(m *Message) (string, bool) {
+	switch data := m.Data.(type) {
+	case *protocol.DirectMessageData:
+		return data.Recipient, true
+	case *protocol.DirectMessageAckData:
+		return data.Recipient, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,162 @@
+package commserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	brokerProtocol "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func encodeIdentityFW(t *testing.T, alias uint64, identity string, data proto.Message) []byte {
+	body, err := proto.Marshal(data)
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&brokerProtocol.TopicIdentityFWMessage{
+		Type:      brokerProtocol.MessageType_TOPIC_IDENTITY_FW,
+		FromAlias: alias,
+		Identity:  []byte(identity),
+		Body:      body,
+	})
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestDirectMessageRouter(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(NewDirectMessageRouter(zerolog.Nop()))
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: {Identity: []byte("0xa")},
+		2: {Identity: []byte("0xb")},
+		3: {Identity: []byte("0xc")},
+	}})
+
+	dm := &protocol.DirectMessageData{Category: protocol.Category_DIRECT_MESSAGE, MessageId: "1", Recipient: "0xB"}
+
+	raw := encodeIdentityFW(t, 1, "0xa", dm)
+	peer2.reliableWriter.Write(raw)
+	peer3.reliableWriter.Write(raw)
+
+	assert.Len(t, peer2.reliable.written, 1)
+	assert.Len(t, peer3.reliable.written, 0)
+
+	raw = encodeFW(t, 1, dm)
+	peer2.reliableWriter.Write(raw)
+	assert.Len(t, peer2.reliable.written, 1, "direct messages need the sender identity")
+
+	raw = encodeIdentityFW(t, 1, "0xa", &protocol.DirectMessageData{Category: protocol.Category_DIRECT_MESSAGE})
+	peer2.reliableWriter.Write(raw)
+	peer3.reliableWriter.Write(raw)
+	assert.Len(t, peer2.reliable.written, 1, "direct messages need a recipient")
+	assert.Len(t, peer3.reliable.written, 0, "direct messages need a recipient")
+}
+
+func TestDirectMessageRouterUnknownRecipient(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	router := NewDirectMessageRouter(zerolog.Nop())
+	p.Use(router)
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: {Identity: []byte("0xa")},
+		2: {},
+		3: {Identity: []byte("0xc")},
+	}})
+
+	dm := &protocol.DirectMessageData{Category: protocol.Category_DIRECT_MESSAGE, MessageId: "1", Recipient: "0xB"}
+	raw := encodeIdentityFW(t, 1, "0xa", dm)
+	peer2.reliableWriter.Write(raw)
+	peer3.reliableWriter.Write(raw)
+
+	assert.Len(t, peer2.reliable.written, 0, "the recipient identity is not known yet")
+	assert.Len(t, peer3.reliable.written, 0)
+
+	expired := encodeIdentityFW(t, 1, "0xa", &protocol.DirectMessageData{
+		Category:  protocol.Category_DIRECT_MESSAGE,
+		MessageId: "2",
+		Recipient: "0xb",
+	})
+	peer2.reliableWriter.Write(expired)
+	router.pending[1].time = time.Now().Add(-directMessageMaxAge)
+
+	other := encodeIdentityFW(t, 1, "0xa", &protocol.DirectMessageData{
+		Category:  protocol.Category_DIRECT_MESSAGE,
+		MessageId: "3",
+		Recipient: "0xd",
+	})
+	peer2.reliableWriter.Write(other)
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: {Identity: []byte("0xa")},
+		2: {Identity: []byte("0xb")},
+		3: {Identity: []byte("0xc")},
+	}})
+
+	require.Len(t, peer2.reliable.written, 1, "the held message is written once the recipient is identified")
+	assert.Equal(t, raw, peer2.reliable.written[0])
+	assert.Len(t, peer3.reliable.written, 0)
+	assert.Len(t, router.pending, 1, "the messages to other identities are still held")
+
+	raw = encodeIdentityFW(t, 1, "0xa", &protocol.DirectMessageData{
+		Category:  protocol.Category_DIRECT_MESSAGE,
+		MessageId: "4",
+		Recipient: "0xb",
+	})
+	peer2.reliableWriter.Write(raw)
+	assert.Len(t, peer2.reliable.written, 2, "the messages to a known recipient are delivered")
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: {Identity: []byte("0xa")},
+		3: {Identity: []byte("0xc")},
+	}})
+	assert.Empty(t, router.identities["0xb"], "the removed peers are forgotten")
+}
+
+func TestDirectMessageRouterMalformed(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(NewDirectMessageRouter(zerolog.Nop()))
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: {Identity: []byte("0xa")},
+		2: {Identity: []byte("0xb")},
+		3: {Identity: []byte("0xc")},
+	}})
+
+	// NOTE: a valid data header, with a recipient that is not valid UTF-8
+	body := []byte{0x08, byte(protocol.Category_DIRECT_MESSAGE), 0x22, 0x01, 0xff}
+	require.NoError(t, proto.Unmarshal(body, &protocol.DataHeader{}))
+	require.Error(t, proto.Unmarshal(body, &protocol.DirectMessageData{}))
+
+	raw, err := proto.Marshal(&brokerProtocol.TopicIdentityFWMessage{
+		Type:      brokerProtocol.MessageType_TOPIC_IDENTITY_FW,
+		FromAlias: 1,
+		Identity:  []byte("0xa"),
+		Body:      body,
+	})
+	require.NoError(t, err)
+
+	peer2.reliableWriter.Write(raw)
+	peer3.reliableWriter.Write(raw)
+
+	assert.Len(t, peer2.reliable.written, 0, "undecodable messages are dropped")
+	assert.Len(t, peer3.reliable.written, 0, "undecodable messages are dropped")
+}
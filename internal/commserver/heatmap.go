@@ -0,0 +1,201 @@
+package commserver
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commserver/statsstore"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const defaultHeatmapWindow = 1 * time.Minute
+
+// HeatmapConfig is the heatmap configuration
+type HeatmapConfig struct {
+	// Window is how long a user counts as active in its last position
+	Window time.Duration
+	Log    logging.Logger
+}
+
+// ParcelDensity is the number of active users in a parcel
+type ParcelDensity struct {
+	X     int `json:"x"`
+	Z     int `json:"z"`
+	Users int `json:"users"`
+}
+
+// CellDensity is the number of active users in a cell topic
+type CellDensity struct {
+	Topic string `json:"topic"`
+	Users int    `json:"users"`
+}
+
+// HeatmapSnapshot is the user density at a time, the parcels and cells are sorted by users
+type HeatmapSnapshot struct {
+	Time    time.Time       `json:"time"`
+	Users   int             `json:"users"`
+	Parcels []ParcelDensity `json:"parcels"`
+	Cells   []CellDensity   `json:"cells"`
+}
+
+type heatmapPosition struct {
+	parcelX  int
+	parcelZ  int
+	cell     string
+	lastSeen time.Time
+}
+
+// Heatmap is a pipeline handler that keeps the last position of each user, to count the active
+// users per parcel and per cell topic. It only sees the positions delivered to the peers of this
+// server, either from local or remote users.
+type Heatmap struct {
+	mutex     sync.Mutex
+	window    time.Duration
+	log       logging.Logger
+	positions map[uint64]heatmapPosition
+}
+
+// NewHeatmap creates a Heatmap, it has to be registered in a Pipeline
+func NewHeatmap(config *HeatmapConfig) *Heatmap {
+	window := config.Window
+	if window == 0 {
+		window = defaultHeatmapWindow
+	}
+
+	return &Heatmap{
+		window:    window,
+		log:       config.Log,
+		positions: make(map[uint64]heatmapPosition),
+	}
+}
+
+// OnMessage ...
+func (h *Heatmap) OnMessage(m *Message) bool {
+	position := m.Position
+	if position == nil {
+		return true
+	}
+
+	x := float64(position.PositionX)
+	z := float64(position.PositionZ)
+
+	h.mutex.Lock()
+	h.positions[m.FromAlias] = heatmapPosition{
+		parcelX:  int(math.Floor(x / protocol.ParcelSize)),
+		parcelZ:  int(math.Floor(z / protocol.ParcelSize)),
+		cell:     protocol.CellTopic(x, z),
+		lastSeen: time.Now(),
+	}
+	h.mutex.Unlock()
+
+	return true
+}
+
+// OnPeerRemoved ...
+func (h *Heatmap) OnPeerRemoved(p *Peer) {
+	h.mutex.Lock()
+	delete(h.positions, p.Alias)
+	h.mutex.Unlock()
+}
+
+// Snapshot returns the users active in the window before now, and forgets the inactive ones. It's
+// safe to call from any goroutine.
+func (h *Heatmap) Snapshot(now time.Time) HeatmapSnapshot {
+	parcels := make(map[[2]int]int)
+	cells := make(map[string]int)
+
+	snapshot := HeatmapSnapshot{Time: now}
+
+	h.mutex.Lock()
+	for alias, position := range h.positions {
+		if now.Sub(position.lastSeen) > h.window {
+			delete(h.positions, alias)
+			continue
+		}
+
+		snapshot.Users++
+		parcels[[2]int{position.parcelX, position.parcelZ}]++
+		cells[position.cell]++
+	}
+	h.mutex.Unlock()
+
+	snapshot.Parcels = make([]ParcelDensity, 0, len(parcels))
+	for parcel, users := range parcels {
+		snapshot.Parcels = append(snapshot.Parcels, ParcelDensity{X: parcel[0], Z: parcel[1], Users: users})
+	}
+
+	sort.Slice(snapshot.Parcels, func(i, j int) bool {
+		a, b := snapshot.Parcels[i], snapshot.Parcels[j]
+		if a.Users != b.Users {
+			return a.Users > b.Users
+		}
+
+		if a.X != b.X {
+			return a.X < b.X
+		}
+
+		return a.Z < b.Z
+	})
+
+	snapshot.Cells = make([]CellDensity, 0, len(cells))
+	for topic, users := range cells {
+		snapshot.Cells = append(snapshot.Cells, CellDensity{Topic: topic, Users: users})
+	}
+
+	sort.Slice(snapshot.Cells, func(i, j int) bool {
+		a, b := snapshot.Cells[i], snapshot.Cells[j]
+		if a.Users != b.Users {
+			return a.Users > b.Users
+		}
+
+		return a.Topic < b.Topic
+	})
+
+	return snapshot
+}
+
+// RunSnapshots writes the parcels of a snapshot to store every period, it never returns
+func (h *Heatmap) RunSnapshots(store statsstore.HeatmapStore, cluster string, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		snapshot := h.Snapshot(now)
+
+		parcels := make([]statsstore.ParcelUsers, 0, len(snapshot.Parcels))
+		for _, p := range snapshot.Parcels {
+			parcels = append(parcels, statsstore.ParcelUsers{
+				Time:    now,
+				Cluster: cluster,
+				X:       p.X,
+				Z:       p.Z,
+				Users:   p.Users,
+			})
+		}
+
+		if err := store.InsertHeatmap(parcels); err != nil {
+			h.log.Error().Err(err).Msg("cannot store the heatmap")
+		}
+	}
+}
+
+// RegisterHeatmapAPI adds the heatmap admin endpoint to mux, it requires the server secret:
+//
+//	GET /heatmap returns the active users by parcel and by cell topic
+func RegisterHeatmapAPI(mux *http.ServeMux, secret string, h *Heatmap) {
+	mux.HandleFunc("/heatmap", requireSecret(secret, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(h.Snapshot(time.Now())) //nolint:errcheck
+	}))
+}
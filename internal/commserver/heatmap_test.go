@@ -0,0 +1,56 @@
+package commserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatmap(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	h := NewHeatmap(&HeatmapConfig{Window: time.Minute, Log: zerolog.Nop()})
+	p.Use(h)
+
+	peer := newTestPeer(p, 10)
+
+	peer.unreliableWriter.Write(encodePosition(t, 1, 1, 1))
+	peer.unreliableWriter.Write(encodePosition(t, 2, 15, 2))
+	peer.unreliableWriter.Write(encodePosition(t, 3, -1, 40))
+	peer.unreliableWriter.Write(encodePosition(t, 3, -1, 50))
+
+	snapshot := h.Snapshot(time.Now())
+	assert.Equal(t, 3, snapshot.Users)
+	assert.Equal(t, []ParcelDensity{{X: 0, Z: 0, Users: 2}, {X: -1, Z: 3, Users: 1}}, snapshot.Parcels)
+	require.Len(t, snapshot.Cells, 2)
+	assert.Equal(t, 2, snapshot.Cells[0].Users)
+
+	t.Run("api", func(t *testing.T) {
+		mux := http.NewServeMux()
+		RegisterHeatmapAPI(mux, "secret", h)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/heatmap", nil))
+		require.Equal(t, http.StatusUnauthorized, w.Code, "the heatmap requires the server secret")
+
+		r := httptest.NewRequest(http.MethodGet, "/heatmap", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		response := HeatmapSnapshot{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, 3, response.Users)
+		assert.Len(t, response.Parcels, 2)
+	})
+
+	snapshot = h.Snapshot(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 0, snapshot.Users, "the inactive users are forgotten")
+	assert.Len(t, snapshot.Parcels, 0)
+}
@@ -0,0 +1,160 @@
+package commserver
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const (
+	defaultNearDistance      = 32
+	defaultFarDistance       = 96
+	defaultFarUpdateInterval = 1 * time.Second
+	defaultStalePosition     = 5 * time.Second
+)
+
+// InterestConfig is the area of interest configuration
+type InterestConfig struct {
+	// NearDistance is the distance, in meters, up to which the positions are delivered at full rate
+	NearDistance float64
+	// FarDistance is the distance, in meters, beyond which the positions are not delivered
+	FarDistance float64
+	// FarUpdateInterval is the min interval between the positions delivered from a peer farther
+	// than NearDistance
+	FarUpdateInterval time.Duration
+	// StalePosition is how long the last position of a recipient is trusted, a recipient with an
+	// older position gets every position, as if it were near
+	StalePosition time.Duration
+	Log           logging.Logger
+}
+
+// InterestStats are the position deliveries since the last InterestManager.Stats call
+type InterestStats struct {
+	Delivered    uint64
+	Decimated    uint64
+	Culled       uint64
+	BytesSkipped uint64
+}
+
+// InterestManager is a pipeline handler that filters the positions by the distance between the
+// sender and the recipient. The broker delivers every position of a cell topic to all of its
+// subscribers, so the peers in the same or an adjacent cell get it, no matter how far they are.
+// Positions are only filtered once both positions are known, the compact positions once decoded,
+// and while the recipient position is not stale.
+type InterestManager struct {
+	// NOTE: accessed atomically, keep them first for the 64 bit alignment
+	delivered    uint64
+	decimated    uint64
+	culled       uint64
+	bytesSkipped uint64
+
+	nearDistance      float64
+	farDistance       float64
+	farUpdateInterval time.Duration
+	stalePosition     time.Duration
+	log               logging.Logger
+
+	// lastDelivered is the time of the last position delivered to a peer, by sender
+	lastDelivered map[uint64]map[uint64]time.Time
+}
+
+// NewInterestManager creates an InterestManager, it has to be registered in a Pipeline
+func NewInterestManager(config *InterestConfig) *InterestManager {
+	nearDistance := config.NearDistance
+	if nearDistance == 0 {
+		nearDistance = defaultNearDistance
+	}
+
+	farDistance := config.FarDistance
+	if farDistance == 0 {
+		farDistance = defaultFarDistance
+	}
+
+	farUpdateInterval := config.FarUpdateInterval
+	if farUpdateInterval == 0 {
+		farUpdateInterval = defaultFarUpdateInterval
+	}
+
+	stalePosition := config.StalePosition
+	if stalePosition == 0 {
+		stalePosition = defaultStalePosition
+	}
+
+	return &InterestManager{
+		nearDistance:      nearDistance,
+		farDistance:       farDistance,
+		farUpdateInterval: farUpdateInterval,
+		stalePosition:     stalePosition,
+		log:               config.Log,
+		lastDelivered:     make(map[uint64]map[uint64]time.Time),
+	}
+}
+
+// OnDelivery ...
+func (im *InterestManager) OnDelivery(m *Message, to *Peer) bool {
+	position := m.Position
+	if position == nil || to.Position == nil || to.Alias == m.FromAlias {
+		return true
+	}
+
+	now := time.Now()
+
+	// the recipient may have moved since, it's safer to deliver too much than to miss updates
+	if now.Sub(to.PositionTime) > im.stalePosition {
+		atomic.AddUint64(&im.delivered, 1)
+		return true
+	}
+
+	dx := float64(position.PositionX - to.Position.PositionX)
+	dz := float64(position.PositionZ - to.Position.PositionZ)
+	distance := math.Sqrt(dx*dx + dz*dz)
+
+	if distance > im.farDistance {
+		atomic.AddUint64(&im.culled, 1)
+		atomic.AddUint64(&im.bytesSkipped, uint64(len(m.Raw)))
+		return false
+	}
+
+	last := im.lastDelivered[to.Alias]
+	if last == nil {
+		last = make(map[uint64]time.Time)
+		im.lastDelivered[to.Alias] = last
+	}
+
+	// the compact position keyframes are never decimated, the deltas until the next one need them
+	compact, ok := m.Data.(*protocol.CompactPositionData)
+	keyframe := ok && compact.Baseline == 0
+
+	if distance > im.nearDistance && !keyframe && now.Sub(last[m.FromAlias]) < im.farUpdateInterval {
+		atomic.AddUint64(&im.decimated, 1)
+		atomic.AddUint64(&im.bytesSkipped, uint64(len(m.Raw)))
+		return false
+	}
+
+	last[m.FromAlias] = now
+	atomic.AddUint64(&im.delivered, 1)
+
+	return true
+}
+
+// OnPeerRemoved ...
+func (im *InterestManager) OnPeerRemoved(p *Peer) {
+	delete(im.lastDelivered, p.Alias)
+
+	for _, last := range im.lastDelivered {
+		delete(last, p.Alias)
+	}
+}
+
+// Stats returns the position deliveries since the last call, it's safe to call from any goroutine
+func (im *InterestManager) Stats() InterestStats {
+	return InterestStats{
+		Delivered:    atomic.SwapUint64(&im.delivered, 0),
+		Decimated:    atomic.SwapUint64(&im.decimated, 0),
+		Culled:       atomic.SwapUint64(&im.culled, 0),
+		BytesSkipped: atomic.SwapUint64(&im.bytesSkipped, 0),
+	}
+}
@@ -0,0 +1,107 @@
+package commserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func TestInterestManager(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	im := NewInterestManager(&InterestConfig{
+		NearDistance:      10,
+		FarDistance:       50,
+		FarUpdateInterval: time.Hour,
+		Log:               zerolog.Nop(),
+	})
+	p.Use(im)
+
+	peer1 := newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	raw := encodePosition(t, 1, 0, 0)
+	peer2.unreliableWriter.Write(raw)
+	assert.Len(t, peer2.unreliable.written, 1, "the recipient position is unknown")
+
+	peer1.unreliableWriter.Write(encodePosition(t, 2, 5, 0))
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 0, 0))
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 0, 1))
+	assert.Len(t, peer2.unreliable.written, 3, "near positions are delivered at full rate")
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 30, 0))
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 30, 1))
+	assert.Len(t, peer2.unreliable.written, 3, "far positions are decimated")
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 60, 0))
+	assert.Len(t, peer2.unreliable.written, 3, "positions beyond the far distance are culled")
+
+	peer2.reliableWriter.Write(encodeChat(t, 1, "a", "hi"))
+	assert.Len(t, peer2.reliable.written, 1, "only positions are filtered")
+
+	stats := im.Stats()
+	assert.Equal(t, uint64(3), stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Decimated)
+	assert.Equal(t, uint64(1), stats.Culled)
+	assert.NotZero(t, stats.BytesSkipped)
+	assert.Equal(t, InterestStats{}, im.Stats(), "the stats are reset on each call")
+}
+
+func TestInterestManagerCompactPositions(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	im := NewInterestManager(&InterestConfig{
+		NearDistance:      10,
+		FarDistance:       50,
+		FarUpdateInterval: time.Hour,
+		Log:               zerolog.Nop(),
+	})
+	p.Use(im)
+
+	peer1 := newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	peer1.unreliableWriter.Write(encodePosition(t, 2, 0, 0))
+
+	encoder := protocol.NewPositionEncoder(2)
+	for i := 0; i < 4; i++ {
+		compact := encoder.Encode(&protocol.PositionData{Category: protocol.Category_POSITION, PositionX: 30, PositionZ: float32(i)})
+		peer2.unreliableWriter.Write(encodeFW(t, 1, compact))
+	}
+
+	assert.Len(t, peer2.unreliable.written, 2, "far compact positions are decimated, except the keyframes")
+
+	stats := im.Stats()
+	assert.Equal(t, uint64(2), stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Decimated)
+}
+
+func TestInterestManagerStaleRecipientPosition(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	im := NewInterestManager(&InterestConfig{
+		NearDistance:      10,
+		FarDistance:       50,
+		FarUpdateInterval: time.Hour,
+		StalePosition:     time.Minute,
+		Log:               zerolog.Nop(),
+	})
+	p.Use(im)
+
+	peer1 := newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	peer1.unreliableWriter.Write(encodePosition(t, 2, 0, 0))
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 60, 0))
+	assert.Len(t, peer2.unreliable.written, 0, "the recipient position is recent")
+
+	p.getPeer(2).PositionTime = time.Now().Add(-2 * time.Minute)
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 60, 0))
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 30, 0))
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 30, 1))
+	assert.Len(t, peer2.unreliable.written, 3, "a stale recipient position counts as near")
+}
@@ -0,0 +1,380 @@
+package commserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/metrics"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+// ChatFilter checks the chat and direct messages text before this server delivers them to its peers
+type ChatFilter interface {
+	// Name identifies the filter in the logs and metrics
+	Name() string
+	// Allow returns false if the message has to be blocked
+	Allow(from *Peer, text string) bool
+}
+
+// MaxLengthFilter blocks the messages longer than MaxLength characters
+type MaxLengthFilter struct {
+	MaxLength int
+}
+
+// Name ...
+func (f *MaxLengthFilter) Name() string { return "maxLength" }
+
+// Allow ...
+func (f *MaxLengthFilter) Allow(from *Peer, text string) bool {
+	return utf8.RuneCountInString(text) <= f.MaxLength
+}
+
+// BannedWordsFilter blocks the messages containing any of the banned words, case insensitive
+type BannedWordsFilter struct {
+	words map[string]bool
+}
+
+// NewBannedWordsFilter creates a filter for the given words
+func NewBannedWordsFilter(words []string) *BannedWordsFilter {
+	f := &BannedWordsFilter{words: make(map[string]bool, len(words))}
+
+	for _, word := range words {
+		f.words[strings.ToLower(word)] = true
+	}
+
+	return f
+}
+
+// ReadWordList reads a file with one word per line, empty lines and lines starting with # are
+// ignored
+func ReadWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	words := []string{}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+
+		words = append(words, word)
+	}
+
+	return words, scanner.Err()
+}
+
+// Name ...
+func (f *BannedWordsFilter) Name() string { return "bannedWords" }
+
+// Allow ...
+func (f *BannedWordsFilter) Allow(from *Peer, text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		if f.words[word] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type sentText struct {
+	text string
+	time time.Time
+}
+
+// RepetitionFilter blocks a peer sending the same text more than MaxRepetitions times within Window
+type RepetitionFilter struct {
+	window         time.Duration
+	maxRepetitions int
+	sent           map[uint64][]sentText
+}
+
+// NewRepetitionFilter creates a RepetitionFilter
+func NewRepetitionFilter(window time.Duration, maxRepetitions int) *RepetitionFilter {
+	return &RepetitionFilter{
+		window:         window,
+		maxRepetitions: maxRepetitions,
+		sent:           make(map[uint64][]sentText),
+	}
+}
+
+// Name ...
+func (f *RepetitionFilter) Name() string { return "repetition" }
+
+// Allow ...
+func (f *RepetitionFilter) Allow(from *Peer, text string) bool {
+	now := time.Now()
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+
+	sent := f.sent[from.Alias]
+
+	i := 0
+	for i < len(sent) && now.Sub(sent[i].time) > f.window {
+		i++
+	}
+
+	sent = sent[i:]
+
+	repetitions := 0
+	for _, s := range sent {
+		if s.text == text {
+			repetitions++
+		}
+	}
+
+	if repetitions >= f.maxRepetitions {
+		f.sent[from.Alias] = sent
+		return false
+	}
+
+	f.sent[from.Alias] = append(sent, sentText{text: text, time: now})
+
+	return true
+}
+
+// OnPeerRemoved ...
+func (f *RepetitionFilter) OnPeerRemoved(p *Peer) {
+	delete(f.sent, p.Alias)
+}
+
+// MuteList blocks the messages of the muted identities delivered by this server to its peers, it's
+// safe to use from the admin API goroutines. The list is in memory and per server: the messages
+// forwarded to other servers are not moderated, so a user has to be muted on every server of the
+// cluster. Peers are identified once they send an identity message or show up in the stats, so
+// while the list isn't empty the messages of the peers not identified yet are blocked too, otherwise
+// a muted user could chat until the server learns who it is.
+type MuteList struct {
+	mux        sync.RWMutex
+	identities map[string]time.Time
+}
+
+// NewMuteList creates an empty mute list
+func NewMuteList() *MuteList {
+	return &MuteList{identities: make(map[string]time.Time)}
+}
+
+// Name ...
+func (l *MuteList) Name() string { return "muted" }
+
+// Allow ...
+func (l *MuteList) Allow(from *Peer, text string) bool {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	if from.Identity == "" {
+		return len(l.identities) == 0
+	}
+
+	_, muted := l.identities[from.Identity]
+
+	return !muted
+}
+
+// Mute adds identity to the list
+func (l *MuteList) Mute(identity string) {
+	l.mux.Lock()
+	l.identities[identity] = time.Now()
+	l.mux.Unlock()
+}
+
+// Unmute removes identity from the list, it returns false if it wasn't muted
+func (l *MuteList) Unmute(identity string) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if _, ok := l.identities[identity]; !ok {
+		return false
+	}
+
+	delete(l.identities, identity)
+
+	return true
+}
+
+// List returns the muted identities, sorted
+func (l *MuteList) List() []string {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	identities := make([]string, 0, len(l.identities))
+	for identity := range l.identities {
+		identities = append(identities, identity)
+	}
+
+	sort.Strings(identities)
+
+	return identities
+}
+
+// ModeratorConfig is the moderator configuration
+type ModeratorConfig struct {
+	Filters  []ChatFilter
+	DDClient *metrics.Client
+	Tags     []string
+	Log      logging.Logger
+}
+
+// Moderator is a pipeline handler that runs the chat filters, in order, blocking a message as soon
+// as one of them rejects it. The pipeline only runs for the deliveries of this server to its peers,
+// so it doesn't moderate the messages forwarded to other servers.
+type Moderator struct {
+	filters  []ChatFilter
+	ddClient *metrics.Client
+	tags     []string
+	log      logging.Logger
+
+	mux     sync.Mutex
+	blocked map[string]uint64
+}
+
+// NewModerator creates a Moderator, it has to be registered in a Pipeline
+func NewModerator(config *ModeratorConfig) *Moderator {
+	return &Moderator{
+		filters:  config.Filters,
+		ddClient: config.DDClient,
+		tags:     config.Tags,
+		log:      config.Log,
+		blocked:  make(map[string]uint64),
+	}
+}
+
+// OnMessage ...
+func (m *Moderator) OnMessage(msg *Message) bool {
+	var id, text string
+
+	switch data := msg.Data.(type) {
+	case *protocol.ChatData:
+		id, text = data.MessageId, data.Text
+	case *protocol.DirectMessageData:
+		id, text = data.MessageId, data.Text
+	default:
+		return true
+	}
+
+	for _, filter := range m.filters {
+		if filter.Allow(msg.From, text) {
+			continue
+		}
+
+		name := filter.Name()
+
+		m.mux.Lock()
+		m.blocked[name]++
+		m.mux.Unlock()
+
+		m.log.Info().
+			Str("filter", name).
+			Uint64("alias", msg.FromAlias).
+			Str("identity", msg.From.Identity).
+			Str("category", msg.Category.String()).
+			Str("id", id).
+			Msg("chat message blocked")
+
+		if m.ddClient != nil {
+			m.ddClient.Incr("chat.blocked", append([]string{"filter:" + name}, m.tags...))
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// OnPeerRemoved notifies the filters that keep per peer state
+func (m *Moderator) OnPeerRemoved(p *Peer) {
+	for _, filter := range m.filters {
+		if h, ok := filter.(PeerHandler); ok {
+			h.OnPeerRemoved(p)
+		}
+	}
+}
+
+// BlockedCount returns the number of messages blocked by each filter
+func (m *Moderator) BlockedCount() map[string]uint64 {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	blocked := make(map[string]uint64, len(m.blocked))
+	for name, count := range m.blocked {
+		blocked[name] = count
+	}
+
+	return blocked
+}
+
+// RegisterModerationAPI adds the moderation admin endpoints of this server to mux, they require the
+// server secret as a bearer token. The mutes only apply to this server:
+//
+//	GET /moderation/stats returns the blocked messages count by filter
+//	GET /moderation/mutes returns the muted identities
+//	POST /moderation/mutes with a {"identity": "..."} body mutes an identity
+//	DELETE /moderation/mutes?identity=... unmutes an identity
+func RegisterModerationAPI(mux *http.ServeMux, secret string, moderator *Moderator, mutes *MuteList) {
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(v) //nolint:errcheck
+	}
+
+	mux.HandleFunc("/moderation/stats", requireSecret(secret, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{"blocked": moderator.BlockedCount()})
+	}))
+
+	mux.HandleFunc("/moderation/mutes", requireSecret(secret, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, mutes.List())
+		case http.MethodPost:
+			body := struct {
+				Identity string `json:"identity"`
+			}{}
+
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Identity == "" {
+				http.Error(w, "invalid body, expected {\"identity\": \"...\"}", http.StatusBadRequest)
+				return
+			}
+
+			mutes.Mute(body.Identity)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			identity := r.URL.Query().Get("identity")
+			if identity == "" {
+				http.Error(w, "missing identity", http.StatusBadRequest)
+				return
+			}
+
+			if !mutes.Unmute(identity) {
+				http.Error(w, "identity not muted", http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
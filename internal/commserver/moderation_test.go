@@ -0,0 +1,110 @@
+package commserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatFilters(t *testing.T) {
+	peer := &Peer{Alias: 1, Identity: "0xabc"}
+
+	t.Run("max length", func(t *testing.T) {
+		f := &MaxLengthFilter{MaxLength: 5}
+		assert.True(t, f.Allow(peer, "héllo"))
+		assert.False(t, f.Allow(peer, "hello!"))
+	})
+
+	t.Run("banned words", func(t *testing.T) {
+		f := NewBannedWordsFilter([]string{"Spam"})
+		assert.True(t, f.Allow(peer, "spammer is not banned"))
+		assert.False(t, f.Allow(peer, "buy SPAM, now!"))
+	})
+
+	t.Run("repetition", func(t *testing.T) {
+		f := NewRepetitionFilter(time.Minute, 2)
+		assert.True(t, f.Allow(peer, "hi"))
+		assert.True(t, f.Allow(peer, " HI "))
+		assert.False(t, f.Allow(peer, "hi"))
+		assert.True(t, f.Allow(peer, "bye"))
+		assert.True(t, f.Allow(&Peer{Alias: 2}, "hi"))
+
+		f.OnPeerRemoved(peer)
+		assert.True(t, f.Allow(peer, "hi"))
+	})
+
+	t.Run("mute list", func(t *testing.T) {
+		l := NewMuteList()
+		assert.True(t, l.Allow(&Peer{Alias: 2}, "hi"))
+
+		l.Mute("0xabc")
+		assert.False(t, l.Allow(peer, "hi"))
+		assert.True(t, l.Allow(&Peer{Alias: 2, Identity: "0xdef"}, "hi"))
+		assert.False(t, l.Allow(&Peer{Alias: 3}, "hi"), "unidentified peers are blocked while someone is muted")
+		assert.True(t, l.Unmute("0xabc"))
+		assert.False(t, l.Unmute("0xabc"))
+		assert.True(t, l.Allow(peer, "hi"))
+		assert.True(t, l.Allow(&Peer{Alias: 3}, "hi"))
+	})
+}
+
+func TestModerator(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	m := NewModerator(&ModeratorConfig{
+		Filters: []ChatFilter{&MaxLengthFilter{MaxLength: 5}},
+		Log:     zerolog.Nop(),
+	})
+	p.Use(m)
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	peer2.reliableWriter.Write(encodeChat(t, 1, "a", "hi"))
+	peer2.reliableWriter.Write(encodeChat(t, 1, "b", "too long"))
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 0, 0))
+
+	assert.Len(t, peer2.reliable.written, 1)
+	assert.Len(t, peer2.unreliable.written, 1)
+	assert.Equal(t, map[string]uint64{"maxLength": 1}, m.BlockedCount())
+}
+
+func TestModerationAPI(t *testing.T) {
+	mutes := NewMuteList()
+	mux := http.NewServeMux()
+	RegisterModerationAPI(mux, "secret", NewModerator(&ModeratorConfig{Log: zerolog.Nop()}), mutes)
+
+	token := "secret"
+	do := func(method string, url string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, do("POST", "/moderation/mutes", `{"identity": "0xabc"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/moderation/mutes", `{}`).Code)
+	assert.Equal(t, []string{"0xabc"}, mutes.List())
+
+	w := do("GET", "/moderation/mutes", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `["0xabc"]`, w.Body.String())
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/moderation/mutes?identity=0xabc", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/moderation/mutes?identity=0xabc", "").Code)
+
+	w = do("GET", "/moderation/stats", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"blocked": {}}`, w.Body.String())
+
+	token = "wrong"
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/moderation/mutes", `{"identity": "0xdef"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/moderation/mutes", "").Code)
+	assert.Empty(t, mutes.List())
+}
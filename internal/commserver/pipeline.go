@@ -0,0 +1,723 @@
+package commserver
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	brokerProtocol "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const (
+	defaultMaxPeerBufferSize = 1024 * 1024
+	remotePeerTimeout        = 1 * time.Minute
+
+	// maxBatchSize is the largest frame the simulation clients read, they read the data channels into
+	// 1024 byte buffers and drop the rest of a larger frame
+	maxBatchSize        = 1024
+	defaultBatchMaxSize = maxBatchSize
+)
+
+// Message is a topic message forwarded by the broker to the clients, it's decoded once no matter
+// how many peers receive it
+type Message struct {
+	Raw       []byte
+	Reliable  bool
+	Type      brokerProtocol.MessageType
+	FromAlias uint64
+	Identity  string
+
+	// From is the sender state, its identity is known if it ever sent an identity message
+	From *Peer
+
+	// Topic is inferred from the last position sent by the peer, it's empty if unknown. Direct
+	// messages are always in the protocol.DirectMessageTopic.
+	Topic string
+
+	Category protocol.Category
+	Body     []byte
+
+	// Data is the decoded body for the known categories, nil otherwise
+	Data proto.Message
+
+	// Position is the sender position of the position and compact position messages, nil if it's
+	// a compact position whose baseline is unknown
+	Position *protocol.PositionData
+
+	// Batch are the messages of a client batch, each one is a forwarded message from the same
+	// sender. The handlers are called for each of them, never for the batch itself.
+	Batch []*Message
+
+	dropped bool
+}
+
+// Peer is the pipeline state of a peer, either connected to this server or seen through the
+// messages forwarded by other servers
+type Peer struct {
+	Alias    uint64
+	Identity string
+	Position *protocol.PositionData
+	Topic    string
+	LastSeen time.Time
+	// PositionTime is when Position was received
+	PositionTime time.Time
+
+	// positions decodes the compact positions of the peer
+	positions *protocol.PositionDecoder
+
+	reliable   broker.WriterController
+	unreliable broker.WriterController
+
+	reliableBatch   *batchWriter
+	unreliableBatch *batchWriter
+}
+
+// IsLocal returns true if the peer is connected to this server
+func (p *Peer) IsLocal() bool {
+	return p.reliable != nil || p.unreliable != nil
+}
+
+// WriteReliable writes a forwarded message to the peer reliable channel, skipping the pipeline
+func (p *Peer) WriteReliable(raw []byte) {
+	if p.reliableBatch != nil {
+		p.reliableBatch.Write(raw, true)
+	} else if p.reliable != nil {
+		p.reliable.Write(raw)
+	}
+}
+
+// WriteUnreliable writes a forwarded message to the peer unreliable channel, skipping the pipeline
+func (p *Peer) WriteUnreliable(raw []byte) {
+	if p.unreliableBatch != nil {
+		p.unreliableBatch.Write(raw, true)
+	} else if p.unreliable != nil {
+		p.unreliable.Write(raw)
+	}
+}
+
+// MessageHandler is called once for each forwarded message, returning false drops the message
+type MessageHandler interface {
+	OnMessage(m *Message) bool
+}
+
+// DeliveryHandler is called for each recipient of a message, returning false drops the message for
+// that recipient only
+type DeliveryHandler interface {
+	OnDelivery(m *Message, to *Peer) bool
+}
+
+// PeerHandler is notified when a peer is removed from the pipeline
+type PeerHandler interface {
+	OnPeerRemoved(p *Peer)
+}
+
+// IdentityHandler is notified when the identity of a local peer is known, from its broker stats or
+// its identity messages
+type IdentityHandler interface {
+	OnPeerIdentified(p *Peer)
+}
+
+// PruneHandler is called on each pipeline Prune, after the removed peers handlers, to expire its
+// own state
+type PruneHandler interface {
+	OnPrune(now time.Time)
+}
+
+// PipelineConfig is the pipeline configuration
+type PipelineConfig struct {
+	// MaxPeerBufferSize is the max data channel buffered amount before queueing messages
+	MaxPeerBufferSize uint64
+
+	// BatchFlushInterval enables the batching of the forwarded messages: the messages to each peer
+	// are packed in a single frame, sent every BatchFlushInterval or once it reaches BatchMaxSize
+	// bytes, up to 1024. The clients have to unpack the protocol.Category_FORWARDED_BATCH messages.
+	BatchFlushInterval time.Duration
+	BatchMaxSize       int
+
+	Log logging.Logger
+}
+
+// Pipeline processes the topic messages forwarded by the broker. The broker only exposes the
+// writes to each peer, so the pipeline wraps the broker writer controllers, it decodes each message
+// once and runs the handlers before writing it. The handlers are called with the pipeline lock held.
+type Pipeline struct {
+	mux sync.Mutex
+
+	maxPeerBufferSize  uint64
+	batchFlushInterval time.Duration
+	batchMaxSize       int
+	log                logging.Logger
+
+	peers map[uint64]*Peer
+
+	messageHandlers  []MessageHandler
+	deliveryHandlers []DeliveryHandler
+	peerHandlers     []PeerHandler
+	identityHandlers []IdentityHandler
+	pruneHandlers    []PruneHandler
+
+	last        []byte
+	lastMessage *Message
+	lastVerdict bool
+}
+
+// NewPipeline creates a pipeline with no handlers
+func NewPipeline(config *PipelineConfig) *Pipeline {
+	maxPeerBufferSize := config.MaxPeerBufferSize
+	if maxPeerBufferSize == 0 {
+		maxPeerBufferSize = defaultMaxPeerBufferSize
+	}
+
+	batchMaxSize := config.BatchMaxSize
+	if batchMaxSize == 0 {
+		batchMaxSize = defaultBatchMaxSize
+	}
+
+	if batchMaxSize > maxBatchSize {
+		config.Log.Warn().Int("batchMaxSize", batchMaxSize).Int("max", maxBatchSize).Msg("batch max size capped")
+		batchMaxSize = maxBatchSize
+	}
+
+	return &Pipeline{
+		maxPeerBufferSize:  maxPeerBufferSize,
+		batchFlushInterval: config.BatchFlushInterval,
+		batchMaxSize:       batchMaxSize,
+		log:                config.Log,
+		peers:              make(map[uint64]*Peer),
+	}
+}
+
+// Use registers a handler, it has to implement at least one of MessageHandler, DeliveryHandler,
+// PeerHandler or IdentityHandler. Handlers run in registration order.
+func (p *Pipeline) Use(handler interface{}) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	registered := false
+
+	if h, ok := handler.(MessageHandler); ok {
+		p.messageHandlers = append(p.messageHandlers, h)
+		registered = true
+	}
+
+	if h, ok := handler.(DeliveryHandler); ok {
+		p.deliveryHandlers = append(p.deliveryHandlers, h)
+		registered = true
+	}
+
+	if h, ok := handler.(PeerHandler); ok {
+		p.peerHandlers = append(p.peerHandlers, h)
+		registered = true
+	}
+
+	if h, ok := handler.(IdentityHandler); ok {
+		p.identityHandlers = append(p.identityHandlers, h)
+		registered = true
+	}
+
+	if h, ok := handler.(PruneHandler); ok {
+		p.pruneHandlers = append(p.pruneHandlers, h)
+		registered = true
+	}
+
+	if !registered {
+		panic("invalid pipeline handler")
+	}
+}
+
+// ReliableWriterControllerFactory is a broker.WriterControllerFactory for the reliable channel
+func (p *Pipeline) ReliableWriterControllerFactory(alias uint64, writer broker.PeerWriter) broker.WriterController {
+	inner := broker.NewBufferedWriterController(writer, 10, p.maxPeerBufferSize)
+	w := &pipelineWriter{pipeline: p, alias: alias, reliable: true, inner: inner}
+
+	if p.batchFlushInterval > 0 {
+		w.batch = &batchWriter{inner: inner, maxSize: p.batchMaxSize}
+	}
+
+	p.mux.Lock()
+	peer := p.getPeer(alias)
+	peer.reliable = inner
+	peer.reliableBatch = w.batch
+	p.mux.Unlock()
+
+	return w
+}
+
+// UnreliableWriterControllerFactory is a broker.WriterControllerFactory for the unreliable channel
+func (p *Pipeline) UnreliableWriterControllerFactory(alias uint64, writer broker.PeerWriter) broker.WriterController {
+	inner := broker.NewFixedQueueWriterController(writer, 10, p.maxPeerBufferSize)
+	w := &pipelineWriter{pipeline: p, alias: alias, reliable: false, inner: inner}
+
+	if p.batchFlushInterval > 0 {
+		w.batch = &batchWriter{inner: inner, maxSize: p.batchMaxSize}
+	}
+
+	p.mux.Lock()
+	peer := p.getPeer(alias)
+	peer.unreliable = inner
+	peer.unreliableBatch = w.batch
+	p.mux.Unlock()
+
+	return w
+}
+
+// ProcessBatches flushes the batched messages every flush interval, it has to run in its own
+// goroutine if batching is enabled
+func (p *Pipeline) ProcessBatches() {
+	ticker := time.NewTicker(p.batchFlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p.FlushBatches()
+	}
+}
+
+// FlushBatches writes the batched messages of every peer
+func (p *Pipeline) FlushBatches() {
+	batches := []*batchWriter{}
+
+	p.mux.Lock()
+	for _, peer := range p.peers {
+		if peer.reliableBatch != nil {
+			batches = append(batches, peer.reliableBatch)
+		}
+
+		if peer.unreliableBatch != nil {
+			batches = append(batches, peer.unreliableBatch)
+		}
+	}
+	p.mux.Unlock()
+
+	for _, batch := range batches {
+		batch.Flush()
+	}
+}
+
+// Prune removes the local peers that are no longer connected, and the remote peers not seen for a
+// while. It also takes the identity of the local peers from the stats, and lets the handlers expire
+// their own state.
+func (p *Pipeline) Prune(stats broker.Stats) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	for alias, peer := range p.peers {
+		if peer.IsLocal() {
+			if peerStats, ok := stats.Peers[alias]; ok {
+				p.setIdentity(peer, string(peerStats.Identity))
+				continue
+			}
+		} else if time.Since(peer.LastSeen) < remotePeerTimeout {
+			continue
+		}
+
+		delete(p.peers, alias)
+
+		for _, h := range p.peerHandlers {
+			h.OnPeerRemoved(peer)
+		}
+	}
+
+	now := time.Now()
+	for _, h := range p.pruneHandlers {
+		h.OnPrune(now)
+	}
+}
+
+// setIdentity sets the identity of a peer if it's known, notifying the identity handlers if it's a
+// local peer
+func (p *Pipeline) setIdentity(peer *Peer, identity string) {
+	if identity == "" || identity == peer.Identity {
+		return
+	}
+
+	peer.Identity = identity
+
+	if peer.IsLocal() {
+		for _, h := range p.identityHandlers {
+			h.OnPeerIdentified(peer)
+		}
+	}
+}
+
+func (p *Pipeline) getPeer(alias uint64) *Peer {
+	peer := p.peers[alias]
+	if peer == nil {
+		peer = &Peer{Alias: alias, LastSeen: time.Now()}
+		p.peers[alias] = peer
+	}
+
+	return peer
+}
+
+// process runs the handlers for a message written to a peer, it returns the message to write, nil if
+// it's dropped, and whether it's a forwarded topic message
+func (p *Pipeline) process(alias uint64, reliable bool, raw []byte) ([]byte, bool) {
+	header := brokerProtocol.MessageHeader{}
+	if err := proto.Unmarshal(raw, &header); err != nil {
+		return raw, false
+	}
+
+	msgType := header.GetType()
+	if msgType != brokerProtocol.MessageType_TOPIC_FW && msgType != brokerProtocol.MessageType_TOPIC_IDENTITY_FW {
+		return raw, false
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	// NOTE: the broker writes the same slice to every recipient of a message, from a single goroutine
+	if !sameSlice(raw, p.last) {
+		p.last = raw
+		p.lastMessage = nil
+		p.lastVerdict = false
+
+		// NOTE: an undecodable message is dropped, the handlers cannot filter it, e.g. a direct message
+		m, err := decodeMessage(reliable, msgType, raw)
+		if err != nil {
+			p.log.Debug().Err(err).Msg("undecodable forwarded message dropped")
+			return nil, true
+		}
+
+		p.lastMessage = m
+
+		if m.Batch == nil {
+			p.lastVerdict = p.onMessage(m)
+		} else {
+			for _, sub := range m.Batch {
+				sub.dropped = !p.onMessage(sub)
+				p.lastVerdict = p.lastVerdict || !sub.dropped
+			}
+		}
+	}
+
+	if !p.lastVerdict {
+		return nil, true
+	}
+
+	to := p.getPeer(alias)
+
+	if p.lastMessage.Batch == nil {
+		if !p.deliver(p.lastMessage, to) {
+			return nil, true
+		}
+
+		return raw, true
+	}
+
+	batch := make([]*Message, 0, len(p.lastMessage.Batch))
+
+	for _, sub := range p.lastMessage.Batch {
+		if !sub.dropped && p.deliver(sub, to) {
+			batch = append(batch, sub)
+		}
+	}
+
+	switch len(batch) {
+	case 0:
+		return nil, true
+	case len(p.lastMessage.Batch):
+		return raw, true
+	case 1:
+		return batch[0].Raw, true
+	}
+
+	bodies := make([][]byte, 0, len(batch))
+	for _, sub := range batch {
+		bodies = append(bodies, sub.Body)
+	}
+
+	raw, err := encodeBatch(p.lastMessage, protocol.Category_BATCH, bodies)
+	if err != nil {
+		p.log.Error().Err(err).Msg("cannot encode batch")
+		return nil, true
+	}
+
+	return raw, true
+}
+
+func (p *Pipeline) onMessage(m *Message) bool {
+	from := p.getPeer(m.FromAlias)
+	from.LastSeen = time.Now()
+	m.From = from
+
+	p.setIdentity(from, m.Identity)
+
+	switch data := m.Data.(type) {
+	case *protocol.PositionData:
+		m.Position = data
+	case *protocol.CompactPositionData:
+		if from.positions == nil {
+			from.positions = protocol.NewPositionDecoder()
+		}
+
+		position, err := from.positions.Decode(data)
+		if err != nil {
+			p.log.Debug().Err(err).Uint64("alias", m.FromAlias).Msg("cannot decode compact position")
+		}
+
+		m.Position = position
+	}
+
+	if m.Position != nil {
+		from.Position = m.Position
+		from.PositionTime = from.LastSeen
+		from.Topic = protocol.CellTopic(float64(m.Position.PositionX), float64(m.Position.PositionZ))
+	}
+
+	switch m.Category {
+	case protocol.Category_DIRECT_MESSAGE, protocol.Category_DIRECT_MESSAGE_ACK:
+		m.Topic = protocol.DirectMessageTopic
+	default:
+		m.Topic = from.Topic
+	}
+
+	for _, h := range p.messageHandlers {
+		if !h.OnMessage(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Pipeline) deliver(m *Message, to *Peer) bool {
+	for _, h := range p.deliveryHandlers {
+		if !h.OnDelivery(m, to) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameSlice(a []byte, b []byte) bool {
+	return len(a) > 0 && len(a) == len(b) && &a[0] == &b[0]
+}
+
+// copyRaw returns a copy of the encoded message, for the handlers that keep it. The broker owns the
+// slice, it may reuse it once the message is written.
+func (m *Message) copyRaw() []byte {
+	raw := make([]byte, len(m.Raw))
+	copy(raw, m.Raw)
+
+	return raw
+}
+
+func decodeMessage(reliable bool, msgType brokerProtocol.MessageType, raw []byte) (*Message, error) {
+	m := &Message{Raw: raw, Reliable: reliable, Type: msgType}
+
+	if msgType == brokerProtocol.MessageType_TOPIC_FW {
+		message := brokerProtocol.TopicFWMessage{}
+		if err := proto.Unmarshal(raw, &message); err != nil {
+			return nil, err
+		}
+
+		m.FromAlias = message.FromAlias
+		m.Body = message.Body
+	} else {
+		message := brokerProtocol.TopicIdentityFWMessage{}
+		if err := proto.Unmarshal(raw, &message); err != nil {
+			return nil, err
+		}
+
+		m.FromAlias = message.FromAlias
+		m.Identity = string(message.Identity)
+		m.Body = message.Body
+	}
+
+	if err := decodeBody(m); err != nil {
+		return nil, err
+	}
+
+	if m.Category != protocol.Category_BATCH {
+		return m, nil
+	}
+
+	batch := protocol.BatchData{}
+	if err := proto.Unmarshal(m.Body, &batch); err != nil {
+		return nil, err
+	}
+
+	m.Batch = make([]*Message, 0, len(batch.Messages))
+
+	for _, body := range batch.Messages {
+		sub := &Message{Reliable: reliable, Type: msgType, FromAlias: m.FromAlias, Identity: m.Identity, Body: body}
+
+		if err := decodeBody(sub); err != nil {
+			return nil, err
+		}
+
+		if sub.Category == protocol.Category_BATCH {
+			return nil, errors.New("nested batch")
+		}
+
+		raw, err := encodeForwarded(m, body)
+		if err != nil {
+			return nil, err
+		}
+
+		sub.Raw = raw
+		m.Batch = append(m.Batch, sub)
+	}
+
+	return m, nil
+}
+
+func decodeBody(m *Message) error {
+	dataHeader := protocol.DataHeader{}
+	if err := proto.Unmarshal(m.Body, &dataHeader); err != nil {
+		return err
+	}
+
+	m.Category = dataHeader.Category
+
+	switch dataHeader.Category {
+	case protocol.Category_POSITION:
+		m.Data = &protocol.PositionData{}
+	case protocol.Category_COMPACT_POSITION:
+		m.Data = &protocol.CompactPositionData{}
+	case protocol.Category_PROFILE:
+		m.Data = &protocol.ProfileData{}
+	case protocol.Category_CHAT:
+		m.Data = &protocol.ChatData{}
+	case protocol.Category_DIRECT_MESSAGE:
+		m.Data = &protocol.DirectMessageData{}
+	case protocol.Category_DIRECT_MESSAGE_ACK:
+		m.Data = &protocol.DirectMessageAckData{}
+	default:
+		return nil
+	}
+
+	return proto.Unmarshal(m.Body, m.Data)
+}
+
+// encodeForwarded encodes body as a forwarded message from the sender of m
+func encodeForwarded(m *Message, body []byte) ([]byte, error) {
+	if m.Type == brokerProtocol.MessageType_TOPIC_FW {
+		return proto.Marshal(&brokerProtocol.TopicFWMessage{
+			Type:      brokerProtocol.MessageType_TOPIC_FW,
+			FromAlias: m.FromAlias,
+			Body:      body,
+		})
+	}
+
+	return proto.Marshal(&brokerProtocol.TopicIdentityFWMessage{
+		Type:      brokerProtocol.MessageType_TOPIC_IDENTITY_FW,
+		FromAlias: m.FromAlias,
+		Identity:  []byte(m.Identity),
+		Body:      body,
+	})
+}
+
+// encodeBatch encodes messages as a batch forwarded from the sender of m
+func encodeBatch(m *Message, category protocol.Category, messages [][]byte) ([]byte, error) {
+	body, err := proto.Marshal(&protocol.BatchData{Category: category, Messages: messages})
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeForwarded(m, body)
+}
+
+type pipelineWriter struct {
+	pipeline *Pipeline
+	alias    uint64
+	reliable bool
+	inner    broker.WriterController
+	batch    *batchWriter
+}
+
+func (w *pipelineWriter) Write(raw []byte) {
+	raw, forwarded := w.pipeline.process(w.alias, w.reliable, raw)
+	if raw == nil {
+		return
+	}
+
+	if w.batch != nil {
+		w.batch.Write(raw, forwarded)
+	} else {
+		w.inner.Write(raw)
+	}
+}
+
+func (w *pipelineWriter) OnBufferedAmountLow() {
+	w.inner.OnBufferedAmountLow()
+}
+
+// batchWriter packs the forwarded messages to a peer in a protocol.Category_FORWARDED_BATCH message,
+// from alias 0. Any other message flushes the batch first, to keep the order. The size is the one of
+// the encoded batch, framing included, so no frame is larger than maxSize unless it holds a single
+// message that already is.
+type batchWriter struct {
+	mux     sync.Mutex
+	inner   broker.WriterController
+	maxSize int
+	pending [][]byte
+	size    int
+}
+
+func (w *batchWriter) Write(raw []byte, forwarded bool) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	entrySize := batchEntrySize(len(raw))
+
+	if !forwarded || forwardedBatchSize(entrySize) > w.maxSize {
+		w.flush()
+		w.inner.Write(raw)
+		return
+	}
+
+	if forwardedBatchSize(w.size+entrySize) > w.maxSize {
+		w.flush()
+	}
+
+	w.pending = append(w.pending, raw)
+	w.size += entrySize
+}
+
+func (w *batchWriter) Flush() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.flush()
+}
+
+func (w *batchWriter) flush() {
+	pending := w.pending
+	w.pending = nil
+	w.size = 0
+
+	switch len(pending) {
+	case 0:
+		return
+	case 1:
+		w.inner.Write(pending[0])
+		return
+	}
+
+	raw, err := encodeBatch(&Message{Type: brokerProtocol.MessageType_TOPIC_FW}, protocol.Category_FORWARDED_BATCH, pending)
+	if err != nil {
+		return
+	}
+
+	w.inner.Write(raw)
+}
+
+// batchEntrySize is the encoded size of a message of n bytes in a protocol.BatchData
+func batchEntrySize(n int) int {
+	return 1 + proto.SizeVarint(uint64(n)) + n
+}
+
+// forwardedBatchSize is the size of a forwarded batch frame, with entries of entriesSize bytes
+func forwardedBatchSize(entriesSize int) int {
+	body := proto.Size(&protocol.BatchData{Category: protocol.Category_FORWARDED_BATCH}) + entriesSize
+	header := proto.Size(&brokerProtocol.TopicFWMessage{Type: brokerProtocol.MessageType_TOPIC_FW})
+
+	return header + 1 + proto.SizeVarint(uint64(body)) + body
+}
@@ -0,0 +1,266 @@
+package commserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	brokerProtocol "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+type mockPeerWriter struct {
+	written [][]byte
+}
+
+func (w *mockPeerWriter) BufferedAmount() uint64 { return 0 }
+
+func (w *mockPeerWriter) Write(p []byte) error {
+	w.written = append(w.written, p)
+	return nil
+}
+
+type testPeer struct {
+	reliable         *mockPeerWriter
+	unreliable       *mockPeerWriter
+	reliableWriter   broker.WriterController
+	unreliableWriter broker.WriterController
+}
+
+func newTestPeer(p *Pipeline, alias uint64) *testPeer {
+	peer := &testPeer{reliable: &mockPeerWriter{}, unreliable: &mockPeerWriter{}}
+	peer.reliableWriter = p.ReliableWriterControllerFactory(alias, peer.reliable)
+	peer.unreliableWriter = p.UnreliableWriterControllerFactory(alias, peer.unreliable)
+	return peer
+}
+
+func encodeFW(t *testing.T, alias uint64, data proto.Message) []byte {
+	body, err := proto.Marshal(data)
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&brokerProtocol.TopicFWMessage{
+		Type:      brokerProtocol.MessageType_TOPIC_FW,
+		FromAlias: alias,
+		Body:      body,
+	})
+	require.NoError(t, err)
+
+	return raw
+}
+
+func encodePosition(t *testing.T, alias uint64, x float32, z float32) []byte {
+	return encodeFW(t, alias, &protocol.PositionData{Category: protocol.Category_POSITION, PositionX: x, PositionZ: z})
+}
+
+func encodeChat(t *testing.T, alias uint64, id string, text string) []byte {
+	return encodeFW(t, alias, &protocol.ChatData{Category: protocol.Category_CHAT, MessageId: id, Text: text})
+}
+
+type dropHandler struct {
+	messages   int
+	deliveries int
+}
+
+func (h *dropHandler) OnMessage(m *Message) bool {
+	h.messages++
+	return m.Category != protocol.Category_CHAT
+}
+
+func (h *dropHandler) OnDelivery(m *Message, to *Peer) bool {
+	h.deliveries++
+	return to.Alias != 3
+}
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	h := &dropHandler{}
+	p.Use(h)
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+
+	raw := encodePosition(t, 1, 10, 10)
+	peer2.unreliableWriter.Write(raw)
+	peer3.unreliableWriter.Write(raw)
+
+	assert.Equal(t, 1, h.messages, "a message has to be processed once")
+	assert.Equal(t, 2, h.deliveries)
+	assert.Len(t, peer2.unreliable.written, 1)
+	assert.Len(t, peer3.unreliable.written, 0)
+	assert.Equal(t, protocol.CellTopic(10, 10), p.peers[1].Topic)
+
+	raw = encodeChat(t, 1, "1", "hi")
+	peer2.reliableWriter.Write(raw)
+	assert.Equal(t, 2, h.messages)
+	assert.Len(t, peer2.reliable.written, 0)
+
+	ping, err := proto.Marshal(&brokerProtocol.PingMessage{Type: brokerProtocol.MessageType_PING})
+	require.NoError(t, err)
+	peer3.reliableWriter.Write(ping)
+	assert.Equal(t, 2, h.messages)
+	assert.Len(t, peer3.reliable.written, 1)
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{2: {}, 3: {}}})
+	assert.NotContains(t, p.peers, uint64(1))
+	assert.Contains(t, p.peers, uint64(2))
+}
+
+func TestChatHistory(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(NewChatHistory(&ChatHistoryConfig{MaxMessages: 2, Log: zerolog.Nop()}))
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 10, 10))
+
+	for i, id := range []string{"a", "b", "c"} {
+		raw := encodeChat(t, 1, id, "hi")
+		peer2.reliableWriter.Write(raw)
+		assert.Len(t, peer2.reliable.written, i+1)
+	}
+
+	peer2.reliableWriter.Write(encodeChat(t, 1, "c", "hi"))
+	assert.Len(t, peer2.reliable.written, 3, "duplicated message")
+
+	peer3 := newTestPeer(p, 3)
+	raw := encodePosition(t, 1, 10, 10)
+	peer2.unreliableWriter.Write(raw)
+	peer3.unreliableWriter.Write(raw)
+
+	require.Len(t, peer3.reliable.written, 2, "the history is replayed to the new peer")
+	assert.Equal(t, encodeChat(t, 1, "b", "hi"), peer3.reliable.written[0])
+	assert.Equal(t, encodeChat(t, 1, "c", "hi"), peer3.reliable.written[1])
+	assert.Len(t, peer3.unreliable.written, 1)
+	assert.Len(t, peer2.reliable.written, 3, "the history is replayed only once")
+}
+
+func TestPipelineClientBatch(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	h := &dropHandler{}
+	p.Use(h)
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+
+	position, err := proto.Marshal(&protocol.PositionData{Category: protocol.Category_POSITION, PositionX: 10})
+	require.NoError(t, err)
+
+	chat, err := proto.Marshal(&protocol.ChatData{Category: protocol.Category_CHAT, MessageId: "1", Text: "hi"})
+	require.NoError(t, err)
+
+	raw := encodeFW(t, 1, &protocol.BatchData{Category: protocol.Category_BATCH, Messages: [][]byte{position, chat}})
+	peer2.reliableWriter.Write(raw)
+	peer3.reliableWriter.Write(raw)
+
+	assert.Equal(t, 2, h.messages, "the handlers are called for each message in the batch")
+	require.Len(t, peer2.reliable.written, 1)
+	assert.Equal(t, encodeFW(t, 1, &protocol.PositionData{Category: protocol.Category_POSITION, PositionX: 10}),
+		peer2.reliable.written[0], "the chat message is dropped from the batch")
+	assert.Len(t, peer3.reliable.written, 0)
+	assert.Equal(t, protocol.CellTopic(10, 0), p.peers[1].Topic)
+}
+
+func TestPipelineForwardedBatch(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{BatchFlushInterval: time.Hour, BatchMaxSize: 64, Log: zerolog.Nop()})
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	position1 := encodePosition(t, 1, 10, 10)
+	position2 := encodePosition(t, 1, 11, 10)
+	peer2.unreliableWriter.Write(position1)
+	peer2.unreliableWriter.Write(position2)
+	assert.Len(t, peer2.unreliable.written, 0)
+
+	ping, err := proto.Marshal(&brokerProtocol.PingMessage{Type: brokerProtocol.MessageType_PING})
+	require.NoError(t, err)
+	peer2.unreliableWriter.Write(ping)
+
+	require.Len(t, peer2.unreliable.written, 2, "any other message flushes the batch first")
+	assert.Equal(t, ping, peer2.unreliable.written[1])
+
+	message := brokerProtocol.TopicFWMessage{}
+	require.NoError(t, proto.Unmarshal(peer2.unreliable.written[0], &message))
+	assert.Equal(t, uint64(0), message.FromAlias)
+
+	batch := protocol.BatchData{}
+	require.NoError(t, proto.Unmarshal(message.Body, &batch))
+	assert.Equal(t, protocol.Category_FORWARDED_BATCH, batch.Category)
+	assert.Equal(t, [][]byte{position1, position2}, batch.Messages)
+
+	for i := 0; i < 5; i++ {
+		peer2.unreliableWriter.Write(encodePosition(t, 1, float32(i), 10))
+	}
+
+	assert.Len(t, peer2.unreliable.written, 3, "the batch is flushed once full")
+
+	p.FlushBatches()
+	assert.Len(t, peer2.unreliable.written, 4)
+
+	for _, raw := range peer2.unreliable.written {
+		assert.True(t, len(raw) <= 64, "frame of %d bytes, the framing counts", len(raw))
+	}
+}
+
+func TestPipelineBatchAtTheLimit(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{BatchFlushInterval: time.Hour, BatchMaxSize: 4096, Log: zerolog.Nop()})
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	positions := [][]byte{}
+	for len(peer2.unreliable.written) == 0 {
+		position := encodePosition(t, 1, float32(len(positions)), 10)
+		positions = append(positions, position)
+		peer2.unreliableWriter.Write(position)
+	}
+
+	frame := peer2.unreliable.written[0]
+	assert.True(t, len(frame) <= maxBatchSize, "frame of %d bytes, the max size is capped", len(frame))
+	assert.True(t, len(frame) > maxBatchSize-batchEntrySize(len(positions[0])), "frame of %d bytes is not full", len(frame))
+
+	// NOTE: the simulation clients read the frames into 1024 byte buffers
+	buffer := make([]byte, 1024)
+	n := copy(buffer, frame)
+
+	message := brokerProtocol.TopicFWMessage{}
+	require.NoError(t, proto.Unmarshal(buffer[:n], &message))
+
+	batch := protocol.BatchData{}
+	require.NoError(t, proto.Unmarshal(message.Body, &batch))
+	assert.Equal(t, positions[:len(positions)-1], batch.Messages, "the last position goes in the next batch")
+}
+
+func TestPipelineCompactPosition(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(&dropHandler{})
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	encoder := protocol.NewPositionEncoder(10)
+	encode := func(x float32, z float32) []byte {
+		return encodeFW(t, 1, encoder.Encode(&protocol.PositionData{Category: protocol.Category_POSITION, PositionX: x, PositionZ: z}))
+	}
+
+	peer2.unreliableWriter.Write(encode(-40, 10))
+	require.NotNil(t, p.peers[1].Position)
+	assert.Equal(t, float32(-40), p.peers[1].Position.PositionX)
+	assert.Equal(t, protocol.CellTopic(-40, 10), p.peers[1].Topic)
+
+	peer2.unreliableWriter.Write(encode(100, 10))
+	assert.Equal(t, float32(100), p.peers[1].Position.PositionX, "the deltas are decoded against the keyframe")
+	assert.Equal(t, protocol.CellTopic(100, 10), p.peers[1].Topic)
+
+	p.peers[1].positions = protocol.NewPositionDecoder()
+	peer2.unreliableWriter.Write(encode(200, 10))
+	assert.Equal(t, float32(100), p.peers[1].Position.PositionX, "a delta with an unknown baseline is ignored")
+}
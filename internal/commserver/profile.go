@@ -0,0 +1,135 @@
+package commserver
+
+import (
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+const defaultProfileCacheMaxAge = 2 * time.Minute
+
+// ProfileCacheConfig is the profile cache configuration, zero values use the defaults
+type ProfileCacheConfig struct {
+	// MaxAge is how long an announcement is answered for, it has to be longer than the clients
+	// keepalive period
+	MaxAge time.Duration
+	Log    logging.Logger
+}
+
+type profileEntry struct {
+	from *Peer
+	time time.Time
+	raw  []byte
+}
+
+// ProfileCache is a pipeline handler that keeps the last profile announcement of each identity, and
+// sends the announcements of the peers in a topic to the peers that start receiving it, instead of
+// having them wait for the next one
+type ProfileCache struct {
+	maxAge time.Duration
+	log    logging.Logger
+
+	profiles map[string]*profileEntry
+	// recipients is the last time each peer received a message, by topic
+	recipients map[uint64]map[string]time.Time
+}
+
+// NewProfileCache creates a ProfileCache, it has to be registered in a Pipeline
+func NewProfileCache(config *ProfileCacheConfig) *ProfileCache {
+	c := &ProfileCache{
+		maxAge:     config.MaxAge,
+		log:        config.Log,
+		profiles:   make(map[string]*profileEntry),
+		recipients: make(map[uint64]map[string]time.Time),
+	}
+
+	if c.maxAge <= 0 {
+		c.maxAge = defaultProfileCacheMaxAge
+	}
+
+	return c
+}
+
+// OnMessage keeps the profile announcements of the identified peers
+func (c *ProfileCache) OnMessage(m *Message) bool {
+	if _, ok := m.Data.(*protocol.ProfileData); !ok || m.From.Identity == "" {
+		return true
+	}
+
+	c.profiles[m.From.Identity] = &profileEntry{
+		from: m.From,
+		time: time.Now(),
+		raw:  m.copyRaw(),
+	}
+
+	return true
+}
+
+// OnDelivery sends the cached announcements of a topic to the peers that start receiving it
+func (c *ProfileCache) OnDelivery(m *Message, to *Peer) bool {
+	if m.Topic == "" {
+		return true
+	}
+
+	topics := c.recipients[to.Alias]
+	if topics == nil {
+		topics = make(map[string]time.Time)
+		c.recipients[to.Alias] = topics
+	}
+
+	now := time.Now()
+
+	lastReceived, ok := topics[m.Topic]
+	if !ok || now.Sub(lastReceived) > topicIdleTimeout {
+		_, isProfile := m.Data.(*protocol.ProfileData)
+
+		for _, entry := range c.profiles {
+			if entry.from.Topic != m.Topic || entry.from.Alias == to.Alias || now.Sub(entry.time) > c.maxAge {
+				continue
+			}
+
+			// the announcement being delivered
+			if isProfile && entry.from.Alias == m.FromAlias {
+				continue
+			}
+
+			to.WriteReliable(entry.raw)
+		}
+	}
+
+	topics[m.Topic] = now
+
+	return true
+}
+
+// OnPeerRemoved forgets the peer announcement and delivery state
+func (c *ProfileCache) OnPeerRemoved(p *Peer) {
+	delete(c.recipients, p.Alias)
+
+	if entry, ok := c.profiles[p.Identity]; ok && entry.from == p {
+		delete(c.profiles, p.Identity)
+	}
+}
+
+// OnPrune drops the announcements older than the max age, even of the peers not removed cleanly, and
+// the idle topics of each recipient
+func (c *ProfileCache) OnPrune(now time.Time) {
+	for identity, entry := range c.profiles {
+		if now.Sub(entry.time) > c.maxAge {
+			delete(c.profiles, identity)
+		}
+	}
+
+	for alias, topics := range c.recipients {
+		for topic, lastReceived := range topics {
+			if now.Sub(lastReceived) > topicIdleTimeout {
+				delete(topics, topic)
+			}
+		}
+
+		if len(topics) == 0 {
+			delete(c.recipients, alias)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package commserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func TestProfileCache(t *testing.T) {
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(NewProfileCache(&ProfileCacheConfig{Log: zerolog.Nop()}))
+
+	newTestPeer(p, 1)
+	peer2 := newTestPeer(p, 2)
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 10, 10))
+
+	profile := encodeIdentityFW(t, 1, "id1", &protocol.ProfileData{
+		Category:    protocol.Category_PROFILE,
+		Hash:        "abc",
+		SnapshotUrl: "https://example.com/abc.png",
+	})
+	peer2.reliableWriter.Write(profile)
+	require.Len(t, peer2.reliable.written, 1)
+
+	peer3 := newTestPeer(p, 3)
+	raw := encodePosition(t, 1, 10, 10)
+	peer2.unreliableWriter.Write(raw)
+	peer3.unreliableWriter.Write(raw)
+
+	require.Len(t, peer3.reliable.written, 1, "the new peer gets the cached announcement")
+	assert.Equal(t, profile, peer3.reliable.written[0])
+	assert.Len(t, peer3.unreliable.written, 1)
+	assert.Len(t, peer2.reliable.written, 1, "the announcement is sent only to new peers")
+
+	peer4 := newTestPeer(p, 4)
+	peer4.reliableWriter.Write(profile)
+	assert.Len(t, peer4.reliable.written, 1, "the announcement being delivered is not repeated")
+
+	p.Prune(broker.Stats{Peers: map[uint64]broker.PeerStats{2: {}, 3: {}, 4: {}}})
+
+	peer5 := newTestPeer(p, 5)
+	peer5.unreliableWriter.Write(encodePosition(t, 2, 10, 10))
+	assert.Len(t, peer5.reliable.written, 0, "the removed peers announcements are dropped")
+}
+
+func TestProfileCacheExpiresOnPrune(t *testing.T) {
+	c := NewProfileCache(&ProfileCacheConfig{MaxAge: time.Minute, Log: zerolog.Nop()})
+
+	now := time.Now()
+	from := &Peer{Alias: 1, Identity: "id1", Topic: "0:0"}
+	c.profiles["id1"] = &profileEntry{from: from, time: now.Add(-2 * time.Minute)}
+	c.profiles["id2"] = &profileEntry{from: &Peer{Alias: 2, Identity: "id2"}, time: now}
+	c.recipients[3] = map[string]time.Time{"0:0": now.Add(-topicIdleTimeout - time.Second)}
+	c.recipients[4] = map[string]time.Time{"0:0": now}
+
+	c.OnPrune(now)
+
+	assert.NotContains(t, c.profiles, "id1", "the expired announcement is dropped without a peer removal")
+	assert.Contains(t, c.profiles, "id2")
+	assert.NotContains(t, c.recipients, uint64(3), "the idle recipients are dropped")
+	assert.Contains(t, c.recipients, uint64(4))
+}
@@ -1,75 +1,254 @@
 package commserver
 
 import (
-	"database/sql"
-	"encoding/json"
 	"fmt"
 	"time"
 
-	pq "github.com/lib/pq"
-
 	"github.com/decentraland/webrtc-broker/pkg/broker"
 	"github.com/decentraland/world/internal/commons/logging"
 	"github.com/decentraland/world/internal/commons/metrics"
 	"github.com/decentraland/world/internal/commons/version"
+	"github.com/decentraland/world/internal/commserver/statsstore"
+)
+
+const (
+	defaultShortReportPeriod = 10 * time.Second
+	defaultLongReportPeriod  = 10 * time.Minute
 )
 
+// ReporterConfig is the reporter configuration. The metrics are reported every ShortReportPeriod, and
+// the peer stats are stored every LongReportPeriod.
 type ReporterConfig struct {
-	LongReportPeriod time.Duration
-	DB               *sql.DB
-	DDClient         *metrics.Client
-	Cluster          string
-	Log              logging.Logger
-	DebugModeEnabled bool
+	ShortReportPeriod time.Duration
+	LongReportPeriod  time.Duration
+	StatsWriter       *statsstore.Writer
+	DDClient          *metrics.Client
+	Cluster           string
+	Log               logging.Logger
+	DebugModeEnabled  bool
+
+	// Interest is reported along the bytes sent, if set
+	Interest *InterestManager
+
+	// Heatmap is reported as the active users, parcels and cells, and the users of the busiest
+	// HeatmapTopCells cells, if set
+	Heatmap         *Heatmap
+	HeatmapTopCells int
+
+	// CellStats is reported as the local traffic of the CellStatsTopK busiest cells and categories, if
+	// set
+	CellStats     *CellStats
+	CellStatsTopK int
 }
 
+// StatsSource is where the reporter takes the stats from, i.e. the broker
+type StatsSource interface {
+	GetBrokerStats() broker.Stats
+}
+
+// trafficCounters are the traffic counters of a peer, or their sum
+type trafficCounters struct {
+	messagesSent      uint64
+	bytesSent         uint64
+	iceBytesSent      uint64
+	sctpBytesSent     uint64
+	messagesReceived  uint64
+	bytesReceived     uint64
+	iceBytesReceived  uint64
+	sctpBytesReceived uint64
+}
+
+func newTrafficCounters(s *broker.PeerStats) trafficCounters {
+	return trafficCounters{
+		messagesSent:      uint64(s.ReliableMessagesSent) + uint64(s.UnreliableMessagesSent),
+		bytesSent:         s.ReliableBytesSent + s.UnreliableBytesSent,
+		iceBytesSent:      s.ICETransportBytesSent,
+		sctpBytesSent:     s.SCTPTransportBytesSent,
+		messagesReceived:  uint64(s.ReliableMessagesReceived) + uint64(s.UnreliableMessagesReceived),
+		bytesReceived:     s.ReliableBytesReceived + s.UnreliableBytesReceived,
+		iceBytesReceived:  s.ICETransportBytesReceived,
+		sctpBytesReceived: s.SCTPTransportBytesReceived,
+	}
+}
+
+// counterDelta is the increment of a counter, a counter lower than before was reset, e.g. the
+// alias was reused after a broker restart, so it counts from 0
+func counterDelta(current uint64, previous uint64) uint64 {
+	if current < previous {
+		return current
+	}
+
+	return current - previous
+}
+
+func (c *trafficCounters) addDelta(current *trafficCounters, previous *trafficCounters) {
+	c.messagesSent += counterDelta(current.messagesSent, previous.messagesSent)
+	c.bytesSent += counterDelta(current.bytesSent, previous.bytesSent)
+	c.iceBytesSent += counterDelta(current.iceBytesSent, previous.iceBytesSent)
+	c.sctpBytesSent += counterDelta(current.sctpBytesSent, previous.sctpBytesSent)
+	c.messagesReceived += counterDelta(current.messagesReceived, previous.messagesReceived)
+	c.bytesReceived += counterDelta(current.bytesReceived, previous.bytesReceived)
+	c.iceBytesReceived += counterDelta(current.iceBytesReceived, previous.iceBytesReceived)
+	c.sctpBytesReceived += counterDelta(current.sctpBytesReceived, previous.sctpBytesReceived)
+}
+
+// Reporter reports the broker stats. The broker counters are cumulative since each peer connected,
+// so the rates are the increments since the previous report by the time elapsed between both. The
+// increments are also sent as counts, the totals are kept by DD, no matter how many peers
+// disconnect or how many times the server restarts.
 type Reporter struct {
-	longReportPeriod time.Duration
-	lastLongReport   time.Time
-	db               *sql.DB
+	shortReportPeriod time.Duration
+	longReportPeriod  time.Duration
+	lastLongReport    time.Time
+	lastReport        time.Time
+	lastPeers         map[uint64]trafficCounters
+
+	statsWriter      *statsstore.Writer
+	cluster          string
 	ddClient         *metrics.Client
 	tags             []string
 	log              logging.Logger
 	debugModeEnabled bool
+	interest         *InterestManager
+	heatmap          *Heatmap
+	heatmapTopCells  int
+	cellStats        *CellStats
+	cellStatsTopK    int
 }
 
-func NewReporter(config *ReporterConfig) *Reporter {
+// MetricTags returns the tags of every metric sent by the server
+func MetricTags(cluster string) []string {
 	versionTag := fmt.Sprintf("version:%s", version.Version())
-	clusterTag := fmt.Sprintf("cluster:%s", config.Cluster)
-	tags := []string{"env:local", versionTag, clusterTag}
+	clusterTag := fmt.Sprintf("cluster:%s", cluster)
+	return []string{"env:local", versionTag, clusterTag}
+}
+
+func NewReporter(config *ReporterConfig) *Reporter {
+	shortReportPeriod := config.ShortReportPeriod
+	if shortReportPeriod == 0 {
+		shortReportPeriod = defaultShortReportPeriod
+	}
+
+	longReportPeriod := config.LongReportPeriod
+	if longReportPeriod == 0 {
+		longReportPeriod = defaultLongReportPeriod
+	}
+
+	now := time.Now()
 
 	return &Reporter{
-		longReportPeriod: config.LongReportPeriod,
-		lastLongReport:   time.Now(),
-		db:               config.DB,
-		ddClient:         config.DDClient,
-		tags:             tags,
-		log:              config.Log,
-		debugModeEnabled: config.DebugModeEnabled,
+		shortReportPeriod: shortReportPeriod,
+		longReportPeriod:  longReportPeriod,
+		lastLongReport:    now,
+		lastReport:        now,
+		lastPeers:         make(map[uint64]trafficCounters),
+		statsWriter:       config.StatsWriter,
+		cluster:           config.Cluster,
+		ddClient:          config.DDClient,
+		tags:              MetricTags(config.Cluster),
+		log:               config.Log,
+		debugModeEnabled:  config.DebugModeEnabled,
+		interest:          config.Interest,
+		heatmap:           config.Heatmap,
+		heatmapTopCells:   config.HeatmapTopCells,
+		cellStats:         config.CellStats,
+		cellStatsTopK:     config.CellStatsTopK,
 	}
 }
 
+// Run reports the stats of source every short period, and calls the listeners with them. It never
+// returns.
+func (r *Reporter) Run(source StatsSource, listeners ...func(broker.Stats)) {
+	ticker := time.NewTicker(r.shortReportPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		stats := source.GetBrokerStats()
+		r.Report(stats)
+
+		for _, listener := range listeners {
+			listener(stats)
+		}
+	}
+}
+
+// interval returns the traffic since the previous stats
+func (r *Reporter) interval(stats broker.Stats) trafficCounters {
+	interval := trafficCounters{}
+	peers := make(map[uint64]trafficCounters, len(stats.Peers))
+
+	for alias, pStats := range stats.Peers {
+		pStats := pStats
+		current := newTrafficCounters(&pStats)
+		previous := r.lastPeers[alias]
+
+		interval.addDelta(&current, &previous)
+		peers[alias] = current
+	}
+
+	r.lastPeers = peers
+
+	return interval
+}
+
+// Report reports the stats, the rates are computed from the time elapsed since the previous call
 func (r *Reporter) Report(stats broker.Stats) {
-	if r.db != nil && time.Since(r.lastLongReport) > r.longReportPeriod {
-		r.lastLongReport = time.Now()
+	now := stats.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
 
-		go r.reportDB(r.db, stats)
+	elapsed := now.Sub(r.lastReport).Seconds()
+	r.lastReport = now
+
+	if r.statsWriter != nil && now.Sub(r.lastLongReport) > r.longReportPeriod {
+		r.lastLongReport = now
+
+		r.reportStore(r.lastLongReport, stats)
 	}
 
-	seconds := uint64(10)
-	summaryGenerator := broker.NewStatsSummaryGenerator()
+	rate := func(count uint64) uint64 {
+		if elapsed <= 0 {
+			return 0
+		}
+
+		return uint64(float64(count) / elapsed)
+	}
 
+	// NOTE: the summary is only used for the peer counts, the generator traffic is cumulative
+	summaryGenerator := broker.NewStatsSummaryGenerator()
 	summary := summaryGenerator.Generate(stats)
+	interval := r.interval(stats)
+
+	messagesSent := rate(interval.messagesSent)
+	bytesSent := rate(interval.bytesSent)
+	iceBytesSent := rate(interval.iceBytesSent)
+	sctpBytesSent := rate(interval.sctpBytesSent)
 
-	messagesSent := summary.MessagesSentByDC / seconds
-	bytesSent := summary.BytesSentByDC / seconds
-	iceBytesSent := summary.BytesSentByICE / seconds
-	sctpBytesSent := summary.BytesSentBySCTP / seconds
+	messagesReceived := rate(interval.messagesReceived)
+	bytesReceived := rate(interval.bytesReceived)
+	iceBytesReceived := rate(interval.iceBytesReceived)
+	sctpBytesReceived := rate(interval.sctpBytesReceived)
 
-	messagesReceived := summary.MessagesReceivedByDC / seconds
-	bytesReceived := summary.BytesReceivedByDC / seconds
-	iceBytesReceived := summary.BytesReceivedByICE / seconds
-	sctpBytesReceived := summary.BytesReceivedBySCTP / seconds
+	writerStats := statsstore.WriterStats{}
+	if r.statsWriter != nil {
+		writerStats = r.statsWriter.Stats()
+	}
+
+	cellStats := CellStatsPeriod{}
+	if r.cellStats != nil {
+		cellStats = r.cellStats.Stats()
+	}
+
+	interestStats := InterestStats{}
+	if r.interest != nil {
+		interestStats = r.interest.Stats()
+	}
+
+	positionsDelivered := rate(interestStats.Delivered)
+	positionsDecimated := rate(interestStats.Decimated)
+	positionsCulled := rate(interestStats.Culled)
+	interestBytesSkipped := rate(interestStats.BytesSkipped)
 
 	if r.ddClient != nil {
 		// r.ddClient.GaugeInt("topicCh.size", stats.TopicChSize, r.tags)
@@ -91,6 +270,34 @@ func (r *Reporter) Report(stats broker.Stats) {
 		r.ddClient.GaugeUint64("bytesReceivedICE", iceBytesReceived, r.tags)
 		r.ddClient.GaugeUint64("bytesReceivedSCTP", sctpBytesReceived, r.tags)
 
+		r.ddClient.Count("messagesSent.total", int64(interval.messagesSent), r.tags)
+		r.ddClient.Count("bytesSent.total", int64(interval.bytesSent), r.tags)
+		r.ddClient.Count("messagesReceived.total", int64(interval.messagesReceived), r.tags)
+		r.ddClient.Count("bytesReceived.total", int64(interval.bytesReceived), r.tags)
+
+		if r.interest != nil {
+			r.ddClient.GaugeUint64("interest.positionsDelivered", positionsDelivered, r.tags)
+			r.ddClient.GaugeUint64("interest.positionsDecimated", positionsDecimated, r.tags)
+			r.ddClient.GaugeUint64("interest.positionsCulled", positionsCulled, r.tags)
+			r.ddClient.GaugeUint64("interest.bytesSkipped", interestBytesSkipped, r.tags)
+		}
+
+		if r.statsWriter != nil {
+			r.ddClient.GaugeInt("stats.queueDepth", writerStats.QueueDepth, r.tags)
+			r.ddClient.GaugeUint64("stats.writtenBatches", writerStats.WrittenBatches, r.tags)
+			r.ddClient.GaugeUint64("stats.droppedBatches", writerStats.DroppedBatches, r.tags)
+			r.ddClient.GaugeUint64("stats.spilledBatches", writerStats.SpilledBatches, r.tags)
+			r.ddClient.GaugeUint64("stats.writeRetries", writerStats.Retries, r.tags)
+		}
+
+		if r.heatmap != nil {
+			r.reportHeatmap()
+		}
+
+		if r.cellStats != nil {
+			r.reportCellStats(cellStats, rate)
+		}
+
 		for connState, count := range summary.StateCount {
 			stateTag := fmt.Sprintf("state:%s", connState.String())
 			stateTags := append([]string{stateTag}, r.tags...)
@@ -120,58 +327,72 @@ func (r *Reporter) Report(stats broker.Stats) {
 			Uint64("bytes received per second [DC]", bytesReceived).
 			Uint64("bytes received per second [ICE]", iceBytesReceived).
 			Uint64("bytes received per second [SCTP]", sctpBytesReceived).
+			Uint64("positions delivered per second [interest]", positionsDelivered).
+			Uint64("positions decimated per second [interest]", positionsDecimated).
+			Uint64("positions culled per second [interest]", positionsCulled).
+			Uint64("bytes skipped per second [interest]", interestBytesSkipped).
+			Int("stats queue depth", writerStats.QueueDepth).
+			Uint64("stats dropped batches", writerStats.DroppedBatches).
+			Uint64("stats spilled batches", writerStats.SpilledBatches).
 			Int("peer_count", len(stats.Peers)).
 			Int("topic_count", stats.TopicCount).
 			Msg("")
 	}
 }
 
-func (r *Reporter) reportDB(db *sql.DB, stats broker.Stats) {
-	if len(stats.Peers) == 0 {
-		return
-	}
+func (r *Reporter) reportHeatmap() {
+	snapshot := r.heatmap.Snapshot(time.Now())
 
-	txn, err := db.Begin()
-	if err != nil {
-		r.log.Error().Err(err).Msg("cannot start tx")
-		return
-	}
+	r.ddClient.GaugeInt("heatmap.activeUsers", snapshot.Users, r.tags)
+	r.ddClient.GaugeInt("heatmap.activeParcels", len(snapshot.Parcels), r.tags)
+	r.ddClient.GaugeInt("heatmap.activeCells", len(snapshot.Cells), r.tags)
 
-	stmt, err := txn.Prepare(pq.CopyIn("stats", "peer_alias", "user_id", "version", "stats"))
-	if err != nil {
-		r.log.Error().Err(err).Msg("cannot prepare statement")
-		return
+	if len(snapshot.Parcels) > 0 {
+		r.ddClient.GaugeInt("heatmap.maxParcelUsers", snapshot.Parcels[0].Users, r.tags)
 	}
 
-	for _, pStats := range stats.Peers {
-		encodedStats, err := json.Marshal(pStats)
-		if err != nil {
-			r.log.Error().Err(err).Msg("cannot encode stats as json")
-			continue
+	for i, cell := range snapshot.Cells {
+		if i >= r.heatmapTopCells {
+			break
 		}
 
-		_, err = stmt.Exec(pStats.Alias, string(pStats.Identity), version.Version(), encodedStats)
-		if err != nil {
-			r.log.Error().Err(err).Msg("cannot exec statement")
-			continue
-		}
+		cellTags := append([]string{fmt.Sprintf("cell:%s", cell.Topic)}, r.tags...)
+		r.ddClient.GaugeInt("heatmap.cellUsers", cell.Users, cellTags)
 	}
+}
 
-	_, err = stmt.Exec()
-	if err != nil {
-		r.log.Error().Err(err).Msg("cannot finalize statement")
-		return
+func (r *Reporter) reportCellStats(period CellStatsPeriod, rate func(uint64) uint64) {
+	r.ddClient.GaugeInt("cell.local.tracked", len(period.Cells), r.tags)
+
+	for i, traffic := range period.Cells {
+		if i >= r.cellStatsTopK {
+			break
+		}
+
+		tags := append([]string{
+			fmt.Sprintf("cell:%s", traffic.Cell),
+			fmt.Sprintf("category:%s", traffic.Category),
+		}, r.tags...)
+
+		r.ddClient.GaugeUint64("cell.local.messages", rate(traffic.Messages), tags)
+		r.ddClient.GaugeUint64("cell.local.bytes", rate(traffic.Bytes), tags)
+		r.ddClient.GaugeUint64("cell.local.deliveries", rate(traffic.Deliveries), tags)
+		r.ddClient.GaugeUint64("cell.local.deliveredBytes", rate(traffic.DeliveredBytes), tags)
+		r.ddClient.GaugeInt("cell.local.recipients", traffic.Recipients, tags)
+		r.ddClient.GaugeFloat64("cell.local.fanOut", traffic.LocalFanOut, tags)
 	}
+}
 
-	err = stmt.Close()
-	if err != nil {
-		r.log.Error().Err(err).Msg("cannot close statement")
+func (r *Reporter) reportStore(t time.Time, stats broker.Stats) {
+	if len(stats.Peers) == 0 {
 		return
 	}
 
-	err = txn.Commit()
-	if err != nil {
-		r.log.Error().Err(err).Msg("cannot commit tx")
-		return
+	samples := make([]statsstore.PeerStats, 0, len(stats.Peers))
+	for _, pStats := range stats.Peers {
+		pStats := pStats
+		samples = append(samples, statsstore.NewPeerStats(t, version.Version(), r.cluster, &pStats))
 	}
+
+	r.statsWriter.Enqueue(samples)
 }
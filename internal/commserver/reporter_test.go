@@ -0,0 +1,133 @@
+package commserver
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/decentraland/world/internal/commons/metrics"
+)
+
+func TestReporterInterval(t *testing.T) {
+	r := NewReporter(&ReporterConfig{Log: zerolog.Nop()})
+
+	peerStats := func(alias uint64, bytesSent uint64) broker.PeerStats {
+		return broker.PeerStats{Alias: alias, ReliableBytesSent: bytesSent, ReliableMessagesSent: uint32(bytesSent / 10)}
+	}
+
+	interval := r.interval(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: peerStats(1, 100),
+		2: peerStats(2, 50),
+	}})
+	assert.Equal(t, uint64(150), interval.bytesSent)
+	assert.Equal(t, uint64(15), interval.messagesSent)
+
+	interval = r.interval(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: peerStats(1, 300),
+	}})
+	assert.Equal(t, uint64(200), interval.bytesSent, "the disconnected peers don't count")
+
+	interval = r.interval(broker.Stats{Peers: map[uint64]broker.PeerStats{
+		1: peerStats(1, 30),
+	}})
+	assert.Equal(t, uint64(30), interval.bytesSent, "a reset counter counts from 0")
+}
+
+func TestReporterSchedule(t *testing.T) {
+	r := NewReporter(&ReporterConfig{Log: zerolog.Nop()})
+	assert.Equal(t, defaultShortReportPeriod, r.shortReportPeriod)
+	assert.Equal(t, defaultLongReportPeriod, r.longReportPeriod)
+
+	start := time.Now()
+	r.Report(broker.Stats{Time: start.Add(3 * time.Second)})
+	assert.Equal(t, start.Add(3*time.Second), r.lastReport, "the rates use the stats time")
+}
+
+// statsdListener returns a metrics client sending to a local udp socket, a function that reads the
+// metrics received, by name, and one that closes both
+func statsdListener(t *testing.T) (*metrics.Client, func() map[string]string, func()) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+
+	for name, value := range map[string]string{"DD_AGENT_HOST": "127.0.0.1", "DD_DOGSTATSD_PORT": port} {
+		previous, ok := os.LookupEnv(name)
+		require.NoError(t, os.Setenv(name, value))
+
+		name := name
+		defer func() {
+			if ok {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		}()
+	}
+
+	client, err := metrics.NewClient("test", zerolog.Nop())
+	require.NoError(t, err)
+
+	read := func() map[string]string {
+		client.Flush()
+
+		received := map[string]string{}
+		buf := make([]byte, 65536)
+
+		for {
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+			n, err := conn.Read(buf)
+			if err != nil {
+				return received
+			}
+
+			// test.name:value|type|#tags
+			for _, line := range strings.Split(string(buf[:n]), "\n") {
+				fields := strings.SplitN(strings.SplitN(line, "|#", 2)[0], ":", 2)
+				if len(fields) == 2 {
+					received[fields[0]] = fields[1]
+				}
+			}
+		}
+	}
+
+	closer := func() {
+		client.Close()
+		conn.Close()
+	}
+
+	return client, read, closer
+}
+
+func TestReporterMetrics(t *testing.T) {
+	client, read, closer := statsdListener(t)
+	defer closer()
+
+	r := NewReporter(&ReporterConfig{DDClient: client, Log: zerolog.Nop()})
+
+	peerStats := func(bytesSent uint64) map[uint64]broker.PeerStats {
+		return map[uint64]broker.PeerStats{1: {Alias: 1, ReliableBytesSent: bytesSent}}
+	}
+
+	start := r.lastReport
+	r.Report(broker.Stats{Time: start.Add(2 * time.Second), Peers: peerStats(1000)})
+
+	received := read()
+	assert.Equal(t, "500|g", received["test.bytesSent"], "1000 bytes in 2 seconds")
+	assert.Equal(t, "1000|c", received["test.bytesSent.total"])
+
+	r.Report(broker.Stats{Time: start.Add(6 * time.Second), Peers: peerStats(3000)})
+
+	received = read()
+	assert.Equal(t, "500|g", received["test.bytesSent"], "2000 bytes in 4 seconds")
+	assert.Equal(t, "2000|c", received["test.bytesSent.total"], "the totals are sent as the increments")
+}
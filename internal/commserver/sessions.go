@@ -0,0 +1,214 @@
+package commserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	"github.com/decentraland/world/internal/commons/logging"
+	"github.com/decentraland/world/internal/commons/version"
+	"github.com/decentraland/world/internal/commserver/statsstore"
+	pion "github.com/pion/webrtc/v2"
+)
+
+// SessionTrackerConfig is the session tracker configuration
+type SessionTrackerConfig struct {
+	Sink    statsstore.SessionSink
+	Cluster string
+	Log     logging.Logger
+}
+
+type session struct {
+	start      time.Time
+	identity   string
+	topicCount uint32
+
+	// seen is set once the peer is in the stats, state is its last ICE state then
+	seen      bool
+	connected bool
+	state     pion.ICEConnectionState
+
+	writeFailed bool
+}
+
+// SessionTracker emits the session events of the local peers. The connect events are emitted when
+// the broker creates the peer writers, and a failed data channel write is recorded as the disconnect
+// reason. The broker doesn't expose the rest of the peer lifecycle, so the other events are inferred
+// from the changes between consecutive broker stats, with the resolution of the stats period. The
+// events are written from the broker and reporter goroutines, so the sink shouldn't block, e.g. a
+// statsstore.SessionWriter.
+type SessionTracker struct {
+	sink    statsstore.SessionSink
+	cluster string
+	version string
+	log     logging.Logger
+
+	mutex    sync.Mutex
+	sessions map[uint64]*session
+}
+
+// NewSessionTracker creates a session tracker, Track has to be called with every broker stats
+func NewSessionTracker(config *SessionTrackerConfig) *SessionTracker {
+	return &SessionTracker{
+		sink:     config.Sink,
+		cluster:  config.Cluster,
+		version:  version.Version(),
+		log:      config.Log,
+		sessions: make(map[uint64]*session),
+	}
+}
+
+// WrapWriterControllerFactory returns a broker.WriterControllerFactory that emits the connect event
+// of each peer, and records its failed writes, before calling factory
+func (t *SessionTracker) WrapWriterControllerFactory(factory broker.WriterControllerFactory) broker.WriterControllerFactory {
+	return func(alias uint64, writer broker.PeerWriter) broker.WriterController {
+		t.connect(time.Now(), alias)
+		return factory(alias, &sessionPeerWriter{PeerWriter: writer, tracker: t, alias: alias})
+	}
+}
+
+func (t *SessionTracker) connect(now time.Time, alias uint64) {
+	t.mutex.Lock()
+
+	if _, ok := t.sessions[alias]; ok {
+		t.mutex.Unlock()
+		return
+	}
+
+	s := &session{start: now}
+	t.sessions[alias] = s
+	e := t.event(now, statsstore.SessionConnected, alias, s)
+
+	t.mutex.Unlock()
+
+	t.write([]statsstore.SessionEvent{e})
+}
+
+func (t *SessionTracker) writeFailed(alias uint64) {
+	t.mutex.Lock()
+	if s, ok := t.sessions[alias]; ok {
+		s.writeFailed = true
+	}
+	t.mutex.Unlock()
+}
+
+// Track compares the stats with the previous ones, and writes the session events to the sink
+func (t *SessionTracker) Track(stats broker.Stats) {
+	now := stats.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mutex.Lock()
+	events := t.diff(now, stats)
+	t.mutex.Unlock()
+
+	t.write(events)
+}
+
+func (t *SessionTracker) write(events []statsstore.SessionEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	if err := t.sink.Write(events); err != nil {
+		t.log.Error().Err(err).Int("events", len(events)).Msg("cannot write the session events")
+	}
+}
+
+func (t *SessionTracker) diff(now time.Time, stats broker.Stats) []statsstore.SessionEvent {
+	events := []statsstore.SessionEvent{}
+
+	for alias, peerStats := range stats.Peers {
+		s := t.sessions[alias]
+		if s == nil {
+			s = &session{start: now}
+			t.sessions[alias] = s
+			events = append(events, t.event(now, statsstore.SessionConnected, alias, s))
+		}
+
+		s.seen = true
+		s.state = peerStats.State
+
+		switch peerStats.State {
+		case pion.ICEConnectionStateConnected, pion.ICEConnectionStateCompleted:
+			s.connected = true
+		}
+
+		if identity := string(peerStats.Identity); identity != "" && s.identity == "" {
+			s.identity = identity
+			events = append(events, t.event(now, statsstore.SessionAuthenticated, alias, s))
+		}
+
+		if peerStats.TopicCount != s.topicCount {
+			s.topicCount = peerStats.TopicCount
+			events = append(events, t.event(now, statsstore.SessionTopicsChanged, alias, s))
+		}
+	}
+
+	for alias, s := range t.sessions {
+		// NOTE: a peer created after the stats were taken is not in them yet
+		if _, ok := stats.Peers[alias]; ok || !s.start.Before(now) {
+			continue
+		}
+
+		delete(t.sessions, alias)
+
+		e := t.event(now, statsstore.SessionDisconnected, alias, s)
+		e.Reason = s.disconnectReason()
+		e.Duration = now.Sub(s.start)
+		events = append(events, e)
+	}
+
+	return events
+}
+
+func (t *SessionTracker) event(now time.Time, eventType statsstore.SessionEventType, alias uint64,
+	s *session) statsstore.SessionEvent {
+	return statsstore.SessionEvent{
+		Time:       now,
+		Type:       eventType,
+		Alias:      alias,
+		Identity:   s.identity,
+		Version:    t.version,
+		Cluster:    t.cluster,
+		TopicCount: s.topicCount,
+	}
+}
+
+// disconnectReason returns why the peer is gone, as far as it was observed. A peer that was
+// connected in its last stats may have failed or closed after them, so its reason is unknown.
+func (s *session) disconnectReason() string {
+	switch {
+	case s.writeFailed:
+		return "write_failed"
+	case !s.seen:
+		return "unknown"
+	case s.state == pion.ICEConnectionStateFailed:
+		return "ice_failed"
+	case s.state == pion.ICEConnectionStateDisconnected:
+		return "ice_disconnected"
+	case s.state == pion.ICEConnectionStateClosed:
+		return "closed"
+	case !s.connected:
+		return "never_connected"
+	default:
+		return "unknown"
+	}
+}
+
+// sessionPeerWriter records the failed writes of a peer, the broker closes the peer after them
+type sessionPeerWriter struct {
+	broker.PeerWriter
+	tracker *SessionTracker
+	alias   uint64
+}
+
+func (w *sessionPeerWriter) Write(p []byte) error {
+	err := w.PeerWriter.Write(p)
+	if err != nil {
+		w.tracker.writeFailed(w.alias)
+	}
+
+	return err
+}
@@ -0,0 +1,141 @@
+package commserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	"github.com/decentraland/world/internal/commserver/statsstore"
+	pion "github.com/pion/webrtc/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTracker(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := NewSessionTracker(&SessionTrackerConfig{
+		Sink:    statsstore.NewNDJSONSessionSink(&buf),
+		Cluster: "local",
+		Log:     zerolog.Nop(),
+	})
+
+	readEvents := func() []statsstore.SessionEvent {
+		events := []statsstore.SessionEvent{}
+		decoder := json.NewDecoder(&buf)
+		for decoder.More() {
+			e := statsstore.SessionEvent{}
+			require.NoError(t, decoder.Decode(&e))
+			events = append(events, e)
+		}
+
+		return events
+	}
+
+	base := time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	tracker.Track(broker.Stats{Time: base, Peers: map[uint64]broker.PeerStats{
+		1: {Alias: 1, State: pion.ICEConnectionStateChecking},
+	}})
+
+	events := readEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, statsstore.SessionConnected, events[0].Type)
+	assert.Equal(t, uint64(1), events[0].Alias)
+	assert.Equal(t, "local", events[0].Cluster)
+
+	tracker.Track(broker.Stats{Time: base.Add(10 * time.Second), Peers: map[uint64]broker.PeerStats{
+		1: {Alias: 1, Identity: []byte("a"), State: pion.ICEConnectionStateConnected, TopicCount: 2},
+		2: {Alias: 2, State: pion.ICEConnectionStateChecking},
+	}})
+
+	events = readEvents()
+	require.Len(t, events, 3)
+
+	types := map[statsstore.SessionEventType]statsstore.SessionEvent{}
+	for _, e := range events {
+		types[e.Type] = e
+	}
+
+	assert.Equal(t, "a", types[statsstore.SessionAuthenticated].Identity)
+	assert.Equal(t, uint32(2), types[statsstore.SessionTopicsChanged].TopicCount)
+	assert.Equal(t, uint64(2), types[statsstore.SessionConnected].Alias)
+
+	tracker.Track(broker.Stats{Time: base.Add(time.Minute), Peers: map[uint64]broker.PeerStats{}})
+
+	events = readEvents()
+	require.Len(t, events, 2)
+
+	for _, e := range events {
+		assert.Equal(t, statsstore.SessionDisconnected, e.Type)
+
+		if e.Alias == 1 {
+			assert.Equal(t, "a", e.Identity)
+			assert.Equal(t, "unknown", e.Reason, "it was connected in its last stats")
+			assert.Equal(t, time.Minute, e.Duration)
+		} else {
+			assert.Equal(t, "never_connected", e.Reason)
+			assert.Equal(t, 50*time.Second, e.Duration)
+		}
+	}
+
+	tracker.Track(broker.Stats{Time: base.Add(2 * time.Minute)})
+	assert.Len(t, readEvents(), 0)
+}
+
+type failingPeerWriter struct{}
+
+func (w *failingPeerWriter) BufferedAmount() uint64 { return 0 }
+
+func (w *failingPeerWriter) Write(p []byte) error { return errors.New("closed") }
+
+func TestSessionTrackerFactory(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := NewSessionTracker(&SessionTrackerConfig{
+		Sink: statsstore.NewNDJSONSessionSink(&buf),
+		Log:  zerolog.Nop(),
+	})
+
+	var writers []broker.PeerWriter
+	factory := tracker.WrapWriterControllerFactory(func(alias uint64, writer broker.PeerWriter) broker.WriterController {
+		writers = append(writers, writer)
+		return nil
+	})
+
+	factory(1, &mockPeerWriter{})
+	factory(1, &mockPeerWriter{})
+	factory(2, &failingPeerWriter{})
+	require.Len(t, writers, 3)
+
+	events := []statsstore.SessionEvent{}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		e := statsstore.SessionEvent{}
+		require.NoError(t, decoder.Decode(&e))
+		events = append(events, e)
+	}
+
+	require.Len(t, events, 2, "a connect event per peer, before any stats")
+	assert.Equal(t, statsstore.SessionConnected, events[0].Type)
+	assert.Equal(t, uint64(1), events[0].Alias)
+	assert.Equal(t, uint64(2), events[1].Alias)
+
+	assert.Error(t, writers[2].Write([]byte{1}))
+
+	// both peers are gone by the next stats
+	tracker.Track(broker.Stats{Time: time.Now().Add(time.Second)})
+
+	reasons := map[uint64]string{}
+	decoder = json.NewDecoder(&buf)
+	for decoder.More() {
+		e := statsstore.SessionEvent{}
+		require.NoError(t, decoder.Decode(&e))
+		assert.Equal(t, statsstore.SessionDisconnected, e.Type)
+		reasons[e.Alias] = e.Reason
+	}
+
+	assert.Equal(t, map[uint64]string{1: "unknown", 2: "write_failed"}, reasons)
+}
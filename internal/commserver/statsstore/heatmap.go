@@ -0,0 +1,68 @@
+package statsstore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const parcelUsersTable = "parcel_users"
+
+// ParcelUsers is the number of active users in a parcel at a time
+type ParcelUsers struct {
+	Time    time.Time `json:"time"`
+	Cluster string    `json:"cluster"`
+	X       int       `json:"x"`
+	Z       int       `json:"z"`
+	Users   int       `json:"users"`
+}
+
+// HeatmapStore keeps the heatmap snapshots, only the sql stores implement it
+type HeatmapStore interface {
+	InsertHeatmap(parcels []ParcelUsers) error
+}
+
+func (s *sqlStore) InsertHeatmap(parcels []ParcelUsers) (err error) {
+	if len(parcels) == 0 {
+		return nil
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot start tx: %v", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	placeholders := make([]string, 5)
+	for i := range placeholders {
+		placeholders[i] = s.dialect.placeholder(i + 1)
+	}
+
+	stmt, err := txn.Prepare(fmt.Sprintf("INSERT INTO %s (created_at, cluster, x, z, users) VALUES (%s)",
+		parcelUsersTable, strings.Join(placeholders, ", ")))
+	if err != nil {
+		return fmt.Errorf("cannot prepare statement: %v", err)
+	}
+
+	for _, p := range parcels {
+		if _, err = stmt.Exec(s.dialect.timeValue(p.Time), p.Cluster, p.X, p.Z, p.Users); err != nil {
+			_ = stmt.Close()
+			return fmt.Errorf("cannot exec statement: %v", err)
+		}
+	}
+
+	if err = stmt.Close(); err != nil {
+		return fmt.Errorf("cannot close statement: %v", err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("cannot commit tx: %v", err)
+	}
+
+	return nil
+}
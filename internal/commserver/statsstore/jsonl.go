@@ -0,0 +1,222 @@
+package statsstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jsonLinesStore is a StatsStore in a file with a json sample per line. Queries and retention read
+// the whole file, it's meant for local runs and small deployments.
+type jsonLinesStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewJSONLinesStore creates a StatsStore in a json lines file, creating the file if it doesn't exist
+func NewJSONLinesStore(path string) (StatsStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return &jsonLinesStore{path: path}, nil
+}
+
+func (s *jsonLinesStore) Insert(samples []PeerStats) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+
+	for i := range samples {
+		if err := encoder.Encode(&samples[i]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *jsonLinesStore) Query(q Query) ([]PeerStats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	samples, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []PeerStats{}
+
+	for i := range samples {
+		if q.matches(&samples[i]) {
+			matched = append(matched, samples[i])
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Time.Before(matched[j].Time) })
+
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+
+	return matched, nil
+}
+
+func (s *jsonLinesStore) DeleteBefore(t time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	samples, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	kept := samples[:0]
+
+	for _, sample := range samples {
+		if !sample.Time.Before(t) {
+			kept = append(kept, sample)
+		}
+	}
+
+	removed := int64(len(samples) - len(kept))
+	if removed == 0 {
+		return 0, nil
+	}
+
+	return removed, s.write(kept)
+}
+
+func (s *jsonLinesStore) Downsample(t time.Time, interval time.Duration) (int64, error) {
+	if interval < time.Second {
+		return 0, fmt.Errorf("invalid downsample interval %s", interval)
+	}
+
+	t = t.Truncate(interval)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	samples, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	// last is the index of the last sample of each peer and interval
+	last := make(map[downsampleKey]int)
+
+	for i := range samples {
+		sample := &samples[i]
+		if !sample.Time.Before(t) || sample.Resolution >= interval {
+			continue
+		}
+
+		key := newDownsampleKey(sample, interval)
+		if j, ok := last[key]; !ok || !sample.Time.Before(samples[j].Time) {
+			last[key] = i
+		}
+	}
+
+	kept := []PeerStats{}
+
+	for i, sample := range samples {
+		if sample.Time.Before(t) && sample.Resolution < interval {
+			if last[newDownsampleKey(&sample, interval)] != i {
+				continue
+			}
+
+			sample.Resolution = interval
+		}
+
+		kept = append(kept, sample)
+	}
+
+	removed := int64(len(samples) - len(kept))
+	if len(last) == 0 {
+		return 0, nil
+	}
+
+	return removed, s.write(kept)
+}
+
+func (s *jsonLinesStore) Close() error {
+	return nil
+}
+
+func (s *jsonLinesStore) read() ([]PeerStats, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	samples := []PeerStats{}
+	decoder := json.NewDecoder(bufio.NewReader(f))
+
+	for decoder.More() {
+		var sample PeerStats
+		if err := decoder.Decode(&sample); err != nil {
+			return nil, fmt.Errorf("invalid stats file %s: %v", s.path, err)
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// write replaces the file content, through a temp file so it's never left half written
+func (s *jsonLinesStore) write(samples []PeerStats) error {
+	f, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+
+	for i := range samples {
+		if err := encoder.Encode(&samples[i]); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), s.path)
+}
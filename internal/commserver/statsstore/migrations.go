@@ -0,0 +1,405 @@
+package statsstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	migrationsTable = "schema_migrations"
+
+	// migrationsLockKey is the postgres advisory lock held while migrating, an arbitrary number
+	// unique to the stats db migrations
+	migrationsLockKey = 8263914011
+)
+
+// ErrUnknownSchema is returned when the db schema is newer than the migrations known by the server
+var ErrUnknownSchema = errors.New("unknown stats db schema")
+
+// ErrOutdatedSchema is returned when the db schema has pending migrations
+var ErrOutdatedSchema = errors.New("outdated stats db schema, run the migrations")
+
+// Migration is a versioned schema change, Up applies it and Down reverts it
+type Migration struct {
+	Version     int
+	Description string
+	Up          string
+	Down        string
+}
+
+// Migrator applies the migrations of a db, keeping the applied versions in the schema_migrations
+// table. Each migration runs in its own transaction. On postgres Up and Down hold an advisory lock,
+// so the servers starting at once migrate one after another instead of running the same migrations.
+type Migrator struct {
+	db          *sql.DB
+	migrations  []Migration
+	placeholder func(n int) string
+
+	// tableExistsQuery returns whether the migrations table exists
+	tableExistsQuery string
+	// lockQuery and unlockQuery take and release the migrations lock, if the db has one
+	lockQuery   string
+	unlockQuery string
+}
+
+// NewPostgresMigrator creates the Migrator of a postgres stats db
+func NewPostgresMigrator(db *sql.DB) *Migrator {
+	return &Migrator{
+		db:               db,
+		migrations:       postgresMigrations,
+		placeholder:      postgresDialect.placeholder,
+		tableExistsQuery: fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", migrationsTable),
+		lockQuery:        fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationsLockKey),
+		unlockQuery:      fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationsLockKey),
+	}
+}
+
+// NewSQLiteMigrator creates the Migrator of a sqlite stats db, sqlite locks the whole db on writes
+// so it needs no migrations lock
+func NewSQLiteMigrator(db *sql.DB) *Migrator {
+	return &Migrator{
+		db:          db,
+		migrations:  sqliteMigrations,
+		placeholder: sqliteDialect.placeholder,
+		tableExistsQuery: fmt.Sprintf("SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = '%s'",
+			migrationsTable),
+	}
+}
+
+// Latest returns the version of the last known migration
+func (m *Migrator) Latest() int {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) createTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    version     integer PRIMARY KEY,
+    description varchar(255) NOT NULL,
+    applied_at  bigint NOT NULL
+)`, migrationsTable))
+
+	return err
+}
+
+// Version returns the current schema version, 0 if no migration was applied. It doesn't change the
+// db, a missing migrations table is version 0.
+func (m *Migrator) Version() (int, error) {
+	var exists bool
+	if err := m.db.QueryRow(m.tableExistsQuery).Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := m.db.QueryRow(fmt.Sprintf("SELECT max(version) FROM %s", migrationsTable)).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+// Check returns ErrUnknownSchema if the schema is newer than the known migrations, and
+// ErrOutdatedSchema if there are pending migrations
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > m.Latest():
+		return fmt.Errorf("%w: version %d, the latest known is %d", ErrUnknownSchema, version, m.Latest())
+	case version < m.Latest():
+		return fmt.Errorf("%w: version %d, the latest is %d", ErrOutdatedSchema, version, m.Latest())
+	default:
+		return nil
+	}
+}
+
+// withLock runs fn holding the migrations lock, on a connection of its own since the postgres
+// advisory locks belong to the session that takes them
+func (m *Migrator) withLock(fn func() error) error {
+	if m.lockQuery == "" {
+		return fn()
+	}
+
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, m.lockQuery); err != nil {
+		return fmt.Errorf("cannot take the migrations lock: %v", err)
+	}
+
+	defer func() {
+		_, _ = conn.ExecContext(ctx, m.unlockQuery)
+	}()
+
+	return fn()
+}
+
+// Up applies the pending migrations, it returns ErrUnknownSchema if the schema is newer than the
+// known migrations
+func (m *Migrator) Up() error {
+	return m.withLock(m.up)
+}
+
+func (m *Migrator) up() error {
+	if err := m.createTable(); err != nil {
+		return err
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return fmt.Errorf("%w: version %d, the latest known is %d", ErrUnknownSchema, version, m.Latest())
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		err := m.apply(migration.Up, fmt.Sprintf("INSERT INTO %s (version, description, applied_at) VALUES (%s, %s, %s)",
+			migrationsTable, m.placeholder(1), m.placeholder(2), m.placeholder(3)),
+			migration.Version, migration.Description, time.Now().Unix())
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", migration.Version, migration.Description, err)
+		}
+	}
+
+	return nil
+}
+
+// Down reverts the migrations after version, down to it
+func (m *Migrator) Down(version int) error {
+	return m.withLock(func() error {
+		return m.down(version)
+	})
+}
+
+func (m *Migrator) down(version int) error {
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if current > m.Latest() {
+		return fmt.Errorf("%w: version %d, the latest known is %d", ErrUnknownSchema, current, m.Latest())
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current || migration.Version <= version {
+			continue
+		}
+
+		err := m.apply(migration.Down, fmt.Sprintf("DELETE FROM %s WHERE version = %s", migrationsTable, m.placeholder(1)),
+			migration.Version)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) revert failed: %v", migration.Version, migration.Description, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) apply(schema string, versionQuery string, args ...interface{}) (err error) {
+	txn, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	if _, err = txn.Exec(schema); err != nil {
+		return err
+	}
+
+	if _, err = txn.Exec(versionQuery, args...); err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
+// openSchema migrates the db to the latest version, or checks it's on it if migrate is false
+func openSchema(m *Migrator, migrate bool) error {
+	if migrate {
+		return m.Up()
+	}
+
+	return m.Check()
+}
+
+// postgresMigrations are the migrations of the postgres stats db. The first ones are idempotent, the
+// dbs created by the former init script, or before the migrations, have those tables already.
+var postgresMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "stats table",
+		Up: `
+CREATE TABLE IF NOT EXISTS stats (
+    peer_alias  bigint,
+    user_id     varchar(255),
+    version     varchar(255),
+    created_at  timestamp DEFAULT now(),
+    stats       json NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_created_at ON stats(created_at);
+`,
+		Down: `DROP TABLE stats;`,
+	},
+	{
+		Version:     2,
+		Description: "peer stats table",
+		Up: `
+CREATE TABLE IF NOT EXISTS peer_stats (
+    id                           bigserial PRIMARY KEY,
+    created_at                   timestamptz NOT NULL,
+    resolution                   integer NOT NULL DEFAULT 0,
+    peer_alias                   bigint NOT NULL,
+    user_id                      varchar(255) NOT NULL,
+    version                      varchar(255) NOT NULL,
+    cluster                      varchar(255) NOT NULL,
+    state                        varchar(32) NOT NULL,
+    topic_count                  integer NOT NULL,
+    local_candidate_type         varchar(32) NOT NULL,
+    remote_candidate_type        varchar(32) NOT NULL,
+    reliable_bytes_sent          bigint NOT NULL,
+    reliable_bytes_received      bigint NOT NULL,
+    reliable_messages_sent       bigint NOT NULL,
+    reliable_messages_received   bigint NOT NULL,
+    reliable_buffered_amount     bigint NOT NULL,
+    unreliable_bytes_sent        bigint NOT NULL,
+    unreliable_bytes_received    bigint NOT NULL,
+    unreliable_messages_sent     bigint NOT NULL,
+    unreliable_messages_received bigint NOT NULL,
+    unreliable_buffered_amount   bigint NOT NULL,
+    ice_bytes_sent               bigint NOT NULL,
+    ice_bytes_received           bigint NOT NULL,
+    sctp_bytes_sent              bigint NOT NULL,
+    sctp_bytes_received          bigint NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_peer_stats_created_at ON peer_stats(created_at);
+CREATE INDEX IF NOT EXISTS idx_peer_stats_user_id ON peer_stats(user_id, created_at);
+`,
+		Down: `DROP TABLE peer_stats;`,
+	},
+	{
+		Version:     3,
+		Description: "session events table",
+		Up: `
+CREATE TABLE session_events (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz NOT NULL,
+    event       varchar(32) NOT NULL,
+    peer_alias  bigint NOT NULL,
+    user_id     varchar(255) NOT NULL,
+    version     varchar(255) NOT NULL,
+    cluster     varchar(255) NOT NULL,
+    topic_count integer NOT NULL,
+    reason      varchar(64) NOT NULL,
+    duration_ms bigint NOT NULL
+);
+
+CREATE INDEX idx_session_events_created_at ON session_events(created_at);
+CREATE INDEX idx_session_events_user_id ON session_events(user_id, created_at);
+`,
+		Down: `DROP TABLE session_events;`,
+	},
+	{
+		Version:     4,
+		Description: "parcel users table",
+		Up: `
+CREATE TABLE parcel_users (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    cluster    varchar(255) NOT NULL,
+    x          integer NOT NULL,
+    z          integer NOT NULL,
+    users      integer NOT NULL
+);
+
+CREATE INDEX idx_parcel_users_created_at ON parcel_users(created_at);
+`,
+		Down: `DROP TABLE parcel_users;`,
+	},
+}
+
+// sqliteMigrations are the migrations of the sqlite stats db, created_at is in unix milliseconds
+var sqliteMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "peer stats table",
+		Up: `
+CREATE TABLE IF NOT EXISTS peer_stats (
+    id                           INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at                   INTEGER NOT NULL,
+    resolution                   INTEGER NOT NULL DEFAULT 0,
+    peer_alias                   INTEGER NOT NULL,
+    user_id                      TEXT NOT NULL,
+    version                      TEXT NOT NULL,
+    cluster                      TEXT NOT NULL,
+    state                        TEXT NOT NULL,
+    topic_count                  INTEGER NOT NULL,
+    local_candidate_type         TEXT NOT NULL,
+    remote_candidate_type        TEXT NOT NULL,
+    reliable_bytes_sent          INTEGER NOT NULL,
+    reliable_bytes_received      INTEGER NOT NULL,
+    reliable_messages_sent       INTEGER NOT NULL,
+    reliable_messages_received   INTEGER NOT NULL,
+    reliable_buffered_amount     INTEGER NOT NULL,
+    unreliable_bytes_sent        INTEGER NOT NULL,
+    unreliable_bytes_received    INTEGER NOT NULL,
+    unreliable_messages_sent     INTEGER NOT NULL,
+    unreliable_messages_received INTEGER NOT NULL,
+    unreliable_buffered_amount   INTEGER NOT NULL,
+    ice_bytes_sent               INTEGER NOT NULL,
+    ice_bytes_received           INTEGER NOT NULL,
+    sctp_bytes_sent              INTEGER NOT NULL,
+    sctp_bytes_received          INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_peer_stats_created_at ON peer_stats(created_at);
+CREATE INDEX IF NOT EXISTS idx_peer_stats_user_id ON peer_stats(user_id, created_at);
+`,
+		Down: `DROP TABLE peer_stats;`,
+	},
+	{
+		Version:     2,
+		Description: "parcel users table",
+		Up: `
+CREATE TABLE parcel_users (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    cluster    TEXT NOT NULL,
+    x          INTEGER NOT NULL,
+    z          INTEGER NOT NULL,
+    users      INTEGER NOT NULL
+);
+
+CREATE INDEX idx_parcel_users_created_at ON parcel_users(created_at);
+`,
+		Down: `DROP TABLE parcel_users;`,
+	},
+}
@@ -0,0 +1,97 @@
+package statsstore
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "stats.db")
+
+	db, err := OpenSQLite(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewSQLiteMigrator(db)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.True(t, errors.Is(m.Check(), ErrOutdatedSchema))
+
+	var tables int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&tables))
+	assert.Equal(t, 0, tables, "check doesn't create the migrations table")
+
+	_, err = NewSQLiteStore(path, false)
+	assert.True(t, errors.Is(err, ErrOutdatedSchema), "the store doesn't start on an outdated schema")
+
+	require.NoError(t, m.Up())
+	require.NoError(t, m.Up(), "up is a noop on the latest version")
+
+	version, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, m.Latest(), version)
+	assert.NoError(t, m.Check())
+
+	store, err := NewSQLiteStore(path, false)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	require.NoError(t, m.Down(0))
+
+	version, err = m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	_, err = db.Exec("SELECT count(*) FROM peer_stats")
+	assert.Error(t, err, "the tables are dropped")
+
+	t.Run("unknown schema", func(t *testing.T) {
+		require.NoError(t, m.Up())
+
+		_, err := db.Exec("INSERT INTO schema_migrations (version, description, applied_at) VALUES (99, 'future', 0)")
+		require.NoError(t, err)
+
+		assert.True(t, errors.Is(m.Check(), ErrUnknownSchema))
+		assert.True(t, errors.Is(m.Up(), ErrUnknownSchema))
+
+		_, err = NewSQLiteStore(path, true)
+		assert.True(t, errors.Is(err, ErrUnknownSchema), "the store refuses to start on an unknown schema")
+	})
+}
+
+// TestPostgresMigratorLock runs against the db in STATS_TEST_DB, see TestPostgresStore
+func TestPostgresMigratorLock(t *testing.T) {
+	conn := os.Getenv("STATS_TEST_DB")
+	if conn == "" {
+		t.Skip("STATS_TEST_DB is not set")
+	}
+
+	db, err := sql.Open("postgres", conn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewPostgresMigrator(db)
+	require.NoError(t, m.Down(0))
+
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- NewPostgresMigrator(db).Up() }()
+	}
+
+	for i := 0; i < cap(errs); i++ {
+		assert.NoError(t, <-errs, "the concurrent migrations run one after another")
+	}
+
+	assert.NoError(t, m.Check())
+}
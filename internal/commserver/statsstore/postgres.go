@@ -0,0 +1,65 @@
+package statsstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	pq "github.com/lib/pq"
+)
+
+var postgresDialect = dialect{
+	placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+	timeValue: func(t time.Time) interface{} {
+		return t.UTC()
+	},
+	scanTime: func(v interface{}) (time.Time, error) {
+		t, ok := v.(time.Time)
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid created_at %v", v)
+		}
+
+		return t, nil
+	},
+	bucket: func(n int) string {
+		return fmt.Sprintf("floor(extract(epoch FROM created_at) / $%d)", n)
+	},
+	insert: func(txn *sql.Tx, d *dialect, samples []PeerStats) error {
+		stmt, err := txn.Prepare(pq.CopyIn(peerStatsTable, peerStatsColumns...))
+		if err != nil {
+			return fmt.Errorf("cannot prepare statement: %v", err)
+		}
+
+		for i := range samples {
+			if _, err := stmt.Exec(sampleValues(d, &samples[i])...); err != nil {
+				_ = stmt.Close()
+				return fmt.Errorf("cannot exec statement: %v", err)
+			}
+		}
+
+		if _, err := stmt.Exec(); err != nil {
+			_ = stmt.Close()
+			return fmt.Errorf("cannot finalize statement: %v", err)
+		}
+
+		if err := stmt.Close(); err != nil {
+			return fmt.Errorf("cannot close statement: %v", err)
+		}
+
+		return nil
+	},
+}
+
+// NewPostgresStore creates a StatsStore in the peer_stats table of a postgres db. With migrate the
+// pending migrations are applied, otherwise the schema has to be on the latest version. Closing the
+// store closes db.
+func NewPostgresStore(db *sql.DB, migrate bool) (StatsStore, error) {
+	if err := openSchema(NewPostgresMigrator(db), migrate); err != nil {
+		return nil, err
+	}
+
+	return &sqlStore{db: db, dialect: postgresDialect}, nil
+}
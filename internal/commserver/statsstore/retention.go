@@ -0,0 +1,78 @@
+package statsstore
+
+import (
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+)
+
+const defaultRetentionPeriod = time.Hour
+
+// RetentionConfig is the stats retention configuration, zero durations disable each job
+type RetentionConfig struct {
+	// MaxAge is how long the samples are kept
+	MaxAge time.Duration
+	// DownsampleAfter is the age after which the samples are downsampled to DownsampleInterval
+	DownsampleAfter    time.Duration
+	DownsampleInterval time.Duration
+	// Period is how often the jobs run, an hour by default
+	Period time.Duration
+	Log    logging.Logger
+}
+
+// Retention periodically removes and downsamples the old samples of a store
+type Retention struct {
+	store  StatsStore
+	config RetentionConfig
+}
+
+// NewRetention creates the retention jobs of a store, Run has to be called to start them
+func NewRetention(store StatsStore, config *RetentionConfig) *Retention {
+	r := &Retention{store: store, config: *config}
+
+	if r.config.Period <= 0 {
+		r.config.Period = defaultRetentionPeriod
+	}
+
+	return r
+}
+
+// RunOnce removes the samples older than MaxAge and downsamples the ones older than
+// DownsampleAfter, relative to now
+func (r *Retention) RunOnce(now time.Time) error {
+	log := r.config.Log
+
+	if r.config.MaxAge > 0 {
+		removed, err := r.store.DeleteBefore(now.Add(-r.config.MaxAge))
+		if err != nil {
+			return err
+		}
+
+		log.Debug().Int64("removed", removed).Msg("stats retention")
+	}
+
+	if r.config.DownsampleAfter > 0 && r.config.DownsampleInterval > 0 {
+		removed, err := r.store.Downsample(now.Add(-r.config.DownsampleAfter), r.config.DownsampleInterval)
+		if err != nil {
+			return err
+		}
+
+		log.Debug().Int64("removed", removed).Msg("stats downsampling")
+	}
+
+	return nil
+}
+
+// Run runs the jobs every Period, it never returns
+func (r *Retention) Run() {
+	ticker := time.NewTicker(r.config.Period)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RunOnce(time.Now()); err != nil {
+			r.config.Log.Error().Err(err).Msg("stats retention failed")
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,172 @@
+package statsstore
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	pq "github.com/lib/pq"
+)
+
+const sessionEventsTable = "session_events"
+
+// SessionEventType is the kind of a session event
+type SessionEventType string
+
+const (
+	// SessionConnected is emitted when the peer connects to the server
+	SessionConnected SessionEventType = "connect"
+	// SessionAuthenticated is emitted when the identity of the peer is known
+	SessionAuthenticated SessionEventType = "authenticated"
+	// SessionTopicsChanged is emitted when the peer subscribed topics change
+	SessionTopicsChanged SessionEventType = "topics_changed"
+	// SessionDisconnected is emitted when the peer is gone, with the reason and the session duration
+	SessionDisconnected SessionEventType = "disconnect"
+)
+
+// SessionEvent is a lifecycle event of a peer session
+type SessionEvent struct {
+	Time     time.Time        `json:"time"`
+	Type     SessionEventType `json:"type"`
+	Alias    uint64           `json:"alias"`
+	Identity string           `json:"identity"`
+	Version  string           `json:"version"`
+	Cluster  string           `json:"cluster"`
+
+	// TopicCount is the number of subscribed topics
+	TopicCount uint32 `json:"topicCount"`
+
+	// Reason and Duration are only set on disconnect
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// SessionSink receives the session events
+type SessionSink interface {
+	Write(events []SessionEvent) error
+	Close() error
+}
+
+type ndjsonSessionSink struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewNDJSONSessionSink creates a SessionSink writing a json event per line to w, e.g. os.Stdout.
+// Closing the sink doesn't close w.
+func NewNDJSONSessionSink(w io.Writer) SessionSink {
+	return &ndjsonSessionSink{w: w}
+}
+
+// OpenNDJSONSessionSink creates a SessionSink appending a json event per line to the file in path
+func OpenNDJSONSessionSink(path string) (SessionSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ndjsonSessionSink{w: f, closer: f}, nil
+}
+
+func (s *ndjsonSessionSink) Write(events []SessionEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	w := bufio.NewWriter(s.w)
+	encoder := json.NewEncoder(w)
+
+	for i := range events {
+		if err := encoder.Encode(&events[i]); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func (s *ndjsonSessionSink) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+
+	return nil
+}
+
+var sessionEventsColumns = []string{
+	"created_at",
+	"event",
+	"peer_alias",
+	"user_id",
+	"version",
+	"cluster",
+	"topic_count",
+	"reason",
+	"duration_ms",
+}
+
+type postgresSessionSink struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionSink creates a SessionSink in the session_events table of a postgres db. With
+// migrate the pending migrations are applied, otherwise the schema has to be on the latest version.
+// Closing the sink closes db.
+func NewPostgresSessionSink(db *sql.DB, migrate bool) (SessionSink, error) {
+	if err := openSchema(NewPostgresMigrator(db), migrate); err != nil {
+		return nil, err
+	}
+
+	return &postgresSessionSink{db: db}, nil
+}
+
+func (s *postgresSessionSink) Write(events []SessionEvent) (err error) {
+	if len(events) == 0 {
+		return nil
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %v", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	stmt, err := txn.Prepare(pq.CopyIn(sessionEventsTable, sessionEventsColumns...))
+	if err != nil {
+		return fmt.Errorf("cannot prepare statement: %v", err)
+	}
+
+	for _, e := range events {
+		_, err = stmt.Exec(e.Time.UTC(), string(e.Type), int64(e.Alias), e.Identity, e.Version, e.Cluster,
+			int64(e.TopicCount), e.Reason, int64(e.Duration/time.Millisecond))
+		if err != nil {
+			_ = stmt.Close()
+			return fmt.Errorf("cannot exec statement: %v", err)
+		}
+	}
+
+	if _, err = stmt.Exec(); err != nil {
+		_ = stmt.Close()
+		return fmt.Errorf("cannot finalize statement: %v", err)
+	}
+
+	if err = stmt.Close(); err != nil {
+		return fmt.Errorf("cannot close statement: %v", err)
+	}
+
+	return txn.Commit()
+}
+
+func (s *postgresSessionSink) Close() error {
+	return s.db.Close()
+}
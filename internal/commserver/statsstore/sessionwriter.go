@@ -0,0 +1,166 @@
+package statsstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+)
+
+const defaultSessionWriterQueueSize = 256
+
+// SessionWriterConfig is the SessionWriter configuration, zero values use the defaults
+type SessionWriterConfig struct {
+	// QueueSize is the max number of event batches waiting to be written, the rest are dropped
+	QueueSize int
+	// MaxRetries is how many times a failed write is retried, with an exponential backoff from
+	// InitialBackoff up to MaxBackoff
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Log            logging.Logger
+}
+
+// SessionWriter is a SessionSink that writes the events to another sink from a single goroutine,
+// so a slow or failing sink never blocks the caller
+type SessionWriter struct {
+	sink           SessionSink
+	queue          chan []SessionEvent
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	log            logging.Logger
+
+	mutex   sync.Mutex
+	closed  bool
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// NewSessionWriter creates a SessionWriter of sink, Run has to be called to start writing
+func NewSessionWriter(sink SessionSink, config *SessionWriterConfig) *SessionWriter {
+	w := &SessionWriter{
+		sink:           sink,
+		maxRetries:     config.MaxRetries,
+		initialBackoff: config.InitialBackoff,
+		maxBackoff:     config.MaxBackoff,
+		log:            config.Log,
+		closing:        make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultSessionWriterQueueSize
+	}
+
+	w.queue = make(chan []SessionEvent, queueSize)
+
+	if w.maxRetries <= 0 {
+		w.maxRetries = defaultWriterMaxRetries
+	}
+
+	if w.initialBackoff <= 0 {
+		w.initialBackoff = defaultWriterInitialBackoff
+	}
+
+	if w.maxBackoff <= 0 {
+		w.maxBackoff = defaultWriterMaxBackoff
+	}
+
+	return w
+}
+
+// Write queues the events, it doesn't wait for the sink. If the queue is full or the writer is
+// closed the events are dropped.
+func (w *SessionWriter) Write(events []SessionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		w.log.Warn().Int("events", len(events)).Msg("session writer is closed, events dropped")
+		return nil
+	}
+
+	select {
+	case w.queue <- events:
+	default:
+		w.log.Warn().Int("events", len(events)).Msg("session writer queue is full, events dropped")
+	}
+
+	return nil
+}
+
+// Run writes the queued events until the writer is closed
+func (w *SessionWriter) Run() {
+	defer close(w.done)
+
+	for events := range w.queue {
+		// merge the batches queued meanwhile
+	merge:
+		for {
+			select {
+			case more, ok := <-w.queue:
+				if !ok {
+					break merge
+				}
+
+				events = append(events, more...)
+			default:
+				break merge
+			}
+		}
+
+		w.write(events)
+	}
+}
+
+// Close stops the writer once the queued events are written, without waiting for the retries,
+// and closes the sink
+func (w *SessionWriter) Close() error {
+	w.mutex.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closing)
+		close(w.queue)
+	}
+	w.mutex.Unlock()
+
+	<-w.done
+
+	return w.sink.Close()
+}
+
+func (w *SessionWriter) write(events []SessionEvent) {
+	backoff := w.initialBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := w.sink.Write(events)
+		if err == nil {
+			return
+		}
+
+		if attempt >= w.maxRetries {
+			w.log.Error().Err(err).Int("events", len(events)).Msg("cannot write the session events, retries exhausted")
+			return
+		}
+
+		w.log.Warn().Err(err).Dur("backoff", backoff).Msg("cannot write the session events, retrying")
+
+		select {
+		case <-time.After(backoff):
+		case <-w.closing:
+			w.log.Error().Err(err).Int("events", len(events)).Msg("cannot write the session events on close")
+			return
+		}
+
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package statsstore
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSessionSink blocks the writes until unblock is closed, and fails them while fail is set
+type blockingSessionSink struct {
+	unblock chan struct{}
+	mutex   sync.Mutex
+	fail    bool
+	events  []SessionEvent
+	closed  bool
+}
+
+func (s *blockingSessionSink) Write(events []SessionEvent) error {
+	<-s.unblock
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.fail {
+		return errors.New("sink is down")
+	}
+
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *blockingSessionSink) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+	return nil
+}
+
+func TestSessionWriter(t *testing.T) {
+	t.Run("async", func(t *testing.T) {
+		sink := &blockingSessionSink{unblock: make(chan struct{})}
+		w := NewSessionWriter(sink, &SessionWriterConfig{QueueSize: 2, Log: zerolog.Nop()})
+
+		go w.Run()
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 5; i++ {
+				require.NoError(t, w.Write([]SessionEvent{{Alias: uint64(i)}}))
+			}
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("write blocked on the sink")
+		}
+
+		close(sink.unblock)
+		require.NoError(t, w.Close())
+
+		assert.True(t, sink.closed)
+		assert.True(t, len(sink.events) >= 2, "the queued events are written on close")
+		assert.True(t, len(sink.events) < 5, "the events beyond the queue are dropped")
+
+		require.NoError(t, w.Write([]SessionEvent{{Alias: 10}}), "writes after close are dropped")
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		sink := &blockingSessionSink{unblock: make(chan struct{}), fail: true}
+		close(sink.unblock)
+
+		w := NewSessionWriter(sink, &SessionWriterConfig{
+			MaxRetries:     1000,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			Log:            zerolog.Nop(),
+		})
+
+		go w.Run()
+
+		require.NoError(t, w.Write([]SessionEvent{{Alias: 1}}))
+		time.Sleep(10 * time.Millisecond)
+
+		sink.mutex.Lock()
+		sink.fail = false
+		sink.mutex.Unlock()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for written := 0; written == 0; {
+			require.True(t, time.Now().Before(deadline), "timeout waiting for the retry")
+			time.Sleep(time.Millisecond)
+
+			sink.mutex.Lock()
+			written = len(sink.events)
+			sink.mutex.Unlock()
+		}
+
+		require.NoError(t, w.Close())
+		assert.Len(t, sink.events, 1)
+	})
+}
@@ -0,0 +1,284 @@
+package statsstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const peerStatsTable = "peer_stats"
+
+// peerStatsColumns are the typed columns of the peer_stats table, in the order of the values of
+// sampleValues and scanSample
+var peerStatsColumns = []string{
+	"created_at",
+	"resolution",
+	"peer_alias",
+	"user_id",
+	"version",
+	"cluster",
+	"state",
+	"topic_count",
+	"local_candidate_type",
+	"remote_candidate_type",
+	"reliable_bytes_sent",
+	"reliable_bytes_received",
+	"reliable_messages_sent",
+	"reliable_messages_received",
+	"reliable_buffered_amount",
+	"unreliable_bytes_sent",
+	"unreliable_bytes_received",
+	"unreliable_messages_sent",
+	"unreliable_messages_received",
+	"unreliable_buffered_amount",
+	"ice_bytes_sent",
+	"ice_bytes_received",
+	"sctp_bytes_sent",
+	"sctp_bytes_received",
+}
+
+// dialect is what differs between the sql backends
+type dialect struct {
+	// placeholder returns the nth (from 1) query parameter
+	placeholder func(n int) string
+	// timeValue and scanTime convert the created_at column
+	timeValue func(t time.Time) interface{}
+	scanTime  func(v interface{}) (time.Time, error)
+	// bucket returns the expression of the downsample interval of created_at, the interval is the
+	// nth parameter, in seconds
+	bucket func(n int) string
+	// insert writes the samples in a transaction
+	insert func(txn *sql.Tx, d *dialect, samples []PeerStats) error
+}
+
+// sqlStore is the StatsStore of the sql backends
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func sampleValues(d *dialect, s *PeerStats) []interface{} {
+	return []interface{}{
+		d.timeValue(s.Time),
+		int64(s.Resolution / time.Second),
+		s.Alias,
+		s.Identity,
+		s.Version,
+		s.Cluster,
+		s.State,
+		s.TopicCount,
+		s.LocalCandidateType,
+		s.RemoteCandidateType,
+		s.ReliableBytesSent,
+		s.ReliableBytesReceived,
+		s.ReliableMessagesSent,
+		s.ReliableMessagesReceived,
+		s.ReliableBufferedAmount,
+		s.UnreliableBytesSent,
+		s.UnreliableBytesReceived,
+		s.UnreliableMessagesSent,
+		s.UnreliableMessagesReceived,
+		s.UnreliableBufferedAmount,
+		s.ICEBytesSent,
+		s.ICEBytesReceived,
+		s.SCTPBytesSent,
+		s.SCTPBytesReceived,
+	}
+}
+
+func scanSample(d *dialect, rows *sql.Rows) (PeerStats, error) {
+	var s PeerStats
+	var t interface{}
+	var resolution int64
+
+	err := rows.Scan(
+		&t,
+		&resolution,
+		&s.Alias,
+		&s.Identity,
+		&s.Version,
+		&s.Cluster,
+		&s.State,
+		&s.TopicCount,
+		&s.LocalCandidateType,
+		&s.RemoteCandidateType,
+		&s.ReliableBytesSent,
+		&s.ReliableBytesReceived,
+		&s.ReliableMessagesSent,
+		&s.ReliableMessagesReceived,
+		&s.ReliableBufferedAmount,
+		&s.UnreliableBytesSent,
+		&s.UnreliableBytesReceived,
+		&s.UnreliableMessagesSent,
+		&s.UnreliableMessagesReceived,
+		&s.UnreliableBufferedAmount,
+		&s.ICEBytesSent,
+		&s.ICEBytesReceived,
+		&s.SCTPBytesSent,
+		&s.SCTPBytesReceived,
+	)
+	if err != nil {
+		return s, err
+	}
+
+	s.Resolution = time.Duration(resolution) * time.Second
+	s.Time, err = d.scanTime(t)
+
+	return s, err
+}
+
+func (s *sqlStore) Insert(samples []PeerStats) (err error) {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot start tx: %v", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	if err = s.dialect.insert(txn, &s.dialect, samples); err != nil {
+		return err
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("cannot commit tx: %v", err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) Query(q Query) ([]PeerStats, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+s.dialect.placeholder(len(args)))
+	}
+
+	if q.Identity != "" {
+		where("user_id = ", q.Identity)
+	}
+
+	if q.Alias != 0 {
+		where("peer_alias = ", q.Alias)
+	}
+
+	if q.Cluster != "" {
+		where("cluster = ", q.Cluster)
+	}
+
+	if !q.From.IsZero() {
+		where("created_at >= ", s.dialect.timeValue(q.From))
+	}
+
+	if !q.To.IsZero() {
+		where("created_at < ", s.dialect.timeValue(q.To))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(peerStatsColumns, ", "), peerStatsTable)
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY created_at, id"
+
+	if q.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []PeerStats{}
+
+	for rows.Next() {
+		sample, err := scanSample(&s.dialect, rows)
+		if err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+func (s *sqlStore) DeleteBefore(t time.Time) (int64, error) {
+	result, err := s.db.Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE created_at < %s", peerStatsTable, s.dialect.placeholder(1)),
+		s.dialect.timeValue(t))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (s *sqlStore) Downsample(t time.Time, interval time.Duration) (removed int64, err error) {
+	t = t.Truncate(interval)
+	seconds := int64(interval / time.Second)
+
+	if seconds <= 0 {
+		return 0, fmt.Errorf("invalid downsample interval %s", interval)
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("cannot start tx: %v", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = txn.Rollback()
+		}
+	}()
+
+	p := s.dialect.placeholder
+
+	result, err := txn.Exec(fmt.Sprintf(`DELETE FROM %[1]s WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY cluster, peer_alias, user_id, %[2]s ORDER BY created_at DESC, id DESC
+        ) AS rn
+        FROM %[1]s WHERE created_at < %[3]s AND resolution < %[4]s
+    ) ranked WHERE rn > 1
+)`, peerStatsTable, s.dialect.bucket(1), p(2), p(3)), seconds, s.dialect.timeValue(t), seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err = result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = txn.Exec(fmt.Sprintf("UPDATE %s SET resolution = %s WHERE created_at < %s AND resolution < %s",
+		peerStatsTable, p(1), p(2), p(3)), seconds, s.dialect.timeValue(t), seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = txn.Commit(); err != nil {
+		return 0, fmt.Errorf("cannot commit tx: %v", err)
+	}
+
+	return removed, nil
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
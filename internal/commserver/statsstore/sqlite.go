@@ -0,0 +1,83 @@
+package statsstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteDialect stores created_at as unix milliseconds
+var sqliteDialect = dialect{
+	placeholder: func(n int) string {
+		return "?" + strconv.Itoa(n)
+	},
+	timeValue: func(t time.Time) interface{} {
+		return t.UnixNano() / int64(time.Millisecond)
+	},
+	scanTime: func(v interface{}) (time.Time, error) {
+		ms, ok := v.(int64)
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid created_at %v", v)
+		}
+
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	},
+	bucket: func(n int) string {
+		return fmt.Sprintf("(created_at / (?%d * 1000))", n)
+	},
+	insert: func(txn *sql.Tx, d *dialect, samples []PeerStats) error {
+		placeholders := make([]string, len(peerStatsColumns))
+		for i := range placeholders {
+			placeholders[i] = "?"
+		}
+
+		stmt, err := txn.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", peerStatsTable,
+			strings.Join(peerStatsColumns, ", "), strings.Join(placeholders, ", ")))
+		if err != nil {
+			return fmt.Errorf("cannot prepare statement: %v", err)
+		}
+		defer stmt.Close()
+
+		for i := range samples {
+			if _, err := stmt.Exec(sampleValues(d, &samples[i])...); err != nil {
+				return fmt.Errorf("cannot exec statement: %v", err)
+			}
+		}
+
+		return nil
+	},
+}
+
+// OpenSQLite opens a sqlite db file
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000", path))
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite has a single writer
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// NewSQLiteStore creates a StatsStore in a sqlite db file, for local runs. With migrate the pending
+// migrations are applied, otherwise the schema has to be on the latest version.
+func NewSQLiteStore(path string, migrate bool) (StatsStore, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := openSchema(NewSQLiteMigrator(db), migrate); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqlStore{db: db, dialect: sqliteDialect}, nil
+}
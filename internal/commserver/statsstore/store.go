@@ -0,0 +1,133 @@
+// Package statsstore keeps the periodic per peer stats samples and the session events of the comms
+// server
+package statsstore
+
+import (
+	"time"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+)
+
+// PeerStats is a stats sample of a peer. The counters are cumulative since the peer connected.
+type PeerStats struct {
+	Time time.Time `json:"time"`
+	// Resolution is the interval the sample was downsampled to, 0 for raw samples
+	Resolution time.Duration `json:"resolution,omitempty"`
+
+	Alias    uint64 `json:"alias"`
+	Identity string `json:"identity"`
+	Version  string `json:"version"`
+	Cluster  string `json:"cluster"`
+
+	State               string `json:"state"`
+	TopicCount          uint32 `json:"topicCount"`
+	LocalCandidateType  string `json:"localCandidateType"`
+	RemoteCandidateType string `json:"remoteCandidateType"`
+
+	ReliableBytesSent        uint64 `json:"reliableBytesSent"`
+	ReliableBytesReceived    uint64 `json:"reliableBytesReceived"`
+	ReliableMessagesSent     uint32 `json:"reliableMessagesSent"`
+	ReliableMessagesReceived uint32 `json:"reliableMessagesReceived"`
+	ReliableBufferedAmount   uint64 `json:"reliableBufferedAmount"`
+
+	UnreliableBytesSent        uint64 `json:"unreliableBytesSent"`
+	UnreliableBytesReceived    uint64 `json:"unreliableBytesReceived"`
+	UnreliableMessagesSent     uint32 `json:"unreliableMessagesSent"`
+	UnreliableMessagesReceived uint32 `json:"unreliableMessagesReceived"`
+	UnreliableBufferedAmount   uint64 `json:"unreliableBufferedAmount"`
+
+	ICEBytesSent      uint64 `json:"iceBytesSent"`
+	ICEBytesReceived  uint64 `json:"iceBytesReceived"`
+	SCTPBytesSent     uint64 `json:"sctpBytesSent"`
+	SCTPBytesReceived uint64 `json:"sctpBytesReceived"`
+}
+
+// NewPeerStats converts a broker peer stats into a sample taken at t
+func NewPeerStats(t time.Time, version string, cluster string, stats *broker.PeerStats) PeerStats {
+	return PeerStats{
+		Time:     t,
+		Alias:    stats.Alias,
+		Identity: string(stats.Identity),
+		Version:  version,
+		Cluster:  cluster,
+
+		State:               stats.State.String(),
+		TopicCount:          stats.TopicCount,
+		LocalCandidateType:  stats.LocalCandidateType.String(),
+		RemoteCandidateType: stats.RemoteCandidateType.String(),
+
+		ReliableBytesSent:        stats.ReliableBytesSent,
+		ReliableBytesReceived:    stats.ReliableBytesReceived,
+		ReliableMessagesSent:     stats.ReliableMessagesSent,
+		ReliableMessagesReceived: stats.ReliableMessagesReceived,
+		ReliableBufferedAmount:   stats.ReliableBufferedAmount,
+
+		UnreliableBytesSent:        stats.UnreliableBytesSent,
+		UnreliableBytesReceived:    stats.UnreliableBytesReceived,
+		UnreliableMessagesSent:     stats.UnreliableMessagesSent,
+		UnreliableMessagesReceived: stats.UnreliableMessagesReceived,
+		UnreliableBufferedAmount:   stats.UnreliableBufferedAmount,
+
+		ICEBytesSent:      stats.ICETransportBytesSent,
+		ICEBytesReceived:  stats.ICETransportBytesReceived,
+		SCTPBytesSent:     stats.SCTPTransportBytesSent,
+		SCTPBytesReceived: stats.SCTPTransportBytesReceived,
+	}
+}
+
+// Query selects samples, the zero value fields match every sample
+type Query struct {
+	Identity string
+	Alias    uint64
+	Cluster  string
+	// From is inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// Limit is the max number of samples returned, the oldest first
+	Limit int
+}
+
+func (q *Query) matches(s *PeerStats) bool {
+	return (q.Identity == "" || q.Identity == s.Identity) &&
+		(q.Alias == 0 || q.Alias == s.Alias) &&
+		(q.Cluster == "" || q.Cluster == s.Cluster) &&
+		(q.From.IsZero() || !s.Time.Before(q.From)) &&
+		(q.To.IsZero() || s.Time.Before(q.To))
+}
+
+// StatsStore is a per peer stats storage backend
+type StatsStore interface {
+	// Insert stores the samples
+	Insert(samples []PeerStats) error
+	// Query returns the samples matching q, sorted by time
+	Query(q Query) ([]PeerStats, error)
+	// DeleteBefore removes the samples older than t, returning how many were removed
+	DeleteBefore(t time.Time) (int64, error)
+	// Downsample keeps a single sample, the last one, per peer and interval of the samples older
+	// than t, returning how many were removed. The counters are cumulative, so no totals are lost.
+	Downsample(t time.Time, interval time.Duration) (int64, error)
+	Close() error
+}
+
+// IdentityStats returns the samples of an identity in the last period, e.g. the stats of a user in
+// the last hour
+func IdentityStats(store StatsStore, identity string, period time.Duration) ([]PeerStats, error) {
+	return store.Query(Query{Identity: identity, From: time.Now().Add(-period)})
+}
+
+// downsampleKey groups the samples that are collapsed together
+type downsampleKey struct {
+	cluster  string
+	alias    uint64
+	identity string
+	bucket   int64
+}
+
+func newDownsampleKey(s *PeerStats, interval time.Duration) downsampleKey {
+	return downsampleKey{
+		cluster:  s.Cluster,
+		alias:    s.Alias,
+		identity: s.Identity,
+		bucket:   s.Time.UnixNano() / int64(interval),
+	}
+}
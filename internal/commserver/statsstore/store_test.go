@@ -0,0 +1,189 @@
+package statsstore
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sample(t time.Time, alias uint64, identity string, bytesSent uint64) PeerStats {
+	return PeerStats{
+		Time:              t,
+		Alias:             alias,
+		Identity:          identity,
+		Version:           "test",
+		Cluster:           "local",
+		State:             "connected",
+		ICEBytesSent:      bytesSent,
+		ReliableBytesSent: bytesSent,
+	}
+}
+
+func testStore(t *testing.T, store StatsStore) {
+	base := time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	samples := []PeerStats{}
+	for i := 0; i < 6; i++ {
+		samples = append(samples, sample(base.Add(time.Duration(i)*10*time.Second), 1, "a", uint64(i)*100))
+		samples = append(samples, sample(base.Add(time.Duration(i)*10*time.Second), 2, "b", uint64(i)*10))
+	}
+
+	require.NoError(t, store.Insert(samples))
+	require.NoError(t, store.Insert(nil))
+
+	t.Run("query", func(t *testing.T) {
+		result, err := store.Query(Query{Identity: "a"})
+		require.NoError(t, err)
+		require.Len(t, result, 6)
+		assert.True(t, base.Equal(result[0].Time))
+		assert.Equal(t, samples[0], withTime(result[0], samples[0].Time))
+
+		result, err = store.Query(Query{Alias: 2, From: base.Add(20 * time.Second), To: base.Add(40 * time.Second)})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, uint64(20), result[0].ICEBytesSent)
+		assert.Equal(t, uint64(30), result[1].ICEBytesSent)
+
+		result, err = store.Query(Query{Limit: 3})
+		require.NoError(t, err)
+		assert.Len(t, result, 3)
+	})
+
+	t.Run("downsample", func(t *testing.T) {
+		removed, err := store.Downsample(base.Add(time.Minute), 30*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), removed)
+
+		result, err := store.Query(Query{Identity: "a"})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, uint64(200), result[0].ICEBytesSent, "the last sample of the interval is kept")
+		assert.Equal(t, uint64(500), result[1].ICEBytesSent)
+		assert.Equal(t, 30*time.Second, result[0].Resolution)
+
+		removed, err = store.Downsample(base.Add(time.Minute), 30*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), removed, "downsampling is idempotent")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		removed, err := store.DeleteBefore(base.Add(30 * time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), removed)
+
+		result, err := store.Query(Query{})
+		require.NoError(t, err)
+		assert.Len(t, result, 2)
+	})
+
+	t.Run("identity stats", func(t *testing.T) {
+		now := time.Now()
+		require.NoError(t, store.Insert([]PeerStats{
+			sample(now.Add(-2*time.Hour), 1, "c", 1),
+			sample(now.Add(-time.Minute), 1, "c", 2),
+		}))
+
+		result, err := IdentityStats(store, "c", time.Hour)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, uint64(2), result[0].ICEBytesSent)
+	})
+
+	require.NoError(t, store.Close())
+}
+
+// withTime replaces the time of a stored sample, the backends don't keep the location or the
+// monotonic clock
+func withTime(s PeerStats, t time.Time) PeerStats {
+	s.Time = t
+	return s
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "statsstore")
+	require.NoError(t, err)
+
+	return dir
+}
+
+func TestSQLiteStore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := NewSQLiteStore(filepath.Join(dir, "stats.db"), true)
+	require.NoError(t, err)
+
+	heatmapStore, ok := store.(HeatmapStore)
+	require.True(t, ok)
+	require.NoError(t, heatmapStore.InsertHeatmap([]ParcelUsers{
+		{Time: time.Now(), Cluster: "local", X: -1, Z: 3, Users: 2},
+	}))
+
+	testStore(t, store)
+}
+
+func TestJSONLinesStore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := NewJSONLinesStore(filepath.Join(dir, "stats.jsonl"))
+	require.NoError(t, err)
+
+	testStore(t, store)
+}
+
+// TestPostgresStore runs against the db in STATS_TEST_DB, e.g.
+// "host=localhost user=testuser dbname=testdb sslmode=disable"
+func TestPostgresStore(t *testing.T) {
+	conn := os.Getenv("STATS_TEST_DB")
+	if conn == "" {
+		t.Skip("STATS_TEST_DB is not set")
+	}
+
+	db, err := sql.Open("postgres", conn)
+	require.NoError(t, err)
+
+	require.NoError(t, NewPostgresMigrator(db).Down(0))
+
+	store, err := NewPostgresStore(db, true)
+	require.NoError(t, err)
+
+	testStore(t, store)
+}
+
+func TestRetention(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := NewJSONLinesStore(filepath.Join(dir, "stats.jsonl"))
+	require.NoError(t, err)
+
+	now := time.Date(2019, 12, 3, 12, 30, 0, 0, time.UTC)
+	require.NoError(t, store.Insert([]PeerStats{
+		sample(now.Add(-48*time.Hour), 1, "a", 1),
+		sample(now.Add(-3*time.Hour), 1, "a", 2),
+		sample(now.Add(-3*time.Hour).Add(10*time.Minute), 1, "a", 3),
+		sample(now, 1, "a", 4),
+	}))
+
+	retention := NewRetention(store, &RetentionConfig{
+		MaxAge:             24 * time.Hour,
+		DownsampleAfter:    time.Hour,
+		DownsampleInterval: time.Hour,
+		Log:                zerolog.Nop(),
+	})
+	require.NoError(t, retention.RunOnce(now))
+
+	result, err := store.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, uint64(3), result[0].ICEBytesSent)
+	assert.Equal(t, uint64(4), result[1].ICEBytesSent)
+}
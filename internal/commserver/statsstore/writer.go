@@ -0,0 +1,459 @@
+package statsstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/decentraland/world/internal/commons/logging"
+)
+
+const (
+	defaultWriterQueueSize      = 16
+	defaultWriterMaxBatchSize   = 5000
+	defaultWriterMaxRetries     = 5
+	defaultWriterInitialBackoff = time.Second
+	defaultWriterMaxBackoff     = time.Minute
+	defaultWriterSpillMaxSize   = 100 * 1024 * 1024
+)
+
+// WriterConfig is the Writer configuration, zero values use the defaults
+type WriterConfig struct {
+	// QueueSize is the max number of batches waiting to be written
+	QueueSize int
+	// MaxBatchSize is the max number of samples written in a single insert, the queued batches are
+	// merged up to it
+	MaxBatchSize int
+	// MaxRetries is how many times a failed insert is retried, with an exponential backoff from
+	// InitialBackoff up to MaxBackoff
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// SpillPath is a json lines file where the batches are kept when the queue is full or the
+	// retries are exhausted, instead of dropping them. They are written to the store once it works
+	// again, also after a restart. Once the file reaches SpillMaxSize bytes the batches are dropped.
+	SpillPath    string
+	SpillMaxSize int64
+	Log          logging.Logger
+}
+
+// WriterStats are the Writer stats, the counters are since the last Writer.Stats call
+type WriterStats struct {
+	QueueDepth     int
+	WrittenBatches uint64
+	DroppedBatches uint64
+	SpilledBatches uint64
+	Retries        uint64
+}
+
+// Writer writes the samples to a store from a single goroutine, so a slow or failing store
+// doesn't pile up goroutines or block the caller
+type Writer struct {
+	// NOTE: accessed atomically, keep them first for the 64 bit alignment
+	written uint64
+	dropped uint64
+	spilled uint64
+	retries uint64
+
+	store          StatsStore
+	spill          *jsonLinesStore
+	queue          chan []PeerStats
+	maxBatchSize   int
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	log            logging.Logger
+
+	mutex   sync.Mutex
+	closed  bool
+	closing chan struct{}
+	done    chan struct{}
+
+	// spillMutex guards the spill file, and spillPending which is set when it has batches to replay.
+	// The spill file is renamed to replayPath to be replayed, so the lock is never held while
+	// writing to the store.
+	spillMutex   sync.Mutex
+	spillPending bool
+	spillMaxSize int64
+	replayPath   string
+
+	// replayAfter and replayBackoff delay the next replay after a failed one, they are only used by
+	// the Run goroutine
+	replayAfter   time.Time
+	replayBackoff time.Duration
+}
+
+// NewWriter creates a Writer of store, Run has to be called to start writing
+func NewWriter(store StatsStore, config *WriterConfig) (*Writer, error) {
+	w := &Writer{
+		store:          store,
+		maxBatchSize:   config.MaxBatchSize,
+		maxRetries:     config.MaxRetries,
+		initialBackoff: config.InitialBackoff,
+		maxBackoff:     config.MaxBackoff,
+		log:            config.Log,
+		closing:        make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultWriterQueueSize
+	}
+
+	w.queue = make(chan []PeerStats, queueSize)
+
+	if w.maxBatchSize <= 0 {
+		w.maxBatchSize = defaultWriterMaxBatchSize
+	}
+
+	if w.maxRetries <= 0 {
+		w.maxRetries = defaultWriterMaxRetries
+	}
+
+	if w.initialBackoff <= 0 {
+		w.initialBackoff = defaultWriterInitialBackoff
+	}
+
+	if w.maxBackoff <= 0 {
+		w.maxBackoff = defaultWriterMaxBackoff
+	}
+
+	if config.SpillPath != "" {
+		info, err := os.Stat(config.SpillPath)
+		w.spillPending = err == nil && info.Size() > 0
+
+		spill, err := NewJSONLinesStore(config.SpillPath)
+		if err != nil {
+			return nil, err
+		}
+
+		w.spill = spill.(*jsonLinesStore)
+		w.replayPath = config.SpillPath + ".replay"
+		w.replayBackoff = w.initialBackoff
+
+		w.spillMaxSize = config.SpillMaxSize
+		if w.spillMaxSize <= 0 {
+			w.spillMaxSize = defaultWriterSpillMaxSize
+		}
+	}
+
+	return w, nil
+}
+
+// Enqueue queues a batch of samples to be written, it doesn't wait for the store. If the queue is
+// full the batch is spilled, or dropped if there is no spill file.
+func (w *Writer) Enqueue(samples []PeerStats) {
+	if len(samples) == 0 {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		w.spillOrDrop(samples)
+		return
+	}
+
+	select {
+	case w.queue <- samples:
+	default:
+		w.log.Warn().Int("samples", len(samples)).Msg("stats writer queue is full")
+		w.spillOrDrop(samples)
+	}
+}
+
+// Stats returns the writer stats, and resets the counters
+func (w *Writer) Stats() WriterStats {
+	return WriterStats{
+		QueueDepth:     len(w.queue),
+		WrittenBatches: atomic.SwapUint64(&w.written, 0),
+		DroppedBatches: atomic.SwapUint64(&w.dropped, 0),
+		SpilledBatches: atomic.SwapUint64(&w.spilled, 0),
+		Retries:        atomic.SwapUint64(&w.retries, 0),
+	}
+}
+
+// Run writes the queued batches until the writer is closed
+func (w *Writer) Run() {
+	defer close(w.done)
+
+	for samples := range w.queue {
+		// merge the batches queued meanwhile
+	merge:
+		for len(samples) < w.maxBatchSize {
+			select {
+			case more, ok := <-w.queue:
+				if !ok {
+					break merge
+				}
+
+				samples = append(samples, more...)
+			default:
+				break merge
+			}
+		}
+
+		w.write(samples)
+	}
+}
+
+// Close stops the writer once the queued batches are written, the failing ones are spilled
+// without waiting for the retries
+func (w *Writer) Close() {
+	w.mutex.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closing)
+		close(w.queue)
+	}
+	w.mutex.Unlock()
+
+	<-w.done
+}
+
+func (w *Writer) write(samples []PeerStats) {
+	for len(samples) > 0 {
+		n := len(samples)
+		if n > w.maxBatchSize {
+			n = w.maxBatchSize
+		}
+
+		if w.insert(samples[:n]) {
+			w.replaySpill()
+		} else {
+			w.spillOrDrop(samples[:n])
+		}
+
+		samples = samples[n:]
+	}
+}
+
+// insert writes a batch with retries, it returns false if all of them failed
+func (w *Writer) insert(samples []PeerStats) bool {
+	backoff := w.initialBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := w.store.Insert(samples)
+		if err == nil {
+			atomic.AddUint64(&w.written, 1)
+			return true
+		}
+
+		if attempt >= w.maxRetries {
+			w.log.Error().Err(err).Int("samples", len(samples)).Msg("cannot write stats, retries exhausted")
+			return false
+		}
+
+		w.log.Warn().Err(err).Dur("backoff", backoff).Msg("cannot write stats, retrying")
+		atomic.AddUint64(&w.retries, 1)
+
+		select {
+		case <-time.After(backoff):
+		case <-w.closing:
+			return false
+		}
+
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+func (w *Writer) spillOrDrop(samples []PeerStats) {
+	if w.spill != nil {
+		w.spillMutex.Lock()
+		defer w.spillMutex.Unlock()
+
+		info, err := os.Stat(w.spill.path)
+		if err == nil && info.Size() >= w.spillMaxSize {
+			w.log.Error().Int64("size", info.Size()).Msg("stats spill file is full")
+			atomic.AddUint64(&w.dropped, 1)
+			return
+		}
+
+		err = w.spill.Insert(samples)
+		if err == nil {
+			atomic.AddUint64(&w.spilled, 1)
+			w.spillPending = true
+			return
+		}
+
+		w.log.Error().Err(err).Msg("cannot spill stats")
+	}
+
+	atomic.AddUint64(&w.dropped, 1)
+}
+
+// takeSpill renames the spill file to the replay file, unless a previous replay is still pending.
+// It returns false if there is nothing to replay.
+func (w *Writer) takeSpill() (bool, error) {
+	if _, err := os.Stat(w.replayPath); err == nil {
+		return true, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	w.spillMutex.Lock()
+	defer w.spillMutex.Unlock()
+
+	if !w.spillPending {
+		return false, nil
+	}
+
+	if err := os.Rename(w.spill.path, w.replayPath); err != nil {
+		return false, err
+	}
+
+	w.spillPending = false
+
+	return true, nil
+}
+
+// replaySpill writes the spilled batches to the store. After a failure the rest is kept, and the
+// replay is delayed with an exponential backoff.
+func (w *Writer) replaySpill() {
+	if w.spill == nil || time.Now().Before(w.replayAfter) {
+		return
+	}
+
+	pending, err := w.takeSpill()
+	if err == nil && pending {
+		err = w.replay()
+	}
+
+	if err != nil {
+		w.log.Error().Err(err).Dur("backoff", w.replayBackoff).Msg("cannot replay the spilled stats")
+
+		w.replayAfter = time.Now().Add(w.replayBackoff)
+		w.replayBackoff *= 2
+		if w.replayBackoff > w.maxBackoff {
+			w.replayBackoff = w.maxBackoff
+		}
+
+		return
+	}
+
+	w.replayAfter = time.Time{}
+	w.replayBackoff = w.initialBackoff
+}
+
+// replay writes the replay file to the store, reading up to maxBatchSize samples at a time. It's
+// removed once written, or the written samples are removed from it if an insert fails.
+func (w *Writer) replay() error {
+	f, err := os.Open(w.replayPath)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	batch := make([]PeerStats, 0, w.maxBatchSize)
+
+	// written is the offset of the first sample not written yet, read of the next one to read
+	var written, read int64
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			f.Close()
+			return readErr
+		}
+
+		read += int64(len(line))
+
+		if len(line) > 0 {
+			var sample PeerStats
+			if err := json.Unmarshal(line, &sample); err != nil {
+				w.log.Error().Err(err).Msg("skipping an invalid spilled sample")
+			} else {
+				batch = append(batch, sample)
+			}
+		}
+
+		if len(batch) == w.maxBatchSize || (readErr == io.EOF && len(batch) > 0) {
+			if err := w.store.Insert(batch); err != nil {
+				f.Close()
+				return w.keepReplay(written, err)
+			}
+
+			written = read
+			batch = batch[:0]
+
+			if readErr == nil && w.isClosing() {
+				f.Close()
+				return w.keepReplay(written, nil)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	f.Close()
+
+	return os.Remove(w.replayPath)
+}
+
+func (w *Writer) isClosing() bool {
+	select {
+	case <-w.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// keepReplay removes the first offset bytes, already written, from the replay file and returns
+// cause
+func (w *Writer) keepReplay(offset int64, cause error) error {
+	if offset == 0 {
+		return cause
+	}
+
+	if err := dropFilePrefix(w.replayPath, offset); err != nil {
+		w.log.Error().Err(err).Msg("cannot rewrite the spilled stats, they may be written twice")
+	}
+
+	return cause
+}
+
+// dropFilePrefix removes the first offset bytes of a file, through a temp file so it's never left
+// half written
+func dropFilePrefix(path string, offset int64) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+
+	return os.Rename(dst.Name(), path)
+}
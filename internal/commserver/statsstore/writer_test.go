@@ -0,0 +1,200 @@
+package statsstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStore fails the inserts while fail is set, or once it got failAfter inserts
+type failingStore struct {
+	StatsStore
+	mutex     sync.Mutex
+	fail      bool
+	failAfter int
+	inserts   int
+}
+
+func (s *failingStore) Insert(samples []PeerStats) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.inserts++
+	if s.fail || (s.failAfter > 0 && s.inserts > s.failAfter) {
+		return errors.New("store is down")
+	}
+
+	return s.StatsStore.Insert(samples)
+}
+
+func (s *failingStore) insertCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.inserts
+}
+
+// waitInserts waits until the store got n inserts
+func (s *failingStore) waitInserts(t *testing.T, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for s.insertCount() < n {
+		require.True(t, time.Now().Before(deadline), "timeout waiting for the inserts")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *failingStore) setFail(fail bool) {
+	s.mutex.Lock()
+	s.fail = fail
+	s.mutex.Unlock()
+}
+
+func TestWriter(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner, err := NewJSONLinesStore(filepath.Join(dir, "stats.jsonl"))
+	require.NoError(t, err)
+
+	base := time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	newWriter := func(store StatsStore, spillPath string) *Writer {
+		w, err := NewWriter(store, &WriterConfig{
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     2 * time.Millisecond,
+			SpillPath:      spillPath,
+			Log:            zerolog.Nop(),
+		})
+		require.NoError(t, err)
+		return w
+	}
+
+	t.Run("write", func(t *testing.T) {
+		w := newWriter(inner, "")
+		w.Enqueue([]PeerStats{sample(base, 1, "a", 1)})
+		w.Enqueue([]PeerStats{sample(base, 2, "b", 1)})
+		w.Enqueue(nil)
+
+		go w.Run()
+		w.Close()
+
+		result, err := inner.Query(Query{})
+		require.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, 0, w.Stats().QueueDepth)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		store := &failingStore{StatsStore: inner, fail: true}
+		w := newWriter(store, "")
+		w.Enqueue([]PeerStats{sample(base, 1, "a", 2)})
+
+		go w.Run()
+		store.waitInserts(t, 3)
+		w.Close()
+
+		stats := w.Stats()
+		assert.Equal(t, 3, store.insertCount(), "the insert is retried")
+		assert.Equal(t, uint64(2), stats.Retries)
+		assert.Equal(t, uint64(1), stats.DroppedBatches)
+		assert.Equal(t, uint64(0), stats.WrittenBatches)
+	})
+
+	t.Run("spill", func(t *testing.T) {
+		spillPath := filepath.Join(dir, "spill.jsonl")
+		store := &failingStore{StatsStore: inner, fail: true}
+
+		w := newWriter(store, spillPath)
+		w.Enqueue([]PeerStats{sample(base, 3, "c", 1)})
+		go w.Run()
+		store.waitInserts(t, 3)
+		w.Close()
+
+		stats := w.Stats()
+		assert.Equal(t, uint64(1), stats.SpilledBatches)
+		assert.Equal(t, uint64(0), stats.DroppedBatches)
+
+		// the spilled samples are written after a restart, once the store works again
+		store.setFail(false)
+		w = newWriter(store, spillPath)
+		w.Enqueue([]PeerStats{sample(base, 3, "c", 2)})
+		go w.Run()
+		w.Close()
+
+		result, err := inner.Query(Query{Identity: "c"})
+		require.NoError(t, err)
+		assert.Len(t, result, 2)
+
+		_, err = os.Stat(spillPath + ".replay")
+		assert.True(t, os.IsNotExist(err), "the replayed file is removed")
+	})
+
+	t.Run("spill max size", func(t *testing.T) {
+		spillPath := filepath.Join(dir, "full.jsonl")
+
+		w, err := NewWriter(&failingStore{StatsStore: inner, fail: true}, &WriterConfig{
+			SpillPath:    spillPath,
+			SpillMaxSize: 1,
+			Log:          zerolog.Nop(),
+		})
+		require.NoError(t, err)
+
+		w.spillOrDrop([]PeerStats{sample(base, 4, "d", 1)})
+		w.spillOrDrop([]PeerStats{sample(base, 4, "d", 2)})
+
+		stats := w.Stats()
+		assert.Equal(t, uint64(1), stats.SpilledBatches)
+		assert.Equal(t, uint64(1), stats.DroppedBatches, "the spill file is full")
+	})
+
+	t.Run("partial replay", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		target, err := NewJSONLinesStore(filepath.Join(dir, "stats.jsonl"))
+		require.NoError(t, err)
+
+		store := &failingStore{StatsStore: target, failAfter: 1}
+		spillPath := filepath.Join(dir, "spill.jsonl")
+
+		w, err := NewWriter(store, &WriterConfig{
+			MaxBatchSize:   1,
+			InitialBackoff: time.Hour,
+			SpillPath:      spillPath,
+			Log:            zerolog.Nop(),
+		})
+		require.NoError(t, err)
+
+		w.spillOrDrop([]PeerStats{sample(base, 5, "e", 1), sample(base, 5, "e", 2), sample(base, 5, "e", 3)})
+		w.replaySpill()
+
+		written, err := target.Query(Query{})
+		require.NoError(t, err)
+		require.Len(t, written, 1)
+		assert.Equal(t, uint64(1), written[0].ICEBytesSent)
+
+		// new batches are spilled while the replay is pending
+		w.spillOrDrop([]PeerStats{sample(base, 5, "e", 4)})
+
+		store.setFail(false)
+		store.failAfter = 0
+		w.replaySpill()
+		assert.Equal(t, 2, store.insertCount(), "the replay waits for the backoff")
+
+		w.replayAfter = time.Time{}
+		w.replaySpill()
+		w.replaySpill()
+
+		written, err = target.Query(Query{})
+		require.NoError(t, err)
+		assert.Len(t, written, 4, "every sample is written once")
+	})
+}
@@ -0,0 +1,202 @@
+package commserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	pion "github.com/pion/webrtc/v2"
+	"go.opentelemetry.io/otel/api/key"
+	"go.opentelemetry.io/otel/api/trace"
+	"google.golang.org/grpc/codes"
+
+	"github.com/decentraland/world/internal/commons/tracing"
+)
+
+// maxOpenForwardSpans bounds the spans waiting for their deliveries, in case every delivery is
+// dropped for a while
+const maxOpenForwardSpans = 64
+
+// ForwardTracerConfig is the forward tracer configuration
+type ForwardTracerConfig struct {
+	// SampleRatio is the fraction of the forwarded messages traced. A sampled message starts a
+	// new trace, so the tracing sample ratio applies on top of it.
+	SampleRatio float64
+}
+
+type forwardSpan struct {
+	m            *Message
+	span         trace.Span
+	recipients   int
+	lastDelivery time.Time
+}
+
+// ForwardTracer is a pipeline handler that traces a sample of the forwarded messages, from the
+// moment they are decoded to their last delivery. It has to be registered after the handlers that
+// drop messages, so it only counts the delivered ones.
+//
+// The pipeline doesn't know when the broker is done writing a message, so the spans are ended once
+// the next message arrives, with the time of their last delivery.
+type ForwardTracer struct {
+	sampleRatio float64
+
+	open       []*forwardSpan
+	delivering bool
+}
+
+// NewForwardTracer creates a ForwardTracer, it has to be registered in a Pipeline
+func NewForwardTracer(config *ForwardTracerConfig) *ForwardTracer {
+	return &ForwardTracer{sampleRatio: config.SampleRatio}
+}
+
+func (t *ForwardTracer) endSpans() {
+	for _, s := range t.open {
+		s.span.SetAttributes(key.Int("recipients", s.recipients))
+		s.span.End(trace.WithEndTime(s.lastDelivery))
+	}
+
+	t.open = t.open[:0]
+	t.delivering = false
+}
+
+// OnMessage ...
+func (t *ForwardTracer) OnMessage(m *Message) bool {
+	// NOTE: the messages of a client batch are all processed before their deliveries
+	if t.delivering || len(t.open) >= maxOpenForwardSpans {
+		t.endSpans()
+	}
+
+	if rand.Float64() >= t.sampleRatio {
+		return true
+	}
+
+	now := time.Now()
+
+	_, span := tracing.Tracer().Start(context.Background(), "forward",
+		trace.WithStartTime(now),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			key.String("topic", m.Topic),
+			key.String("category", m.Category.String()),
+			key.Uint64("from", m.FromAlias),
+			key.Bool("reliable", m.Reliable),
+			key.Int("bytes", len(m.Raw)),
+		),
+	)
+
+	t.open = append(t.open, &forwardSpan{m: m, span: span, lastDelivery: now})
+
+	return true
+}
+
+// OnDelivery ...
+func (t *ForwardTracer) OnDelivery(m *Message, to *Peer) bool {
+	t.delivering = true
+
+	for _, s := range t.open {
+		if s.m == m {
+			s.recipients++
+			s.lastDelivery = time.Now()
+		}
+	}
+
+	return true
+}
+
+type negotiationSpan struct {
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+}
+
+// NegotiationTracer traces the server side of the WebRTC negotiation of each peer, from the moment
+// the broker creates its data channels until its ICE connection is established. The broker doesn't
+// expose the negotiation, so the outcome is inferred from its stats: the span ends with the stats
+// that show the peer connected, or with an error if it failed or is gone. Its end time has the
+// resolution of the stats period.
+type NegotiationTracer struct {
+	mutex sync.Mutex
+	open  map[uint64]*negotiationSpan
+}
+
+// NewNegotiationTracer creates a NegotiationTracer, Track has to be called with every broker stats
+func NewNegotiationTracer() *NegotiationTracer {
+	return &NegotiationTracer{open: make(map[uint64]*negotiationSpan)}
+}
+
+// WrapWriterControllerFactory returns a broker.WriterControllerFactory that starts the negotiation
+// span of each peer before calling factory
+func (t *NegotiationTracer) WrapWriterControllerFactory(factory broker.WriterControllerFactory) broker.WriterControllerFactory {
+	return func(alias uint64, writer broker.PeerWriter) broker.WriterController {
+		t.start(time.Now(), alias)
+		return factory(alias, writer)
+	}
+}
+
+func (t *NegotiationTracer) start(now time.Time, alias uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// NOTE: the factories are called once per data channel
+	if _, ok := t.open[alias]; ok {
+		return
+	}
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "webrtc.negotiation",
+		trace.WithStartTime(now),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(key.Uint64("alias", alias)),
+	)
+
+	t.open[alias] = &negotiationSpan{ctx: ctx, span: span, start: now}
+}
+
+// Track ends the spans of the peers connected, failed or gone in the stats
+func (t *NegotiationTracer) Track(stats broker.Stats) {
+	now := stats.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for alias, s := range t.open {
+		var err error
+
+		peerStats, ok := stats.Peers[alias]
+		switch {
+		case !ok && !s.start.Before(now):
+			// NOTE: a peer created after the stats were taken is not in them yet
+			continue
+		case !ok:
+			err = errors.New("peer closed during the negotiation")
+		case peerStats.State == pion.ICEConnectionStateConnected || peerStats.State == pion.ICEConnectionStateCompleted:
+		case peerStats.State == pion.ICEConnectionStateFailed || peerStats.State == pion.ICEConnectionStateClosed:
+			err = fmt.Errorf("ice connection %s", peerStats.State)
+		default:
+			continue
+		}
+
+		delete(t.open, alias)
+
+		if ok {
+			s.span.SetAttributes(
+				key.String("ice.state", peerStats.State.String()),
+				key.Bool("ice.nomination", peerStats.Nomination),
+				key.String("ice.local_candidate", peerStats.LocalCandidateType.String()),
+				key.String("ice.remote_candidate", peerStats.RemoteCandidateType.String()),
+			)
+		}
+
+		if err != nil {
+			s.span.RecordError(s.ctx, err, trace.WithErrorStatus(codes.Unknown))
+		}
+
+		s.span.End(trace.WithEndTime(now))
+	}
+}
@@ -0,0 +1,134 @@
+package commserver
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decentraland/webrtc-broker/pkg/broker"
+	pion "github.com/pion/webrtc/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/api/global"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+)
+
+type spanRecorder struct {
+	mutex sync.Mutex
+	spans []*export.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(ctx context.Context, span *export.SpanData) {
+	r.mutex.Lock()
+	r.spans = append(r.spans, span)
+	r.mutex.Unlock()
+}
+
+func TestForwardTracer(t *testing.T) {
+	recorder := &spanRecorder{}
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSyncer(recorder),
+	)
+	require.NoError(t, err)
+
+	defer global.SetTraceProvider(global.TraceProvider())
+	global.SetTraceProvider(provider)
+
+	p := NewPipeline(&PipelineConfig{Log: zerolog.Nop()})
+	p.Use(&dropHandler{})
+	tracer := NewForwardTracer(&ForwardTracerConfig{SampleRatio: 1})
+	p.Use(tracer)
+
+	peer2 := newTestPeer(p, 2)
+	peer3 := newTestPeer(p, 3)
+	peer4 := newTestPeer(p, 4)
+
+	position := encodePosition(t, 1, 0, 0)
+	peer2.unreliableWriter.Write(position)
+	peer3.unreliableWriter.Write(position)
+	peer4.unreliableWriter.Write(position)
+
+	assert.Len(t, recorder.spans, 0, "the span waits for the next message")
+
+	peer2.reliableWriter.Write(encodeChat(t, 1, "1", "hi"))
+	assert.Len(t, recorder.spans, 0, "dropped messages are not traced")
+
+	peer2.unreliableWriter.Write(encodePosition(t, 1, 1, 1))
+	require.Len(t, recorder.spans, 1)
+
+	span := recorder.spans[0]
+	assert.Equal(t, "forward", span.Name)
+
+	attributes := map[string]interface{}{}
+	for _, kv := range span.Attributes {
+		attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+
+	assert.Equal(t, "POSITION", attributes["category"])
+	assert.Equal(t, uint64(1), attributes["from"])
+	assert.Equal(t, int64(2), attributes["recipients"], "the drop handler blocks the delivery to peer 3")
+}
+
+func TestNegotiationTracer(t *testing.T) {
+	recorder := &spanRecorder{}
+	provider, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSyncer(recorder),
+	)
+	require.NoError(t, err)
+
+	defer global.SetTraceProvider(global.TraceProvider())
+	global.SetTraceProvider(provider)
+
+	tracer := NewNegotiationTracer()
+	factory := func(alias uint64, writer broker.PeerWriter) broker.WriterController {
+		return nil
+	}
+	reliableFactory := tracer.WrapWriterControllerFactory(factory)
+	unreliableFactory := tracer.WrapWriterControllerFactory(factory)
+
+	for alias := uint64(1); alias <= 3; alias++ {
+		reliableFactory(alias, nil)
+		unreliableFactory(alias, nil)
+	}
+
+	now := time.Now().Add(time.Second)
+
+	tracer.Track(broker.Stats{Time: now, Peers: map[uint64]broker.PeerStats{
+		1: {State: pion.ICEConnectionStateChecking},
+		2: {State: pion.ICEConnectionStateChecking},
+		3: {State: pion.ICEConnectionStateChecking},
+	}})
+	assert.Len(t, recorder.spans, 0, "the negotiations are not done")
+
+	tracer.Track(broker.Stats{Time: now.Add(time.Second), Peers: map[uint64]broker.PeerStats{
+		1: {State: pion.ICEConnectionStateConnected, Nomination: true},
+		2: {State: pion.ICEConnectionStateFailed},
+	}})
+	require.Len(t, recorder.spans, 3, "one span per peer")
+
+	spans := map[uint64]*export.SpanData{}
+	for _, span := range recorder.spans {
+		assert.Equal(t, "webrtc.negotiation", span.Name)
+
+		for _, kv := range span.Attributes {
+			if kv.Key == "alias" {
+				spans[kv.Value.AsUint64()] = span
+			}
+		}
+	}
+
+	require.Len(t, spans, 3)
+	assert.Equal(t, codes.OK, spans[1].StatusCode)
+	assert.Equal(t, now.Add(time.Second), spans[1].EndTime, "the span ends with the stats")
+	assert.Equal(t, codes.Unknown, spans[2].StatusCode, "the ice connection failed")
+	assert.Equal(t, codes.Unknown, spans[3].StatusCode, "the peer is gone")
+
+	tracer.Track(broker.Stats{Time: now.Add(2 * time.Second)})
+	assert.Len(t, recorder.spans, 3, "the spans end once")
+}
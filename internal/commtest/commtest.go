@@ -1,16 +1,17 @@
 package commtest
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 
 	brokerAuth "github.com/decentraland/webrtc-broker/pkg/authentication"
-	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
 	"github.com/decentraland/webrtc-broker/pkg/simulation"
 	"github.com/decentraland/world/internal/cli"
 	"github.com/decentraland/world/pkg/protocol"
-	"github.com/golang/protobuf/proto"
 
 	pion "github.com/pion/webrtc/v2"
 )
@@ -23,7 +24,10 @@ type Options struct {
 	Log            zerolog.Logger
 }
 
-func StartBot(opts Options) {
+// StartBot subscribes to the given topics and, if a topic is set, sends a position message to it
+// every 100ms. It runs until ctx is done, then it stops the simulation client and, if TrackStats is
+// enabled, reports the final stats.
+func StartBot(ctx context.Context, opts Options) error {
 	log := opts.Log
 	config := simulation.Config{
 		Auth:           &brokerAuth.NoopAuthenticator{},
@@ -36,86 +40,54 @@ func StartBot(opts Options) {
 		Log: log,
 	}
 
-	if opts.TrackStats {
-		trackCh := make(chan []byte, 256)
-		config.OnMessageReceived = func(reliable bool, msgType broker.MessageType, raw []byte) {
-			if !reliable && msgType == broker.MessageType_TOPIC_FW {
-				trackCh <- raw
-			}
-		}
-
-		go func() {
-			peers := make(map[uint64]*simulation.Stats)
-			topicFwMessage := broker.TopicFWMessage{}
-			dataHeader := protocol.DataHeader{}
-
-			onMessage := func(rawMsg []byte) {
-				if err := proto.Unmarshal(rawMsg, &topicFwMessage); err != nil {
-					log.Error().Err(err).Msg("error unmarshalling data message")
-					return
-				}
+	var wg sync.WaitGroup
+	defer wg.Wait()
 
-				if err := proto.Unmarshal(topicFwMessage.Body, &dataHeader); err != nil {
-					log.Error().Err(err).Msg("error unmarshalling data header")
-					return
-				}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-				if dataHeader.Category != protocol.Category_POSITION {
-					return
-				}
-
-				alias := topicFwMessage.FromAlias
-				stats := peers[alias]
+	if opts.TrackStats {
+		trackCh, onMessageReceived := cli.NewTrackChannel(ctx)
+		config.OnMessageReceived = onMessageReceived
 
-				if stats == nil {
-					stats = &simulation.Stats{}
-					peers[alias] = stats
-				}
+		wg.Add(1)
 
-				stats.Seen(time.Now())
-			}
+		go func() {
+			defer wg.Done()
 
-			reportTicker := time.NewTicker(30 * time.Second)
-			defer reportTicker.Stop()
-
-			for {
-				select {
-				case rawMsg := <-trackCh:
-					onMessage(rawMsg)
-
-					n := len(trackCh)
-					for i := 0; i < n; i++ {
-						rawMsg = <-trackCh
-						onMessage(rawMsg)
-					}
-				case <-reportTicker.C:
-					for alias, stats := range peers {
-						log.Info().Msgf("%d: %f ms (%d messages)", alias, stats.Avg(), stats.Samples())
-
-						if time.Since(stats.LastSeen).Seconds() > 1 {
-							delete(peers, alias)
-						}
-					}
+			cli.TrackPositionStats(ctx, log, trackCh, 30*time.Second, func(peers map[uint64]*simulation.Stats) {
+				for alias, stats := range peers {
+					log.Info().Msgf("%d: %f ms (%d messages)", alias, stats.Avg(), stats.Samples())
 				}
-
-			}
+			})
 		}()
 	}
 
 	client := simulation.Start(&config)
-	client.SendTopicSubscriptionMessage(opts.Subscription)
+	defer cli.StopClient(client)
+
+	if err := client.SendTopicSubscriptionMessage(opts.Subscription); err != nil {
+		return fmt.Errorf("subscription failed: %v", err)
+	}
+
+	if opts.Topic == "" {
+		<-ctx.Done()
+		return nil
+	}
 
-	if opts.Topic != "" {
-		positionTicker := time.NewTicker(100 * time.Millisecond)
-		defer positionTicker.Stop()
+	positionTicker := time.NewTicker(100 * time.Millisecond)
+	defer positionTicker.Stop()
 
-		for {
-			<-positionTicker.C
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-positionTicker.C:
 			bytes, err := cli.EncodeTopicMessage(opts.Topic, &protocol.PositionData{
 				Category: protocol.Category_POSITION,
 			})
 			if err != nil {
-				log.Fatal().Err(err).Msg("encode position failed")
+				return fmt.Errorf("encode position failed: %v", err)
 			}
 
 			client.SendUnreliable <- bytes
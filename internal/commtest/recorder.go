@@ -0,0 +1,359 @@
+package commtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog"
+
+	brokerAuth "github.com/decentraland/webrtc-broker/pkg/authentication"
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/webrtc-broker/pkg/simulation"
+	"github.com/decentraland/world/internal/cli"
+	"github.com/decentraland/world/pkg/protocol"
+
+	pion "github.com/pion/webrtc/v2"
+)
+
+// RecorderOptions is the recorder configuration
+type RecorderOptions struct {
+	CoordinatorURL string
+	Subscription   map[string]bool
+	Output         io.Writer
+	Log            zerolog.Logger
+}
+
+// StartRecorder subscribes to the given topics and writes every topic message it receives to
+// opts.Output until ctx is done.
+//
+// NOTE: forwarded messages don't carry their topic, so it's inferred from the cell of the last
+// position of the sender, compact or not. Messages received before the first position of a peer, or
+// before the first compact position keyframe, are recorded with no topic and skipped on replay,
+// unless there is a single topic subscribed
+func StartRecorder(ctx context.Context, opts RecorderOptions) error {
+	log := opts.Log
+
+	writer, err := NewRecordWriter(opts.Output)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	recordCh := make(chan *Record, 256)
+
+	config := simulation.Config{
+		Auth:           &brokerAuth.NoopAuthenticator{},
+		CoordinatorURL: opts.CoordinatorURL,
+		ICEServers: []pion.ICEServer{
+			{
+				URLs: []string{"stun:stun.l.google.com:19302"},
+			},
+		},
+		Log: log,
+		OnMessageReceived: cli.UnbatchMessages(func(reliable bool, msgType broker.MessageType, raw []byte) {
+			record := &Record{Time: time.Since(start), Reliable: reliable}
+
+			switch msgType {
+			case broker.MessageType_TOPIC_FW:
+				message := broker.TopicFWMessage{}
+				if err := proto.Unmarshal(raw, &message); err != nil {
+					log.Error().Err(err).Msg("error unmarshalling topic fw message")
+					return
+				}
+
+				record.Alias = message.FromAlias
+				record.Body = message.Body
+			case broker.MessageType_TOPIC_IDENTITY_FW:
+				message := broker.TopicIdentityFWMessage{}
+				if err := proto.Unmarshal(raw, &message); err != nil {
+					log.Error().Err(err).Msg("error unmarshalling topic identity fw message")
+					return
+				}
+
+				record.Alias = message.FromAlias
+				record.Identity = true
+				record.Body = message.Body
+			default:
+				return
+			}
+
+			select {
+			case recordCh <- record:
+			case <-ctx.Done():
+			}
+		}),
+	}
+
+	client := simulation.Start(&config)
+	defer cli.StopClient(client)
+
+	if err := client.SendTopicSubscriptionMessage(opts.Subscription); err != nil {
+		return fmt.Errorf("subscription failed: %v", err)
+	}
+
+	topics := newTopicTracker(opts.Subscription)
+	count := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Int("records", count).Msg("recording finished")
+			return writer.Flush()
+		case record := <-recordCh:
+			if err := topics.track(record); err != nil {
+				log.Error().Err(err).Uint64("alias", record.Alias).Msg("cannot infer the record topic")
+			}
+
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("cannot write record: %v", err)
+			}
+
+			count++
+		}
+	}
+}
+
+// topicTracker sets the topic of the recorded messages, the cell of the last position of their
+// sender, either a POSITION or a COMPACT_POSITION
+type topicTracker struct {
+	singleTopic string
+	peerTopics  map[uint64]string
+	decoders    map[uint64]*protocol.PositionDecoder
+}
+
+func newTopicTracker(subscription map[string]bool) *topicTracker {
+	t := &topicTracker{
+		peerTopics: make(map[uint64]string),
+		decoders:   make(map[uint64]*protocol.PositionDecoder),
+	}
+
+	if len(subscription) == 1 {
+		for topic := range subscription {
+			t.singleTopic = topic
+		}
+	}
+
+	return t
+}
+
+// track sets the topic of record, it's still set if the record is not a valid position
+func (t *topicTracker) track(record *Record) error {
+	err := t.trackPosition(record)
+
+	if t.singleTopic != "" {
+		record.Topic = t.singleTopic
+	} else {
+		record.Topic = t.peerTopics[record.Alias]
+	}
+
+	return err
+}
+
+func (t *topicTracker) trackPosition(record *Record) error {
+	dataHeader := protocol.DataHeader{}
+	if err := proto.Unmarshal(record.Body, &dataHeader); err != nil {
+		return err
+	}
+
+	var position *protocol.PositionData
+
+	switch dataHeader.Category {
+	case protocol.Category_POSITION:
+		position = &protocol.PositionData{}
+		if err := proto.Unmarshal(record.Body, position); err != nil {
+			return err
+		}
+	case protocol.Category_COMPACT_POSITION:
+		compact := protocol.CompactPositionData{}
+		if err := proto.Unmarshal(record.Body, &compact); err != nil {
+			return err
+		}
+
+		decoder := t.decoders[record.Alias]
+		if decoder == nil {
+			decoder = protocol.NewPositionDecoder()
+			t.decoders[record.Alias] = decoder
+		}
+
+		var err error
+		if position, err = decoder.Decode(&compact); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	p := cli.V3{X: float64(position.PositionX), Z: float64(position.PositionZ)}
+	t.peerTopics[record.Alias] = cli.CellTopic(p)
+
+	return nil
+}
+
+// ReplayOptions is the replayer configuration
+type ReplayOptions struct {
+	CoordinatorURL string
+	Input          io.Reader
+
+	// Speed scales the recorded timing, 2 replays twice as fast
+	Speed float64
+	Log   zerolog.Logger
+}
+
+// Replay starts a simulation client for every peer in the recording and sends its messages with
+// the recorded timing, scaled by opts.Speed. It returns once the recording ends or ctx is done.
+func Replay(ctx context.Context, opts ReplayOptions) error {
+	log := opts.Log
+
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	reader, err := NewRecordReader(opts.Input)
+	if err != nil {
+		return err
+	}
+
+	records := []*Record{}
+	aliases := make(map[uint64]bool)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("cannot read record: %v", err)
+		}
+
+		records = append(records, record)
+		aliases[record.Alias] = true
+	}
+
+	log.Info().Int("records", len(records)).Int("peers", len(aliases)).Msg("starting replay")
+
+	var clientsMux sync.Mutex
+	clients := make(map[uint64]*simulation.Client, len(aliases))
+
+	defer func() {
+		for _, client := range clients {
+			cli.StopClient(client)
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for alias := range aliases {
+		wg.Add(1)
+
+		go func(alias uint64) {
+			defer wg.Done()
+
+			config := simulation.Config{
+				Auth:           &brokerAuth.NoopAuthenticator{},
+				CoordinatorURL: opts.CoordinatorURL,
+				ICEServers: []pion.ICEServer{
+					{
+						URLs: []string{"stun:stun.l.google.com:19302"},
+					},
+				},
+				Log: log.With().Uint64("recordedAlias", alias).Logger(),
+			}
+
+			client := simulation.Start(&config)
+
+			clientsMux.Lock()
+			clients[alias] = client
+			clientsMux.Unlock()
+		}(alias)
+	}
+
+	wg.Wait()
+
+	sent, skipped, err := replayRecords(ctx, records, speed, func(record *Record, raw []byte) bool {
+		client := clients[record.Alias]
+		queue := client.SendUnreliable
+		if record.Reliable {
+			queue = client.SendReliable
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case queue <- raw:
+			return true
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		log.Info().Int("sent", sent).Int("skipped", skipped).Msg("replay cancelled")
+		return nil
+	}
+
+	log.Info().Int("sent", sent).Int("skipped", skipped).Msg("replay finished")
+
+	return nil
+}
+
+// replayRecords sends the records with a topic through send, with the recorded timing scaled by
+// speed. It returns once every record is sent, ctx is done or send returns false.
+func replayRecords(ctx context.Context, records []*Record, speed float64,
+	send func(record *Record, raw []byte) bool) (sent int, skipped int, err error) {
+	start := time.Now()
+
+	for _, record := range records {
+		wait := time.Until(start.Add(time.Duration(float64(record.Time) / speed)))
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return sent, skipped, nil
+			case <-time.After(wait):
+			}
+		}
+
+		if record.Topic == "" {
+			skipped++
+			continue
+		}
+
+		raw, err := encodeRecord(record)
+		if err != nil {
+			return sent, skipped, fmt.Errorf("encode record failed: %v", err)
+		}
+
+		if !send(record, raw) {
+			return sent, skipped, nil
+		}
+
+		sent++
+	}
+
+	return sent, skipped, nil
+}
+
+func encodeRecord(record *Record) ([]byte, error) {
+	if record.Identity {
+		return proto.Marshal(&broker.TopicIdentityMessage{
+			Type:  broker.MessageType_TOPIC_IDENTITY,
+			Topic: record.Topic,
+			Body:  record.Body,
+		})
+	}
+
+	return proto.Marshal(&broker.TopicMessage{
+		Type:  broker.MessageType_TOPIC,
+		Topic: record.Topic,
+		Body:  record.Body,
+	})
+}
@@ -0,0 +1,70 @@
+package commtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	broker "github.com/decentraland/webrtc-broker/pkg/protocol"
+	"github.com/decentraland/world/internal/cli"
+	"github.com/decentraland/world/pkg/protocol"
+)
+
+func TestReplayCompactPositions(t *testing.T) {
+	encoder := protocol.NewPositionEncoder(10)
+	records := []*Record{}
+
+	addRecord := func(alias uint64, data proto.Message) {
+		body, err := proto.Marshal(data)
+		require.NoError(t, err)
+		records = append(records, &Record{Time: time.Duration(len(records)) * time.Millisecond, Alias: alias, Body: body})
+	}
+
+	for i := 0; i < 5; i++ {
+		addRecord(1, encoder.Encode(&protocol.PositionData{PositionX: 100 + float32(i), PositionZ: 100}))
+	}
+	addRecord(1, &protocol.ChatData{Category: protocol.Category_CHAT, MessageId: "1", Text: "hi"})
+
+	// NOTE: a delta whose keyframe wasn't recorded
+	lateEncoder := protocol.NewPositionEncoder(10)
+	lateEncoder.Encode(&protocol.PositionData{PositionX: 200, PositionZ: 200})
+	addRecord(2, lateEncoder.Encode(&protocol.PositionData{PositionX: 201, PositionZ: 200}))
+
+	topics := newTopicTracker(map[string]bool{"a": true, "b": true})
+	for i, record := range records {
+		err := topics.track(record)
+		if record.Alias == 2 {
+			assert.Equal(t, protocol.ErrUnknownBaseline, err)
+			continue
+		}
+
+		require.NoError(t, err, "record %d", i)
+		assert.Equal(t, cli.CellTopic(cli.V3{X: 100, Z: 100}), record.Topic, "record %d", i)
+	}
+
+	replayed := []*broker.TopicMessage{}
+	sent, skipped, err := replayRecords(context.Background(), records, 100, func(record *Record, raw []byte) bool {
+		message := &broker.TopicMessage{}
+		require.NoError(t, proto.Unmarshal(raw, message))
+		replayed = append(replayed, message)
+		return true
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, sent, "the compact positions and the messages after them are replayed")
+	assert.Equal(t, 1, skipped, "the records with no topic are skipped")
+
+	decoder := protocol.NewPositionDecoder()
+	for i, message := range replayed[:5] {
+		compact := &protocol.CompactPositionData{}
+		require.NoError(t, proto.Unmarshal(message.Body, compact))
+
+		position, err := decoder.Decode(compact)
+		require.NoError(t, err)
+		assert.InDelta(t, 100+float64(i), position.PositionX, 0.1)
+	}
+}
@@ -0,0 +1,191 @@
+package commtest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	recordingMagic   = "DCLREC"
+	recordingVersion = 1
+
+	recordFlagReliable = 1 << 0
+	recordFlagIdentity = 1 << 1
+
+	maxRecordFieldSize = 1024 * 1024
+)
+
+// Record is a topic message as seen by a client
+type Record struct {
+	// Time is the time elapsed since the start of the recording
+	Time     time.Duration
+	Alias    uint64
+	Reliable bool
+	Identity bool
+	Topic    string
+	Body     []byte
+}
+
+// RecordWriter writes records in the recording format: a magic and version header followed by the
+// records, each one encoded as uvarint time (microseconds), uvarint alias, a flags byte, and the
+// topic and body as uvarint length prefixed bytes
+type RecordWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+// NewRecordWriter writes the recording header to w and returns a writer for the records
+func NewRecordWriter(w io.Writer) (*RecordWriter, error) {
+	rw := &RecordWriter{w: bufio.NewWriter(w)}
+
+	if _, err := rw.w.WriteString(recordingMagic); err != nil {
+		return nil, err
+	}
+
+	if err := rw.w.WriteByte(recordingVersion); err != nil {
+		return nil, err
+	}
+
+	return rw, nil
+}
+
+// Write writes a record, records has to be written in time order
+func (rw *RecordWriter) Write(r *Record) error {
+	var flags byte
+	if r.Reliable {
+		flags |= recordFlagReliable
+	}
+
+	if r.Identity {
+		flags |= recordFlagIdentity
+	}
+
+	if err := rw.writeUvarint(uint64(r.Time / time.Microsecond)); err != nil {
+		return err
+	}
+
+	if err := rw.writeUvarint(r.Alias); err != nil {
+		return err
+	}
+
+	if err := rw.w.WriteByte(flags); err != nil {
+		return err
+	}
+
+	if err := rw.writeBytes([]byte(r.Topic)); err != nil {
+		return err
+	}
+
+	return rw.writeBytes(r.Body)
+}
+
+// Flush writes any buffered record to the underlying writer
+func (rw *RecordWriter) Flush() error {
+	return rw.w.Flush()
+}
+
+func (rw *RecordWriter) writeUvarint(v uint64) error {
+	n := binary.PutUvarint(rw.buf[:], v)
+	_, err := rw.w.Write(rw.buf[:n])
+	return err
+}
+
+func (rw *RecordWriter) writeBytes(b []byte) error {
+	if err := rw.writeUvarint(uint64(len(b))); err != nil {
+		return err
+	}
+
+	_, err := rw.w.Write(b)
+
+	return err
+}
+
+// RecordReader reads records written by a RecordWriter
+type RecordReader struct {
+	r *bufio.Reader
+}
+
+// NewRecordReader validates the recording header and returns a reader for the records
+func NewRecordReader(r io.Reader) (*RecordReader, error) {
+	rr := &RecordReader{r: bufio.NewReader(r)}
+
+	header := make([]byte, len(recordingMagic)+1)
+	if _, err := io.ReadFull(rr.r, header); err != nil {
+		return nil, fmt.Errorf("cannot read recording header: %v", err)
+	}
+
+	if string(header[:len(recordingMagic)]) != recordingMagic {
+		return nil, errors.New("invalid recording, magic mismatch")
+	}
+
+	if version := header[len(recordingMagic)]; version != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", version)
+	}
+
+	return rr, nil
+}
+
+// Read returns the next record, or io.EOF when there are no more records
+func (rr *RecordReader) Read() (*Record, error) {
+	t, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Record{Time: time.Duration(t) * time.Microsecond}
+
+	if r.Alias, err = binary.ReadUvarint(rr.r); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	flags, err := rr.r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	r.Reliable = flags&recordFlagReliable != 0
+	r.Identity = flags&recordFlagIdentity != 0
+
+	topic, err := rr.readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Topic = string(topic)
+
+	if r.Body, err = rr.readBytes(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (rr *RecordReader) readBytes() ([]byte, error) {
+	size, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	if size > maxRecordFieldSize {
+		return nil, fmt.Errorf("invalid record, field too big (%d bytes)", size)
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(rr.r, b); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	return b, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package commtest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingRoundTrip(t *testing.T) {
+	records := []*Record{
+		{Time: 0, Alias: 1, Topic: "37:37", Body: []byte{1, 2, 3}},
+		{Time: 100 * time.Millisecond, Alias: 300, Reliable: true, Identity: true, Topic: "38:37", Body: []byte{4}},
+		{Time: 2 * time.Second, Alias: 1, Reliable: true, Body: []byte{}},
+	}
+
+	buffer := bytes.Buffer{}
+
+	writer, err := NewRecordWriter(&buffer)
+	require.NoError(t, err)
+
+	for _, r := range records {
+		require.NoError(t, writer.Write(r))
+	}
+
+	require.NoError(t, writer.Flush())
+
+	reader, err := NewRecordReader(&buffer)
+	require.NoError(t, err)
+
+	for _, expected := range records {
+		r, err := reader.Read()
+		require.NoError(t, err)
+		assert.Equal(t, expected, r)
+	}
+
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRecordingInvalidInput(t *testing.T) {
+	_, err := NewRecordReader(bytes.NewReader([]byte("NOTREC1")))
+	assert.Error(t, err)
+
+	buffer := bytes.Buffer{}
+	writer, err := NewRecordWriter(&buffer)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(&Record{Alias: 1, Topic: "topic", Body: []byte{1, 2, 3}}))
+	require.NoError(t, writer.Flush())
+
+	truncated := buffer.Bytes()[:buffer.Len()-1]
+	reader, err := NewRecordReader(bytes.NewReader(truncated))
+	require.NoError(t, err)
+
+	_, err = reader.Read()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
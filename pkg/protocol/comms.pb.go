@@ -24,11 +24,16 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type Category int32
 
 const (
-	Category_UNKNOWN       Category = 0
-	Category_POSITION      Category = 1
-	Category_PROFILE       Category = 2
-	Category_CHAT          Category = 3
-	Category_SCENE_MESSAGE Category = 4
+	Category_UNKNOWN            Category = 0
+	Category_POSITION           Category = 1
+	Category_PROFILE            Category = 2
+	Category_CHAT               Category = 3
+	Category_SCENE_MESSAGE      Category = 4
+	Category_DIRECT_MESSAGE     Category = 5
+	Category_DIRECT_MESSAGE_ACK Category = 6
+	Category_COMPACT_POSITION   Category = 7
+	Category_BATCH              Category = 8
+	Category_FORWARDED_BATCH    Category = 9
 )
 
 var Category_name = map[int32]string{
@@ -37,14 +42,24 @@ var Category_name = map[int32]string{
 	2: "PROFILE",
 	3: "CHAT",
 	4: "SCENE_MESSAGE",
+	5: "DIRECT_MESSAGE",
+	6: "DIRECT_MESSAGE_ACK",
+	7: "COMPACT_POSITION",
+	8: "BATCH",
+	9: "FORWARDED_BATCH",
 }
 
 var Category_value = map[string]int32{
-	"UNKNOWN":       0,
-	"POSITION":      1,
-	"PROFILE":       2,
-	"CHAT":          3,
-	"SCENE_MESSAGE": 4,
+	"UNKNOWN":            0,
+	"POSITION":           1,
+	"PROFILE":            2,
+	"CHAT":               3,
+	"SCENE_MESSAGE":      4,
+	"DIRECT_MESSAGE":     5,
+	"DIRECT_MESSAGE_ACK": 6,
+	"COMPACT_POSITION":   7,
+	"BATCH":              8,
+	"FORWARDED_BATCH":    9,
 }
 
 func (x Category) String() string {
@@ -260,10 +275,123 @@ func (m *PositionData) GetRotationW() float32 {
 	return 0
 }
 
+type CompactPositionData struct {
+	Category             Category `protobuf:"varint,1,opt,name=category,proto3,enum=protocol.Category" json:"category,omitempty"`
+	Sequence             uint32   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Baseline             uint32   `protobuf:"varint,3,opt,name=baseline,proto3" json:"baseline,omitempty"`
+	Time                 int64    `protobuf:"zigzag64,4,opt,name=time,proto3" json:"time,omitempty"`
+	CellX                int32    `protobuf:"zigzag32,5,opt,name=cell_x,json=cellX,proto3" json:"cell_x,omitempty"`
+	CellZ                int32    `protobuf:"zigzag32,6,opt,name=cell_z,json=cellZ,proto3" json:"cell_z,omitempty"`
+	PositionX            int32    `protobuf:"zigzag32,7,opt,name=position_x,json=positionX,proto3" json:"position_x,omitempty"`
+	PositionY            int32    `protobuf:"zigzag32,8,opt,name=position_y,json=positionY,proto3" json:"position_y,omitempty"`
+	PositionZ            int32    `protobuf:"zigzag32,9,opt,name=position_z,json=positionZ,proto3" json:"position_z,omitempty"`
+	Rotation             uint32   `protobuf:"varint,10,opt,name=rotation,proto3" json:"rotation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompactPositionData) Reset()         { *m = CompactPositionData{} }
+func (m *CompactPositionData) String() string { return proto.CompactTextString(m) }
+func (*CompactPositionData) ProtoMessage()    {}
+func (*CompactPositionData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_db39efb7717b7d47, []int{3}
+}
+
+func (m *CompactPositionData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactPositionData.Unmarshal(m, b)
+}
+func (m *CompactPositionData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactPositionData.Marshal(b, m, deterministic)
+}
+func (m *CompactPositionData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactPositionData.Merge(m, src)
+}
+func (m *CompactPositionData) XXX_Size() int {
+	return xxx_messageInfo_CompactPositionData.Size(m)
+}
+func (m *CompactPositionData) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactPositionData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactPositionData proto.InternalMessageInfo
+
+func (m *CompactPositionData) GetCategory() Category {
+	if m != nil {
+		return m.Category
+	}
+	return Category_UNKNOWN
+}
+
+func (m *CompactPositionData) GetSequence() uint32 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetBaseline() uint32 {
+	if m != nil {
+		return m.Baseline
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetCellX() int32 {
+	if m != nil {
+		return m.CellX
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetCellZ() int32 {
+	if m != nil {
+		return m.CellZ
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetPositionX() int32 {
+	if m != nil {
+		return m.PositionX
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetPositionY() int32 {
+	if m != nil {
+		return m.PositionY
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetPositionZ() int32 {
+	if m != nil {
+		return m.PositionZ
+	}
+	return 0
+}
+
+func (m *CompactPositionData) GetRotation() uint32 {
+	if m != nil {
+		return m.Rotation
+	}
+	return 0
+}
+
 type ProfileData struct {
 	Category             Category `protobuf:"varint,1,opt,name=category,proto3,enum=protocol.Category" json:"category,omitempty"`
 	Time                 float64  `protobuf:"fixed64,2,opt,name=time,proto3" json:"time,omitempty"`
 	ProfileVersion       string   `protobuf:"bytes,3,opt,name=profile_version,json=profileVersion,proto3" json:"profile_version,omitempty"`
+	Hash                 string   `protobuf:"bytes,4,opt,name=hash,proto3" json:"hash,omitempty"`
+	SnapshotUrl          string   `protobuf:"bytes,5,opt,name=snapshot_url,json=snapshotUrl,proto3" json:"snapshot_url,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -273,7 +401,7 @@ func (m *ProfileData) Reset()         { *m = ProfileData{} }
 func (m *ProfileData) String() string { return proto.CompactTextString(m) }
 func (*ProfileData) ProtoMessage()    {}
 func (*ProfileData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_db39efb7717b7d47, []int{3}
+	return fileDescriptor_db39efb7717b7d47, []int{4}
 }
 
 func (m *ProfileData) XXX_Unmarshal(b []byte) error {
@@ -315,6 +443,20 @@ func (m *ProfileData) GetProfileVersion() string {
 	return ""
 }
 
+func (m *ProfileData) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *ProfileData) GetSnapshotUrl() string {
+	if m != nil {
+		return m.SnapshotUrl
+	}
+	return ""
+}
+
 type ChatData struct {
 	Category             Category `protobuf:"varint,1,opt,name=category,proto3,enum=protocol.Category" json:"category,omitempty"`
 	Time                 float64  `protobuf:"fixed64,2,opt,name=time,proto3" json:"time,omitempty"`
@@ -329,7 +471,7 @@ func (m *ChatData) Reset()         { *m = ChatData{} }
 func (m *ChatData) String() string { return proto.CompactTextString(m) }
 func (*ChatData) ProtoMessage()    {}
 func (*ChatData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_db39efb7717b7d47, []int{4}
+	return fileDescriptor_db39efb7717b7d47, []int{5}
 }
 
 func (m *ChatData) XXX_Unmarshal(b []byte) error {
@@ -378,43 +520,243 @@ func (m *ChatData) GetText() string {
 	return ""
 }
 
+type DirectMessageData struct {
+	Category             Category `protobuf:"varint,1,opt,name=category,proto3,enum=protocol.Category" json:"category,omitempty"`
+	Time                 float64  `protobuf:"fixed64,2,opt,name=time,proto3" json:"time,omitempty"`
+	MessageId            string   `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Recipient            string   `protobuf:"bytes,4,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	Text                 string   `protobuf:"bytes,5,opt,name=text,proto3" json:"text,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DirectMessageData) Reset()         { *m = DirectMessageData{} }
+func (m *DirectMessageData) String() string { return proto.CompactTextString(m) }
+func (*DirectMessageData) ProtoMessage()    {}
+func (*DirectMessageData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_db39efb7717b7d47, []int{6}
+}
+
+func (m *DirectMessageData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DirectMessageData.Unmarshal(m, b)
+}
+func (m *DirectMessageData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DirectMessageData.Marshal(b, m, deterministic)
+}
+func (m *DirectMessageData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DirectMessageData.Merge(m, src)
+}
+func (m *DirectMessageData) XXX_Size() int {
+	return xxx_messageInfo_DirectMessageData.Size(m)
+}
+func (m *DirectMessageData) XXX_DiscardUnknown() {
+	xxx_messageInfo_DirectMessageData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DirectMessageData proto.InternalMessageInfo
+
+func (m *DirectMessageData) GetCategory() Category {
+	if m != nil {
+		return m.Category
+	}
+	return Category_UNKNOWN
+}
+
+func (m *DirectMessageData) GetTime() float64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *DirectMessageData) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *DirectMessageData) GetRecipient() string {
+	if m != nil {
+		return m.Recipient
+	}
+	return ""
+}
+
+func (m *DirectMessageData) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+type DirectMessageAckData struct {
+	Category             Category `protobuf:"varint,1,opt,name=category,proto3,enum=protocol.Category" json:"category,omitempty"`
+	Time                 float64  `protobuf:"fixed64,2,opt,name=time,proto3" json:"time,omitempty"`
+	MessageId            string   `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Recipient            string   `protobuf:"bytes,4,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DirectMessageAckData) Reset()         { *m = DirectMessageAckData{} }
+func (m *DirectMessageAckData) String() string { return proto.CompactTextString(m) }
+func (*DirectMessageAckData) ProtoMessage()    {}
+func (*DirectMessageAckData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_db39efb7717b7d47, []int{7}
+}
+
+func (m *DirectMessageAckData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DirectMessageAckData.Unmarshal(m, b)
+}
+func (m *DirectMessageAckData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DirectMessageAckData.Marshal(b, m, deterministic)
+}
+func (m *DirectMessageAckData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DirectMessageAckData.Merge(m, src)
+}
+func (m *DirectMessageAckData) XXX_Size() int {
+	return xxx_messageInfo_DirectMessageAckData.Size(m)
+}
+func (m *DirectMessageAckData) XXX_DiscardUnknown() {
+	xxx_messageInfo_DirectMessageAckData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DirectMessageAckData proto.InternalMessageInfo
+
+func (m *DirectMessageAckData) GetCategory() Category {
+	if m != nil {
+		return m.Category
+	}
+	return Category_UNKNOWN
+}
+
+func (m *DirectMessageAckData) GetTime() float64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *DirectMessageAckData) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *DirectMessageAckData) GetRecipient() string {
+	if m != nil {
+		return m.Recipient
+	}
+	return ""
+}
+
+type BatchData struct {
+	Category             Category `protobuf:"varint,1,opt,name=category,proto3,enum=protocol.Category" json:"category,omitempty"`
+	Messages             [][]byte `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchData) Reset()         { *m = BatchData{} }
+func (m *BatchData) String() string { return proto.CompactTextString(m) }
+func (*BatchData) ProtoMessage()    {}
+func (*BatchData) Descriptor() ([]byte, []int) {
+	return fileDescriptor_db39efb7717b7d47, []int{8}
+}
+
+func (m *BatchData) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchData.Unmarshal(m, b)
+}
+func (m *BatchData) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchData.Marshal(b, m, deterministic)
+}
+func (m *BatchData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchData.Merge(m, src)
+}
+func (m *BatchData) XXX_Size() int {
+	return xxx_messageInfo_BatchData.Size(m)
+}
+func (m *BatchData) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchData.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchData proto.InternalMessageInfo
+
+func (m *BatchData) GetCategory() Category {
+	if m != nil {
+		return m.Category
+	}
+	return Category_UNKNOWN
+}
+
+func (m *BatchData) GetMessages() [][]byte {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("protocol.Category", Category_name, Category_value)
 	proto.RegisterType((*AuthData)(nil), "protocol.AuthData")
 	proto.RegisterType((*DataHeader)(nil), "protocol.DataHeader")
 	proto.RegisterType((*PositionData)(nil), "protocol.PositionData")
+	proto.RegisterType((*CompactPositionData)(nil), "protocol.CompactPositionData")
 	proto.RegisterType((*ProfileData)(nil), "protocol.ProfileData")
 	proto.RegisterType((*ChatData)(nil), "protocol.ChatData")
+	proto.RegisterType((*DirectMessageData)(nil), "protocol.DirectMessageData")
+	proto.RegisterType((*DirectMessageAckData)(nil), "protocol.DirectMessageAckData")
+	proto.RegisterType((*BatchData)(nil), "protocol.BatchData")
 }
 
 func init() { proto.RegisterFile("comms.proto", fileDescriptor_db39efb7717b7d47) }
 
 var fileDescriptor_db39efb7717b7d47 = []byte{
-	// 406 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x92, 0x41, 0x6f, 0x94, 0x40,
-	0x14, 0xc7, 0x65, 0xbb, 0xb6, 0xc3, 0x63, 0xad, 0x38, 0xa7, 0x89, 0xd1, 0x44, 0xb9, 0x68, 0x3c,
-	0x70, 0xd0, 0xab, 0x97, 0x0d, 0xa2, 0x25, 0x2a, 0x10, 0x58, 0x6d, 0xeb, 0x85, 0x8c, 0x30, 0x6e,
-	0x27, 0x16, 0x86, 0x30, 0x53, 0x2d, 0x7b, 0xf3, 0xe0, 0xa7, 0xf1, 0x4b, 0x1a, 0x06, 0x58, 0x9d,
-	0x63, 0x93, 0x9e, 0x18, 0x7e, 0xbf, 0x79, 0xff, 0xbc, 0x07, 0x0f, 0x9c, 0x52, 0xd4, 0xb5, 0xf4,
-	0xdb, 0x4e, 0x28, 0x81, 0x91, 0x7e, 0x94, 0xe2, 0xd2, 0xfb, 0x6d, 0x01, 0x5a, 0x5f, 0xa9, 0x8b,
-	0x37, 0x54, 0x51, 0xfc, 0x08, 0x6c, 0xc9, 0xb7, 0x0d, 0x55, 0x57, 0x1d, 0x23, 0xd6, 0x13, 0xeb,
-	0xb9, 0x9d, 0xfd, 0x03, 0xf8, 0x21, 0x20, 0x5e, 0xb1, 0x46, 0x71, 0xd5, 0x93, 0x85, 0x96, 0xfb,
-	0xf7, 0xa1, 0x52, 0xf1, 0x9a, 0x49, 0x45, 0xeb, 0x96, 0x1c, 0x8c, 0x95, 0x7b, 0x80, 0x9f, 0xc2,
-	0x8a, 0x96, 0x25, 0x93, 0xb2, 0x50, 0xe2, 0x3b, 0x6b, 0xc8, 0x52, 0x5f, 0x70, 0x46, 0xb6, 0x19,
-	0x90, 0xf7, 0x1a, 0x60, 0x68, 0xe1, 0x84, 0xd1, 0x8a, 0x75, 0xd8, 0x07, 0x54, 0x52, 0xc5, 0xb6,
-	0xa2, 0xeb, 0x75, 0x1f, 0xc7, 0x2f, 0xb1, 0x3f, 0xb7, 0xec, 0x07, 0x93, 0xc9, 0xf6, 0x77, 0xbc,
-	0x3f, 0x0b, 0x58, 0xa5, 0x42, 0x72, 0xc5, 0x45, 0xa3, 0x27, 0xb9, 0x61, 0x00, 0xc6, 0xb0, 0x1c,
-	0xda, 0xd5, 0x73, 0x59, 0x99, 0x3e, 0xe3, 0xc7, 0x00, 0xed, 0x94, 0x59, 0x5c, 0xeb, 0xa1, 0x16,
-	0x99, 0x3d, 0x93, 0x33, 0x43, 0xf7, 0x7a, 0xa4, 0xff, 0xf4, 0xb9, 0xa1, 0x77, 0xe4, 0xae, 0xa9,
-	0xbf, 0x0c, 0xba, 0x13, 0x8a, 0x4e, 0xe1, 0x87, 0xa3, 0x9e, 0xc9, 0x99, 0xa1, 0x7b, 0x72, 0x64,
-	0xea, 0x73, 0x43, 0xef, 0x08, 0x32, 0xb5, 0x19, 0xfe, 0x93, 0xd8, 0xa6, 0x3e, 0xf5, 0x76, 0xe0,
-	0xa4, 0x9d, 0xf8, 0xc6, 0x2f, 0xd9, 0xad, 0x7d, 0xab, 0x67, 0x70, 0xbf, 0x1d, 0x23, 0x8b, 0x1f,
-	0xac, 0x93, 0x5c, 0x34, 0xd3, 0x16, 0x1c, 0x4f, 0xf8, 0xf3, 0x48, 0xbd, 0x5f, 0x16, 0xa0, 0xe0,
-	0x82, 0xaa, 0xdb, 0xfc, 0x4b, 0x35, 0x93, 0x92, 0x6e, 0x59, 0xc1, 0xab, 0x79, 0xf5, 0x26, 0x12,
-	0x55, 0xba, 0x84, 0x5d, 0xab, 0x69, 0xe5, 0xf4, 0xf9, 0x45, 0x02, 0x68, 0x0e, 0xc7, 0x0e, 0x1c,
-	0x7d, 0x8a, 0xdf, 0xc7, 0xc9, 0x69, 0xec, 0xde, 0xc1, 0x2b, 0x40, 0x69, 0x92, 0x47, 0x9b, 0x28,
-	0x89, 0x5d, 0x6b, 0x50, 0x69, 0x96, 0xbc, 0x8d, 0x3e, 0x84, 0xee, 0x02, 0x23, 0x58, 0x06, 0x27,
-	0xeb, 0x8d, 0x7b, 0x80, 0x1f, 0xc0, 0xbd, 0x3c, 0x08, 0xe3, 0xb0, 0xf8, 0x18, 0xe6, 0xf9, 0xfa,
-	0x5d, 0xe8, 0x2e, 0xbf, 0x1e, 0xea, 0xa6, 0x5f, 0xfd, 0x0d, 0x00, 0x00, 0xff, 0xff, 0x06, 0xda,
-	0xae, 0x32, 0x64, 0x03, 0x00, 0x00,
+	// 655 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x93, 0xcb, 0x6e, 0xd3, 0x4e,
+	0x14, 0xc6, 0xff, 0x4e, 0x73, 0xb1, 0x4f, 0xd2, 0xd6, 0x9d, 0xf6, 0x8f, 0xac, 0xaa, 0x48, 0xc5,
+	0x1b, 0x2a, 0x16, 0x59, 0xc0, 0x96, 0x8d, 0xeb, 0xa4, 0x34, 0x2a, 0x4d, 0xa2, 0x49, 0x4a, 0xda,
+	0x6e, 0xac, 0xa9, 0x33, 0x34, 0x56, 0x1d, 0xdb, 0x78, 0x26, 0xd0, 0x74, 0xc7, 0x82, 0x67, 0xe0,
+	0x15, 0x90, 0x58, 0x75, 0xc7, 0xe3, 0xa1, 0x19, 0x5f, 0xca, 0x54, 0x62, 0x01, 0x54, 0x88, 0x95,
+	0xe7, 0x7c, 0xbf, 0xb9, 0x7c, 0xf3, 0x8d, 0x0f, 0x34, 0xfd, 0x78, 0x3e, 0x67, 0xed, 0x24, 0x8d,
+	0x79, 0x8c, 0x74, 0xf9, 0xf1, 0xe3, 0xd0, 0xfe, 0xa4, 0x81, 0xee, 0x2c, 0xf8, 0xac, 0x43, 0x38,
+	0x41, 0x3b, 0x60, 0xb0, 0xe0, 0x32, 0x22, 0x7c, 0x91, 0x52, 0x4b, 0xdb, 0xd5, 0xf6, 0x0c, 0x7c,
+	0x27, 0xa0, 0x6d, 0xd0, 0x83, 0x29, 0x8d, 0x78, 0xc0, 0x97, 0x56, 0x45, 0xc2, 0xb2, 0x16, 0x2b,
+	0x79, 0x30, 0xa7, 0x8c, 0x93, 0x79, 0x62, 0xad, 0x64, 0x2b, 0x4b, 0x01, 0x3d, 0x81, 0x16, 0xf1,
+	0x7d, 0xca, 0x98, 0xc7, 0xe3, 0x2b, 0x1a, 0x59, 0x55, 0x39, 0xa1, 0x99, 0x69, 0x63, 0x21, 0xd9,
+	0x2f, 0x01, 0x84, 0x85, 0x43, 0x4a, 0xa6, 0x34, 0x45, 0x6d, 0xd0, 0x7d, 0xc2, 0xe9, 0x65, 0x9c,
+	0x2e, 0xa5, 0x8f, 0xb5, 0xe7, 0xa8, 0x5d, 0x58, 0x6e, 0xbb, 0x39, 0xc1, 0xe5, 0x1c, 0xfb, 0x6b,
+	0x05, 0x5a, 0xc3, 0x98, 0x05, 0x3c, 0x88, 0x23, 0x79, 0x93, 0x5f, 0xdc, 0x00, 0x21, 0xa8, 0x0a,
+	0xbb, 0xf2, 0x5e, 0x1a, 0x96, 0x63, 0xf4, 0x18, 0x20, 0xc9, 0xf7, 0xf4, 0xae, 0xe5, 0xa5, 0x2a,
+	0xd8, 0x28, 0x94, 0x53, 0x05, 0x2f, 0xad, 0xaa, 0x8a, 0xcf, 0x14, 0x7c, 0x63, 0xd5, 0x54, 0x7c,
+	0x2e, 0x70, 0x1a, 0x73, 0x92, 0x6f, 0x5e, 0xcf, 0x70, 0xa1, 0x9c, 0x2a, 0x78, 0x69, 0x35, 0x54,
+	0x7c, 0xa6, 0xe0, 0x1b, 0x4b, 0x57, 0xb1, 0xba, 0xf9, 0x07, 0xcb, 0x50, 0xf1, 0xc4, 0xfe, 0x56,
+	0x81, 0x4d, 0x37, 0x9e, 0x27, 0xc4, 0xe7, 0x7f, 0x14, 0xda, 0x36, 0xe8, 0x8c, 0xbe, 0x5b, 0xd0,
+	0xc8, 0xcf, 0x82, 0x5b, 0xc5, 0x65, 0x2d, 0xd8, 0x05, 0x61, 0x34, 0x0c, 0x22, 0x2a, 0xa3, 0x5b,
+	0xc5, 0x65, 0x5d, 0x86, 0x2d, 0x32, 0x43, 0x79, 0xd8, 0xff, 0x43, 0xdd, 0xa7, 0x61, 0xe8, 0x5d,
+	0xcb, 0xa8, 0x36, 0x70, 0x4d, 0x54, 0xa7, 0xa5, 0x7c, 0x63, 0xd5, 0xef, 0xe4, 0xf3, 0x7b, 0x4f,
+	0xd3, 0x90, 0xe8, 0xa7, 0x4f, 0xa3, 0xab, 0xf8, 0xfe, 0xd3, 0x18, 0x2a, 0x3e, 0x17, 0xd6, 0x8b,
+	0xac, 0x2c, 0xc8, 0xac, 0x17, 0xb5, 0x7d, 0xab, 0x41, 0x73, 0x98, 0xc6, 0x6f, 0x83, 0x90, 0x3e,
+	0xd8, 0x7f, 0xf6, 0x14, 0xd6, 0x93, 0x6c, 0x4b, 0xef, 0x3d, 0x4d, 0x99, 0x38, 0x36, 0xeb, 0xa0,
+	0xb5, 0x5c, 0x7e, 0x93, 0xa9, 0x62, 0xf1, 0x8c, 0xb0, 0x59, 0xde, 0x3e, 0x72, 0x2c, 0x5a, 0x8b,
+	0x45, 0x24, 0x61, 0xb3, 0x98, 0x7b, 0x8b, 0x34, 0x94, 0xe9, 0x19, 0xb8, 0x59, 0x68, 0x27, 0x69,
+	0x68, 0x7f, 0xd4, 0x40, 0x77, 0x67, 0x84, 0x3f, 0x64, 0x63, 0xcc, 0x29, 0x63, 0xe4, 0x92, 0x7a,
+	0xc1, 0xb4, 0xe8, 0xf6, 0x5c, 0xe9, 0x4d, 0xe5, 0x12, 0x7a, 0xcd, 0x0b, 0x9b, 0x62, 0x6c, 0x7f,
+	0xd1, 0x60, 0xa3, 0x13, 0xa4, 0xd4, 0xe7, 0xc7, 0xd9, 0xbc, 0xbf, 0x65, 0x66, 0x07, 0x8c, 0x94,
+	0xfa, 0x41, 0x12, 0xd0, 0xa8, 0x70, 0x74, 0x27, 0x94, 0x56, 0x6b, 0x3f, 0x58, 0xfd, 0xac, 0xc1,
+	0x96, 0x62, 0xd5, 0xf1, 0xaf, 0xfe, 0x09, 0xb7, 0xf6, 0x04, 0x8c, 0x7d, 0xc2, 0xfd, 0xd9, 0xef,
+	0x36, 0x6b, 0x7e, 0x0e, 0xb3, 0x2a, 0xbb, 0x2b, 0x7b, 0x2d, 0x5c, 0xd6, 0xcf, 0x6e, 0xc5, 0x1f,
+	0x52, 0x4c, 0x6c, 0x42, 0xe3, 0xa4, 0x7f, 0xd4, 0x1f, 0x4c, 0xfa, 0xe6, 0x7f, 0xa8, 0x05, 0xfa,
+	0x70, 0x30, 0xea, 0x8d, 0x7b, 0x83, 0xbe, 0xa9, 0x09, 0x34, 0xc4, 0x83, 0x83, 0xde, 0xeb, 0xae,
+	0x59, 0x41, 0x3a, 0x54, 0xdd, 0x43, 0x67, 0x6c, 0xae, 0xa0, 0x0d, 0x58, 0x1d, 0xb9, 0xdd, 0x7e,
+	0xd7, 0x3b, 0xee, 0x8e, 0x46, 0xce, 0xab, 0xae, 0x59, 0x45, 0x08, 0xd6, 0x3a, 0x3d, 0xdc, 0x75,
+	0xc7, 0xa5, 0x56, 0x43, 0x8f, 0x00, 0xa9, 0x9a, 0xe7, 0xb8, 0x47, 0x66, 0x1d, 0x6d, 0x81, 0xe9,
+	0x0e, 0x8e, 0x87, 0x8e, 0x3b, 0xf6, 0xca, 0xb3, 0x1a, 0xc8, 0x80, 0xda, 0xbe, 0x33, 0x76, 0x0f,
+	0x4d, 0x1d, 0x6d, 0xc2, 0xfa, 0xc1, 0x00, 0x4f, 0x1c, 0xdc, 0xe9, 0x76, 0xbc, 0x4c, 0x34, 0x2e,
+	0xea, 0xf2, 0xb2, 0x2f, 0xbe, 0x0f, 0x00, 0x57, 0xd3, 0xee, 0x56, 0xd8, 0x06, 0x00, 0x00,
 }
@@ -107,6 +107,62 @@ export namespace PositionData {
   }
 }
 
+export class CompactPositionData extends jspb.Message {
+  getCategory(): Category;
+  setCategory(value: Category): void;
+
+  getSequence(): number;
+  setSequence(value: number): void;
+
+  getBaseline(): number;
+  setBaseline(value: number): void;
+
+  getTime(): number;
+  setTime(value: number): void;
+
+  getCellX(): number;
+  setCellX(value: number): void;
+
+  getCellZ(): number;
+  setCellZ(value: number): void;
+
+  getPositionX(): number;
+  setPositionX(value: number): void;
+
+  getPositionY(): number;
+  setPositionY(value: number): void;
+
+  getPositionZ(): number;
+  setPositionZ(value: number): void;
+
+  getRotation(): number;
+  setRotation(value: number): void;
+
+  serializeBinary(): Uint8Array;
+  toObject(includeInstance?: boolean): CompactPositionData.AsObject;
+  static toObject(includeInstance: boolean, msg: CompactPositionData): CompactPositionData.AsObject;
+  static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+  static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+  static serializeBinaryToWriter(message: CompactPositionData, writer: jspb.BinaryWriter): void;
+  static deserializeBinary(bytes: Uint8Array): CompactPositionData;
+  static deserializeBinaryFromReader(message: CompactPositionData, reader: jspb.BinaryReader): CompactPositionData;
+}
+
+export namespace CompactPositionData {
+  export type AsObject = {
+    category: Category,
+    sequence: number,
+    baseline: number,
+    time: number,
+    cellX: number,
+    cellZ: number,
+    positionX: number,
+    positionY: number,
+    positionZ: number,
+    rotation: number,
+  }
+}
+
 export class ProfileData extends jspb.Message {
   getCategory(): Category;
   setCategory(value: Category): void;
@@ -117,6 +173,12 @@ export class ProfileData extends jspb.Message {
   getProfileVersion(): string;
   setProfileVersion(value: string): void;
 
+  getHash(): string;
+  setHash(value: string): void;
+
+  getSnapshotUrl(): string;
+  setSnapshotUrl(value: string): void;
+
   serializeBinary(): Uint8Array;
   toObject(includeInstance?: boolean): ProfileData.AsObject;
   static toObject(includeInstance: boolean, msg: ProfileData): ProfileData.AsObject;
@@ -132,6 +194,8 @@ export namespace ProfileData {
     category: Category,
     time: number,
     profileVersion: string,
+    hash: string,
+    snapshotUrl: string,
   }
 }
 
@@ -167,11 +231,112 @@ export namespace ChatData {
   }
 }
 
+export class DirectMessageData extends jspb.Message {
+  getCategory(): Category;
+  setCategory(value: Category): void;
+
+  getTime(): number;
+  setTime(value: number): void;
+
+  getMessageId(): string;
+  setMessageId(value: string): void;
+
+  getRecipient(): string;
+  setRecipient(value: string): void;
+
+  getText(): string;
+  setText(value: string): void;
+
+  serializeBinary(): Uint8Array;
+  toObject(includeInstance?: boolean): DirectMessageData.AsObject;
+  static toObject(includeInstance: boolean, msg: DirectMessageData): DirectMessageData.AsObject;
+  static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+  static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+  static serializeBinaryToWriter(message: DirectMessageData, writer: jspb.BinaryWriter): void;
+  static deserializeBinary(bytes: Uint8Array): DirectMessageData;
+  static deserializeBinaryFromReader(message: DirectMessageData, reader: jspb.BinaryReader): DirectMessageData;
+}
+
+export namespace DirectMessageData {
+  export type AsObject = {
+    category: Category,
+    time: number,
+    messageId: string,
+    recipient: string,
+    text: string,
+  }
+}
+
+export class DirectMessageAckData extends jspb.Message {
+  getCategory(): Category;
+  setCategory(value: Category): void;
+
+  getTime(): number;
+  setTime(value: number): void;
+
+  getMessageId(): string;
+  setMessageId(value: string): void;
+
+  getRecipient(): string;
+  setRecipient(value: string): void;
+
+  serializeBinary(): Uint8Array;
+  toObject(includeInstance?: boolean): DirectMessageAckData.AsObject;
+  static toObject(includeInstance: boolean, msg: DirectMessageAckData): DirectMessageAckData.AsObject;
+  static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+  static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+  static serializeBinaryToWriter(message: DirectMessageAckData, writer: jspb.BinaryWriter): void;
+  static deserializeBinary(bytes: Uint8Array): DirectMessageAckData;
+  static deserializeBinaryFromReader(message: DirectMessageAckData, reader: jspb.BinaryReader): DirectMessageAckData;
+}
+
+export namespace DirectMessageAckData {
+  export type AsObject = {
+    category: Category,
+    time: number,
+    messageId: string,
+    recipient: string,
+  }
+}
+
+export class BatchData extends jspb.Message {
+  getCategory(): Category;
+  setCategory(value: Category): void;
+
+  clearMessagesList(): void;
+  getMessagesList(): Array<Uint8Array | string>;
+  getMessagesList_asU8(): Array<Uint8Array>;
+  getMessagesList_asB64(): Array<string>;
+  setMessagesList(value: Array<Uint8Array | string>): void;
+  addMessages(value: Uint8Array | string, index?: number): Uint8Array | string;
+
+  serializeBinary(): Uint8Array;
+  toObject(includeInstance?: boolean): BatchData.AsObject;
+  static toObject(includeInstance: boolean, msg: BatchData): BatchData.AsObject;
+  static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+  static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+  static serializeBinaryToWriter(message: BatchData, writer: jspb.BinaryWriter): void;
+  static deserializeBinary(bytes: Uint8Array): BatchData;
+  static deserializeBinaryFromReader(message: BatchData, reader: jspb.BinaryReader): BatchData;
+}
+
+export namespace BatchData {
+  export type AsObject = {
+    category: Category,
+    messagesList: Array<Uint8Array | string>,
+  }
+}
+
 export enum Category {
   UNKNOWN = 0,
   POSITION = 1,
   PROFILE = 2,
   CHAT = 3,
   SCENE_MESSAGE = 4,
+  DIRECT_MESSAGE = 5,
+  DIRECT_MESSAGE_ACK = 6,
+  COMPACT_POSITION = 7,
+  BATCH = 8,
+  FORWARDED_BATCH = 9,
 }
 
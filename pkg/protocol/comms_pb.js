@@ -12,9 +12,13 @@ var goog = jspb;
 var global = Function('return this')();
 
 goog.exportSymbol('proto.protocol.AuthData', null, global);
+goog.exportSymbol('proto.protocol.BatchData', null, global);
 goog.exportSymbol('proto.protocol.Category', null, global);
 goog.exportSymbol('proto.protocol.ChatData', null, global);
+goog.exportSymbol('proto.protocol.CompactPositionData', null, global);
 goog.exportSymbol('proto.protocol.DataHeader', null, global);
+goog.exportSymbol('proto.protocol.DirectMessageAckData', null, global);
+goog.exportSymbol('proto.protocol.DirectMessageData', null, global);
 goog.exportSymbol('proto.protocol.PositionData', null, global);
 goog.exportSymbol('proto.protocol.ProfileData', null, global);
 
@@ -741,6 +745,391 @@ proto.protocol.PositionData.prototype.setRotationW = function(value) {
 
 
 
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.protocol.CompactPositionData = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+};
+goog.inherits(proto.protocol.CompactPositionData, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  proto.protocol.CompactPositionData.displayName = 'proto.protocol.CompactPositionData';
+}
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto suitable for use in Soy templates.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     com.google.apps.jspb.JsClassTemplate.JS_RESERVED_WORDS.
+ * @param {boolean=} opt_includeInstance Whether to include the JSPB instance
+ *     for transitional soy proto support: http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.protocol.CompactPositionData.prototype.toObject = function(opt_includeInstance) {
+  return proto.protocol.CompactPositionData.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Whether to include the JSPB
+ *     instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.protocol.CompactPositionData} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.CompactPositionData.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    category: jspb.Message.getFieldWithDefault(msg, 1, 0),
+    sequence: jspb.Message.getFieldWithDefault(msg, 2, 0),
+    baseline: jspb.Message.getFieldWithDefault(msg, 3, 0),
+    time: jspb.Message.getFieldWithDefault(msg, 4, 0),
+    cellX: jspb.Message.getFieldWithDefault(msg, 5, 0),
+    cellZ: jspb.Message.getFieldWithDefault(msg, 6, 0),
+    positionX: jspb.Message.getFieldWithDefault(msg, 7, 0),
+    positionY: jspb.Message.getFieldWithDefault(msg, 8, 0),
+    positionZ: jspb.Message.getFieldWithDefault(msg, 9, 0),
+    rotation: jspb.Message.getFieldWithDefault(msg, 10, 0)
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.protocol.CompactPositionData}
+ */
+proto.protocol.CompactPositionData.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.protocol.CompactPositionData;
+  return proto.protocol.CompactPositionData.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.protocol.CompactPositionData} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.protocol.CompactPositionData}
+ */
+proto.protocol.CompactPositionData.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = /** @type {!proto.protocol.Category} */ (reader.readEnum());
+      msg.setCategory(value);
+      break;
+    case 2:
+      var value = /** @type {number} */ (reader.readUint32());
+      msg.setSequence(value);
+      break;
+    case 3:
+      var value = /** @type {number} */ (reader.readUint32());
+      msg.setBaseline(value);
+      break;
+    case 4:
+      var value = /** @type {number} */ (reader.readSint64());
+      msg.setTime(value);
+      break;
+    case 5:
+      var value = /** @type {number} */ (reader.readSint32());
+      msg.setCellX(value);
+      break;
+    case 6:
+      var value = /** @type {number} */ (reader.readSint32());
+      msg.setCellZ(value);
+      break;
+    case 7:
+      var value = /** @type {number} */ (reader.readSint32());
+      msg.setPositionX(value);
+      break;
+    case 8:
+      var value = /** @type {number} */ (reader.readSint32());
+      msg.setPositionY(value);
+      break;
+    case 9:
+      var value = /** @type {number} */ (reader.readSint32());
+      msg.setPositionZ(value);
+      break;
+    case 10:
+      var value = /** @type {number} */ (reader.readUint32());
+      msg.setRotation(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.protocol.CompactPositionData.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.protocol.CompactPositionData.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.protocol.CompactPositionData} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.CompactPositionData.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getCategory();
+  if (f !== 0.0) {
+    writer.writeEnum(
+      1,
+      f
+    );
+  }
+  f = message.getSequence();
+  if (f !== 0) {
+    writer.writeUint32(
+      2,
+      f
+    );
+  }
+  f = message.getBaseline();
+  if (f !== 0) {
+    writer.writeUint32(
+      3,
+      f
+    );
+  }
+  f = message.getTime();
+  if (f !== 0) {
+    writer.writeSint64(
+      4,
+      f
+    );
+  }
+  f = message.getCellX();
+  if (f !== 0) {
+    writer.writeSint32(
+      5,
+      f
+    );
+  }
+  f = message.getCellZ();
+  if (f !== 0) {
+    writer.writeSint32(
+      6,
+      f
+    );
+  }
+  f = message.getPositionX();
+  if (f !== 0) {
+    writer.writeSint32(
+      7,
+      f
+    );
+  }
+  f = message.getPositionY();
+  if (f !== 0) {
+    writer.writeSint32(
+      8,
+      f
+    );
+  }
+  f = message.getPositionZ();
+  if (f !== 0) {
+    writer.writeSint32(
+      9,
+      f
+    );
+  }
+  f = message.getRotation();
+  if (f !== 0) {
+    writer.writeUint32(
+      10,
+      f
+    );
+  }
+};
+
+
+/**
+ * optional Category category = 1;
+ * @return {!proto.protocol.Category}
+ */
+proto.protocol.CompactPositionData.prototype.getCategory = function() {
+  return /** @type {!proto.protocol.Category} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
+};
+
+
+/** @param {!proto.protocol.Category} value */
+proto.protocol.CompactPositionData.prototype.setCategory = function(value) {
+  jspb.Message.setProto3EnumField(this, 1, value);
+};
+
+
+/**
+ * optional uint32 sequence = 2;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getSequence = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 2, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setSequence = function(value) {
+  jspb.Message.setProto3IntField(this, 2, value);
+};
+
+
+/**
+ * optional uint32 baseline = 3;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getBaseline = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 3, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setBaseline = function(value) {
+  jspb.Message.setProto3IntField(this, 3, value);
+};
+
+
+/**
+ * optional sint64 time = 4;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getTime = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 4, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setTime = function(value) {
+  jspb.Message.setProto3IntField(this, 4, value);
+};
+
+
+/**
+ * optional sint32 cell_x = 5;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getCellX = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 5, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setCellX = function(value) {
+  jspb.Message.setProto3IntField(this, 5, value);
+};
+
+
+/**
+ * optional sint32 cell_z = 6;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getCellZ = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 6, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setCellZ = function(value) {
+  jspb.Message.setProto3IntField(this, 6, value);
+};
+
+
+/**
+ * optional sint32 position_x = 7;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getPositionX = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 7, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setPositionX = function(value) {
+  jspb.Message.setProto3IntField(this, 7, value);
+};
+
+
+/**
+ * optional sint32 position_y = 8;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getPositionY = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 8, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setPositionY = function(value) {
+  jspb.Message.setProto3IntField(this, 8, value);
+};
+
+
+/**
+ * optional sint32 position_z = 9;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getPositionZ = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 9, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setPositionZ = function(value) {
+  jspb.Message.setProto3IntField(this, 9, value);
+};
+
+
+/**
+ * optional uint32 rotation = 10;
+ * @return {number}
+ */
+proto.protocol.CompactPositionData.prototype.getRotation = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 10, 0));
+};
+
+
+/** @param {number} value */
+proto.protocol.CompactPositionData.prototype.setRotation = function(value) {
+  jspb.Message.setProto3IntField(this, 10, value);
+};
+
+
+
 /**
  * Generated by JsPbCodeGenerator.
  * @param {Array=} opt_data Optional initial data array, typically from a
@@ -754,9 +1143,482 @@ proto.protocol.PositionData.prototype.setRotationW = function(value) {
 proto.protocol.ProfileData = function(opt_data) {
   jspb.Message.initialize(this, opt_data, 0, -1, null, null);
 };
-goog.inherits(proto.protocol.ProfileData, jspb.Message);
+goog.inherits(proto.protocol.ProfileData, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  proto.protocol.ProfileData.displayName = 'proto.protocol.ProfileData';
+}
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto suitable for use in Soy templates.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     com.google.apps.jspb.JsClassTemplate.JS_RESERVED_WORDS.
+ * @param {boolean=} opt_includeInstance Whether to include the JSPB instance
+ *     for transitional soy proto support: http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.protocol.ProfileData.prototype.toObject = function(opt_includeInstance) {
+  return proto.protocol.ProfileData.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Whether to include the JSPB
+ *     instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.protocol.ProfileData} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.ProfileData.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    category: jspb.Message.getFieldWithDefault(msg, 1, 0),
+    time: +jspb.Message.getFieldWithDefault(msg, 2, 0.0),
+    profileVersion: jspb.Message.getFieldWithDefault(msg, 3, ""),
+    hash: jspb.Message.getFieldWithDefault(msg, 4, ""),
+    snapshotUrl: jspb.Message.getFieldWithDefault(msg, 5, "")
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.protocol.ProfileData}
+ */
+proto.protocol.ProfileData.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.protocol.ProfileData;
+  return proto.protocol.ProfileData.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.protocol.ProfileData} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.protocol.ProfileData}
+ */
+proto.protocol.ProfileData.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = /** @type {!proto.protocol.Category} */ (reader.readEnum());
+      msg.setCategory(value);
+      break;
+    case 2:
+      var value = /** @type {number} */ (reader.readDouble());
+      msg.setTime(value);
+      break;
+    case 3:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setProfileVersion(value);
+      break;
+    case 4:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setHash(value);
+      break;
+    case 5:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setSnapshotUrl(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.protocol.ProfileData.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.protocol.ProfileData.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.protocol.ProfileData} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.ProfileData.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getCategory();
+  if (f !== 0.0) {
+    writer.writeEnum(
+      1,
+      f
+    );
+  }
+  f = message.getTime();
+  if (f !== 0.0) {
+    writer.writeDouble(
+      2,
+      f
+    );
+  }
+  f = message.getProfileVersion();
+  if (f.length > 0) {
+    writer.writeString(
+      3,
+      f
+    );
+  }
+  f = message.getHash();
+  if (f.length > 0) {
+    writer.writeString(
+      4,
+      f
+    );
+  }
+  f = message.getSnapshotUrl();
+  if (f.length > 0) {
+    writer.writeString(
+      5,
+      f
+    );
+  }
+};
+
+
+/**
+ * optional Category category = 1;
+ * @return {!proto.protocol.Category}
+ */
+proto.protocol.ProfileData.prototype.getCategory = function() {
+  return /** @type {!proto.protocol.Category} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
+};
+
+
+/** @param {!proto.protocol.Category} value */
+proto.protocol.ProfileData.prototype.setCategory = function(value) {
+  jspb.Message.setProto3EnumField(this, 1, value);
+};
+
+
+/**
+ * optional double time = 2;
+ * @return {number}
+ */
+proto.protocol.ProfileData.prototype.getTime = function() {
+  return /** @type {number} */ (+jspb.Message.getFieldWithDefault(this, 2, 0.0));
+};
+
+
+/** @param {number} value */
+proto.protocol.ProfileData.prototype.setTime = function(value) {
+  jspb.Message.setProto3FloatField(this, 2, value);
+};
+
+
+/**
+ * optional string profile_version = 3;
+ * @return {string}
+ */
+proto.protocol.ProfileData.prototype.getProfileVersion = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 3, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.ProfileData.prototype.setProfileVersion = function(value) {
+  jspb.Message.setProto3StringField(this, 3, value);
+};
+
+
+/**
+ * optional string hash = 4;
+ * @return {string}
+ */
+proto.protocol.ProfileData.prototype.getHash = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 4, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.ProfileData.prototype.setHash = function(value) {
+  jspb.Message.setProto3StringField(this, 4, value);
+};
+
+
+/**
+ * optional string snapshot_url = 5;
+ * @return {string}
+ */
+proto.protocol.ProfileData.prototype.getSnapshotUrl = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 5, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.ProfileData.prototype.setSnapshotUrl = function(value) {
+  jspb.Message.setProto3StringField(this, 5, value);
+};
+
+
+
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.protocol.ChatData = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+};
+goog.inherits(proto.protocol.ChatData, jspb.Message);
 if (goog.DEBUG && !COMPILED) {
-  proto.protocol.ProfileData.displayName = 'proto.protocol.ProfileData';
+  proto.protocol.ChatData.displayName = 'proto.protocol.ChatData';
+}
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto suitable for use in Soy templates.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     com.google.apps.jspb.JsClassTemplate.JS_RESERVED_WORDS.
+ * @param {boolean=} opt_includeInstance Whether to include the JSPB instance
+ *     for transitional soy proto support: http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.protocol.ChatData.prototype.toObject = function(opt_includeInstance) {
+  return proto.protocol.ChatData.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Whether to include the JSPB
+ *     instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.protocol.ChatData} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.ChatData.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    category: jspb.Message.getFieldWithDefault(msg, 1, 0),
+    time: +jspb.Message.getFieldWithDefault(msg, 2, 0.0),
+    messageId: jspb.Message.getFieldWithDefault(msg, 3, ""),
+    text: jspb.Message.getFieldWithDefault(msg, 4, "")
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.protocol.ChatData}
+ */
+proto.protocol.ChatData.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.protocol.ChatData;
+  return proto.protocol.ChatData.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.protocol.ChatData} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.protocol.ChatData}
+ */
+proto.protocol.ChatData.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = /** @type {!proto.protocol.Category} */ (reader.readEnum());
+      msg.setCategory(value);
+      break;
+    case 2:
+      var value = /** @type {number} */ (reader.readDouble());
+      msg.setTime(value);
+      break;
+    case 3:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setMessageId(value);
+      break;
+    case 4:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setText(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.protocol.ChatData.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.protocol.ChatData.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.protocol.ChatData} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.ChatData.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getCategory();
+  if (f !== 0.0) {
+    writer.writeEnum(
+      1,
+      f
+    );
+  }
+  f = message.getTime();
+  if (f !== 0.0) {
+    writer.writeDouble(
+      2,
+      f
+    );
+  }
+  f = message.getMessageId();
+  if (f.length > 0) {
+    writer.writeString(
+      3,
+      f
+    );
+  }
+  f = message.getText();
+  if (f.length > 0) {
+    writer.writeString(
+      4,
+      f
+    );
+  }
+};
+
+
+/**
+ * optional Category category = 1;
+ * @return {!proto.protocol.Category}
+ */
+proto.protocol.ChatData.prototype.getCategory = function() {
+  return /** @type {!proto.protocol.Category} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
+};
+
+
+/** @param {!proto.protocol.Category} value */
+proto.protocol.ChatData.prototype.setCategory = function(value) {
+  jspb.Message.setProto3EnumField(this, 1, value);
+};
+
+
+/**
+ * optional double time = 2;
+ * @return {number}
+ */
+proto.protocol.ChatData.prototype.getTime = function() {
+  return /** @type {number} */ (+jspb.Message.getFieldWithDefault(this, 2, 0.0));
+};
+
+
+/** @param {number} value */
+proto.protocol.ChatData.prototype.setTime = function(value) {
+  jspb.Message.setProto3FloatField(this, 2, value);
+};
+
+
+/**
+ * optional string message_id = 3;
+ * @return {string}
+ */
+proto.protocol.ChatData.prototype.getMessageId = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 3, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.ChatData.prototype.setMessageId = function(value) {
+  jspb.Message.setProto3StringField(this, 3, value);
+};
+
+
+/**
+ * optional string text = 4;
+ * @return {string}
+ */
+proto.protocol.ChatData.prototype.getText = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 4, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.ChatData.prototype.setText = function(value) {
+  jspb.Message.setProto3StringField(this, 4, value);
+};
+
+
+
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.protocol.DirectMessageData = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+};
+goog.inherits(proto.protocol.DirectMessageData, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  proto.protocol.DirectMessageData.displayName = 'proto.protocol.DirectMessageData';
 }
 
 
@@ -771,8 +1633,8 @@ if (jspb.Message.GENERATE_TO_OBJECT) {
  *     for transitional soy proto support: http://goto/soy-param-migration
  * @return {!Object}
  */
-proto.protocol.ProfileData.prototype.toObject = function(opt_includeInstance) {
-  return proto.protocol.ProfileData.toObject(opt_includeInstance, this);
+proto.protocol.DirectMessageData.prototype.toObject = function(opt_includeInstance) {
+  return proto.protocol.DirectMessageData.toObject(opt_includeInstance, this);
 };
 
 
@@ -781,15 +1643,17 @@ proto.protocol.ProfileData.prototype.toObject = function(opt_includeInstance) {
  * @param {boolean|undefined} includeInstance Whether to include the JSPB
  *     instance for transitional soy proto support:
  *     http://goto/soy-param-migration
- * @param {!proto.protocol.ProfileData} msg The msg instance to transform.
+ * @param {!proto.protocol.DirectMessageData} msg The msg instance to transform.
  * @return {!Object}
  * @suppress {unusedLocalVariables} f is only used for nested messages
  */
-proto.protocol.ProfileData.toObject = function(includeInstance, msg) {
+proto.protocol.DirectMessageData.toObject = function(includeInstance, msg) {
   var f, obj = {
     category: jspb.Message.getFieldWithDefault(msg, 1, 0),
     time: +jspb.Message.getFieldWithDefault(msg, 2, 0.0),
-    profileVersion: jspb.Message.getFieldWithDefault(msg, 3, "")
+    messageId: jspb.Message.getFieldWithDefault(msg, 3, ""),
+    recipient: jspb.Message.getFieldWithDefault(msg, 4, ""),
+    text: jspb.Message.getFieldWithDefault(msg, 5, "")
   };
 
   if (includeInstance) {
@@ -803,23 +1667,23 @@ proto.protocol.ProfileData.toObject = function(includeInstance, msg) {
 /**
  * Deserializes binary data (in protobuf wire format).
  * @param {jspb.ByteSource} bytes The bytes to deserialize.
- * @return {!proto.protocol.ProfileData}
+ * @return {!proto.protocol.DirectMessageData}
  */
-proto.protocol.ProfileData.deserializeBinary = function(bytes) {
+proto.protocol.DirectMessageData.deserializeBinary = function(bytes) {
   var reader = new jspb.BinaryReader(bytes);
-  var msg = new proto.protocol.ProfileData;
-  return proto.protocol.ProfileData.deserializeBinaryFromReader(msg, reader);
+  var msg = new proto.protocol.DirectMessageData;
+  return proto.protocol.DirectMessageData.deserializeBinaryFromReader(msg, reader);
 };
 
 
 /**
  * Deserializes binary data (in protobuf wire format) from the
  * given reader into the given message object.
- * @param {!proto.protocol.ProfileData} msg The message object to deserialize into.
+ * @param {!proto.protocol.DirectMessageData} msg The message object to deserialize into.
  * @param {!jspb.BinaryReader} reader The BinaryReader to use.
- * @return {!proto.protocol.ProfileData}
+ * @return {!proto.protocol.DirectMessageData}
  */
-proto.protocol.ProfileData.deserializeBinaryFromReader = function(msg, reader) {
+proto.protocol.DirectMessageData.deserializeBinaryFromReader = function(msg, reader) {
   while (reader.nextField()) {
     if (reader.isEndGroup()) {
       break;
@@ -836,7 +1700,15 @@ proto.protocol.ProfileData.deserializeBinaryFromReader = function(msg, reader) {
       break;
     case 3:
       var value = /** @type {string} */ (reader.readString());
-      msg.setProfileVersion(value);
+      msg.setMessageId(value);
+      break;
+    case 4:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setRecipient(value);
+      break;
+    case 5:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setText(value);
       break;
     default:
       reader.skipField();
@@ -851,9 +1723,9 @@ proto.protocol.ProfileData.deserializeBinaryFromReader = function(msg, reader) {
  * Serializes the message to binary data (in protobuf wire format).
  * @return {!Uint8Array}
  */
-proto.protocol.ProfileData.prototype.serializeBinary = function() {
+proto.protocol.DirectMessageData.prototype.serializeBinary = function() {
   var writer = new jspb.BinaryWriter();
-  proto.protocol.ProfileData.serializeBinaryToWriter(this, writer);
+  proto.protocol.DirectMessageData.serializeBinaryToWriter(this, writer);
   return writer.getResultBuffer();
 };
 
@@ -861,11 +1733,11 @@ proto.protocol.ProfileData.prototype.serializeBinary = function() {
 /**
  * Serializes the given message to binary data (in protobuf wire
  * format), writing to the given BinaryWriter.
- * @param {!proto.protocol.ProfileData} message
+ * @param {!proto.protocol.DirectMessageData} message
  * @param {!jspb.BinaryWriter} writer
  * @suppress {unusedLocalVariables} f is only used for nested messages
  */
-proto.protocol.ProfileData.serializeBinaryToWriter = function(message, writer) {
+proto.protocol.DirectMessageData.serializeBinaryToWriter = function(message, writer) {
   var f = undefined;
   f = message.getCategory();
   if (f !== 0.0) {
@@ -881,13 +1753,27 @@ proto.protocol.ProfileData.serializeBinaryToWriter = function(message, writer) {
       f
     );
   }
-  f = message.getProfileVersion();
+  f = message.getMessageId();
   if (f.length > 0) {
     writer.writeString(
       3,
       f
     );
   }
+  f = message.getRecipient();
+  if (f.length > 0) {
+    writer.writeString(
+      4,
+      f
+    );
+  }
+  f = message.getText();
+  if (f.length > 0) {
+    writer.writeString(
+      5,
+      f
+    );
+  }
 };
 
 
@@ -895,13 +1781,13 @@ proto.protocol.ProfileData.serializeBinaryToWriter = function(message, writer) {
  * optional Category category = 1;
  * @return {!proto.protocol.Category}
  */
-proto.protocol.ProfileData.prototype.getCategory = function() {
+proto.protocol.DirectMessageData.prototype.getCategory = function() {
   return /** @type {!proto.protocol.Category} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
 };
 
 
 /** @param {!proto.protocol.Category} value */
-proto.protocol.ProfileData.prototype.setCategory = function(value) {
+proto.protocol.DirectMessageData.prototype.setCategory = function(value) {
   jspb.Message.setProto3EnumField(this, 1, value);
 };
 
@@ -910,32 +1796,62 @@ proto.protocol.ProfileData.prototype.setCategory = function(value) {
  * optional double time = 2;
  * @return {number}
  */
-proto.protocol.ProfileData.prototype.getTime = function() {
+proto.protocol.DirectMessageData.prototype.getTime = function() {
   return /** @type {number} */ (+jspb.Message.getFieldWithDefault(this, 2, 0.0));
 };
 
 
 /** @param {number} value */
-proto.protocol.ProfileData.prototype.setTime = function(value) {
+proto.protocol.DirectMessageData.prototype.setTime = function(value) {
   jspb.Message.setProto3FloatField(this, 2, value);
 };
 
 
 /**
- * optional string profile_version = 3;
+ * optional string message_id = 3;
  * @return {string}
  */
-proto.protocol.ProfileData.prototype.getProfileVersion = function() {
+proto.protocol.DirectMessageData.prototype.getMessageId = function() {
   return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 3, ""));
 };
 
 
 /** @param {string} value */
-proto.protocol.ProfileData.prototype.setProfileVersion = function(value) {
+proto.protocol.DirectMessageData.prototype.setMessageId = function(value) {
   jspb.Message.setProto3StringField(this, 3, value);
 };
 
 
+/**
+ * optional string recipient = 4;
+ * @return {string}
+ */
+proto.protocol.DirectMessageData.prototype.getRecipient = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 4, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.DirectMessageData.prototype.setRecipient = function(value) {
+  jspb.Message.setProto3StringField(this, 4, value);
+};
+
+
+/**
+ * optional string text = 5;
+ * @return {string}
+ */
+proto.protocol.DirectMessageData.prototype.getText = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 5, ""));
+};
+
+
+/** @param {string} value */
+proto.protocol.DirectMessageData.prototype.setText = function(value) {
+  jspb.Message.setProto3StringField(this, 5, value);
+};
+
+
 
 /**
  * Generated by JsPbCodeGenerator.
@@ -947,12 +1863,12 @@ proto.protocol.ProfileData.prototype.setProfileVersion = function(value) {
  * @extends {jspb.Message}
  * @constructor
  */
-proto.protocol.ChatData = function(opt_data) {
+proto.protocol.DirectMessageAckData = function(opt_data) {
   jspb.Message.initialize(this, opt_data, 0, -1, null, null);
 };
-goog.inherits(proto.protocol.ChatData, jspb.Message);
+goog.inherits(proto.protocol.DirectMessageAckData, jspb.Message);
 if (goog.DEBUG && !COMPILED) {
-  proto.protocol.ChatData.displayName = 'proto.protocol.ChatData';
+  proto.protocol.DirectMessageAckData.displayName = 'proto.protocol.DirectMessageAckData';
 }
 
 
@@ -967,8 +1883,8 @@ if (jspb.Message.GENERATE_TO_OBJECT) {
  *     for transitional soy proto support: http://goto/soy-param-migration
  * @return {!Object}
  */
-proto.protocol.ChatData.prototype.toObject = function(opt_includeInstance) {
-  return proto.protocol.ChatData.toObject(opt_includeInstance, this);
+proto.protocol.DirectMessageAckData.prototype.toObject = function(opt_includeInstance) {
+  return proto.protocol.DirectMessageAckData.toObject(opt_includeInstance, this);
 };
 
 
@@ -977,16 +1893,16 @@ proto.protocol.ChatData.prototype.toObject = function(opt_includeInstance) {
  * @param {boolean|undefined} includeInstance Whether to include the JSPB
  *     instance for transitional soy proto support:
  *     http://goto/soy-param-migration
- * @param {!proto.protocol.ChatData} msg The msg instance to transform.
+ * @param {!proto.protocol.DirectMessageAckData} msg The msg instance to transform.
  * @return {!Object}
  * @suppress {unusedLocalVariables} f is only used for nested messages
  */
-proto.protocol.ChatData.toObject = function(includeInstance, msg) {
+proto.protocol.DirectMessageAckData.toObject = function(includeInstance, msg) {
   var f, obj = {
     category: jspb.Message.getFieldWithDefault(msg, 1, 0),
     time: +jspb.Message.getFieldWithDefault(msg, 2, 0.0),
     messageId: jspb.Message.getFieldWithDefault(msg, 3, ""),
-    text: jspb.Message.getFieldWithDefault(msg, 4, "")
+    recipient: jspb.Message.getFieldWithDefault(msg, 4, "")
   };
 
   if (includeInstance) {
@@ -1000,23 +1916,23 @@ proto.protocol.ChatData.toObject = function(includeInstance, msg) {
 /**
  * Deserializes binary data (in protobuf wire format).
  * @param {jspb.ByteSource} bytes The bytes to deserialize.
- * @return {!proto.protocol.ChatData}
+ * @return {!proto.protocol.DirectMessageAckData}
  */
-proto.protocol.ChatData.deserializeBinary = function(bytes) {
+proto.protocol.DirectMessageAckData.deserializeBinary = function(bytes) {
   var reader = new jspb.BinaryReader(bytes);
-  var msg = new proto.protocol.ChatData;
-  return proto.protocol.ChatData.deserializeBinaryFromReader(msg, reader);
+  var msg = new proto.protocol.DirectMessageAckData;
+  return proto.protocol.DirectMessageAckData.deserializeBinaryFromReader(msg, reader);
 };
 
 
 /**
  * Deserializes binary data (in protobuf wire format) from the
  * given reader into the given message object.
- * @param {!proto.protocol.ChatData} msg The message object to deserialize into.
+ * @param {!proto.protocol.DirectMessageAckData} msg The message object to deserialize into.
  * @param {!jspb.BinaryReader} reader The BinaryReader to use.
- * @return {!proto.protocol.ChatData}
+ * @return {!proto.protocol.DirectMessageAckData}
  */
-proto.protocol.ChatData.deserializeBinaryFromReader = function(msg, reader) {
+proto.protocol.DirectMessageAckData.deserializeBinaryFromReader = function(msg, reader) {
   while (reader.nextField()) {
     if (reader.isEndGroup()) {
       break;
@@ -1037,7 +1953,7 @@ proto.protocol.ChatData.deserializeBinaryFromReader = function(msg, reader) {
       break;
     case 4:
       var value = /** @type {string} */ (reader.readString());
-      msg.setText(value);
+      msg.setRecipient(value);
       break;
     default:
       reader.skipField();
@@ -1052,9 +1968,9 @@ proto.protocol.ChatData.deserializeBinaryFromReader = function(msg, reader) {
  * Serializes the message to binary data (in protobuf wire format).
  * @return {!Uint8Array}
  */
-proto.protocol.ChatData.prototype.serializeBinary = function() {
+proto.protocol.DirectMessageAckData.prototype.serializeBinary = function() {
   var writer = new jspb.BinaryWriter();
-  proto.protocol.ChatData.serializeBinaryToWriter(this, writer);
+  proto.protocol.DirectMessageAckData.serializeBinaryToWriter(this, writer);
   return writer.getResultBuffer();
 };
 
@@ -1062,11 +1978,11 @@ proto.protocol.ChatData.prototype.serializeBinary = function() {
 /**
  * Serializes the given message to binary data (in protobuf wire
  * format), writing to the given BinaryWriter.
- * @param {!proto.protocol.ChatData} message
+ * @param {!proto.protocol.DirectMessageAckData} message
  * @param {!jspb.BinaryWriter} writer
  * @suppress {unusedLocalVariables} f is only used for nested messages
  */
-proto.protocol.ChatData.serializeBinaryToWriter = function(message, writer) {
+proto.protocol.DirectMessageAckData.serializeBinaryToWriter = function(message, writer) {
   var f = undefined;
   f = message.getCategory();
   if (f !== 0.0) {
@@ -1089,7 +2005,7 @@ proto.protocol.ChatData.serializeBinaryToWriter = function(message, writer) {
       f
     );
   }
-  f = message.getText();
+  f = message.getRecipient();
   if (f.length > 0) {
     writer.writeString(
       4,
@@ -1103,13 +2019,13 @@ proto.protocol.ChatData.serializeBinaryToWriter = function(message, writer) {
  * optional Category category = 1;
  * @return {!proto.protocol.Category}
  */
-proto.protocol.ChatData.prototype.getCategory = function() {
+proto.protocol.DirectMessageAckData.prototype.getCategory = function() {
   return /** @type {!proto.protocol.Category} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
 };
 
 
 /** @param {!proto.protocol.Category} value */
-proto.protocol.ChatData.prototype.setCategory = function(value) {
+proto.protocol.DirectMessageAckData.prototype.setCategory = function(value) {
   jspb.Message.setProto3EnumField(this, 1, value);
 };
 
@@ -1118,13 +2034,13 @@ proto.protocol.ChatData.prototype.setCategory = function(value) {
  * optional double time = 2;
  * @return {number}
  */
-proto.protocol.ChatData.prototype.getTime = function() {
+proto.protocol.DirectMessageAckData.prototype.getTime = function() {
   return /** @type {number} */ (+jspb.Message.getFieldWithDefault(this, 2, 0.0));
 };
 
 
 /** @param {number} value */
-proto.protocol.ChatData.prototype.setTime = function(value) {
+proto.protocol.DirectMessageAckData.prototype.setTime = function(value) {
   jspb.Message.setProto3FloatField(this, 2, value);
 };
 
@@ -1133,32 +2049,245 @@ proto.protocol.ChatData.prototype.setTime = function(value) {
  * optional string message_id = 3;
  * @return {string}
  */
-proto.protocol.ChatData.prototype.getMessageId = function() {
+proto.protocol.DirectMessageAckData.prototype.getMessageId = function() {
   return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 3, ""));
 };
 
 
 /** @param {string} value */
-proto.protocol.ChatData.prototype.setMessageId = function(value) {
+proto.protocol.DirectMessageAckData.prototype.setMessageId = function(value) {
   jspb.Message.setProto3StringField(this, 3, value);
 };
 
 
 /**
- * optional string text = 4;
+ * optional string recipient = 4;
  * @return {string}
  */
-proto.protocol.ChatData.prototype.getText = function() {
+proto.protocol.DirectMessageAckData.prototype.getRecipient = function() {
   return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 4, ""));
 };
 
 
 /** @param {string} value */
-proto.protocol.ChatData.prototype.setText = function(value) {
+proto.protocol.DirectMessageAckData.prototype.setRecipient = function(value) {
   jspb.Message.setProto3StringField(this, 4, value);
 };
 
 
+
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.protocol.BatchData = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, proto.protocol.BatchData.repeatedFields_, null);
+};
+goog.inherits(proto.protocol.BatchData, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  proto.protocol.BatchData.displayName = 'proto.protocol.BatchData';
+}
+/**
+ * List of repeated fields within this message type.
+ * @private {!Array<number>}
+ * @const
+ */
+proto.protocol.BatchData.repeatedFields_ = [2];
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto suitable for use in Soy templates.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     com.google.apps.jspb.JsClassTemplate.JS_RESERVED_WORDS.
+ * @param {boolean=} opt_includeInstance Whether to include the JSPB instance
+ *     for transitional soy proto support: http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.protocol.BatchData.prototype.toObject = function(opt_includeInstance) {
+  return proto.protocol.BatchData.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Whether to include the JSPB
+ *     instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.protocol.BatchData} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.BatchData.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    category: jspb.Message.getFieldWithDefault(msg, 1, 0),
+    messagesList: msg.getMessagesList_asB64()
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.protocol.BatchData}
+ */
+proto.protocol.BatchData.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.protocol.BatchData;
+  return proto.protocol.BatchData.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.protocol.BatchData} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.protocol.BatchData}
+ */
+proto.protocol.BatchData.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = /** @type {!proto.protocol.Category} */ (reader.readEnum());
+      msg.setCategory(value);
+      break;
+    case 2:
+      var value = /** @type {!Uint8Array} */ (reader.readBytes());
+      msg.addMessages(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.protocol.BatchData.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.protocol.BatchData.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.protocol.BatchData} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.protocol.BatchData.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getCategory();
+  if (f !== 0.0) {
+    writer.writeEnum(
+      1,
+      f
+    );
+  }
+  f = message.getMessagesList_asU8();
+  if (f.length > 0) {
+    writer.writeRepeatedBytes(
+      2,
+      f
+    );
+  }
+};
+
+
+/**
+ * optional Category category = 1;
+ * @return {!proto.protocol.Category}
+ */
+proto.protocol.BatchData.prototype.getCategory = function() {
+  return /** @type {!proto.protocol.Category} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
+};
+
+
+/** @param {!proto.protocol.Category} value */
+proto.protocol.BatchData.prototype.setCategory = function(value) {
+  jspb.Message.setProto3EnumField(this, 1, value);
+};
+
+
+/**
+ * repeated bytes messages = 2;
+ * @return {!(Array<!Uint8Array>|Array<string>)}
+ */
+proto.protocol.BatchData.prototype.getMessagesList = function() {
+  return /** @type {!(Array<!Uint8Array>|Array<string>)} */ (jspb.Message.getRepeatedField(this, 2));
+};
+
+
+/**
+ * repeated bytes messages = 2;
+ * This is a type-conversion wrapper around `getMessagesList()`
+ * @return {!Array<string>}
+ */
+proto.protocol.BatchData.prototype.getMessagesList_asB64 = function() {
+  return /** @type {!Array<string>} */ (jspb.Message.bytesListAsB64(
+      this.getMessagesList()));
+};
+
+
+/**
+ * repeated bytes messages = 2;
+ * Note that Uint8Array is not supported on all browsers.
+ * @see http://caniuse.com/Uint8Array
+ * This is a type-conversion wrapper around `getMessagesList()`
+ * @return {!Array<!Uint8Array>}
+ */
+proto.protocol.BatchData.prototype.getMessagesList_asU8 = function() {
+  return /** @type {!Array<!Uint8Array>} */ (jspb.Message.bytesListAsU8(
+      this.getMessagesList()));
+};
+
+
+/** @param {!(Array<!Uint8Array>|Array<string>)} value */
+proto.protocol.BatchData.prototype.setMessagesList = function(value) {
+  jspb.Message.setField(this, 2, value || []);
+};
+
+
+/**
+ * @param {!(string|Uint8Array)} value
+ * @param {number=} opt_index
+ */
+proto.protocol.BatchData.prototype.addMessages = function(value, opt_index) {
+  jspb.Message.addToRepeatedField(this, 2, value, opt_index);
+};
+
+
+proto.protocol.BatchData.prototype.clearMessagesList = function() {
+  this.setMessagesList([]);
+};
+
+
 /**
  * @enum {number}
  */
@@ -1167,7 +2296,12 @@ proto.protocol.Category = {
   POSITION: 1,
   PROFILE: 2,
   CHAT: 3,
-  SCENE_MESSAGE: 4
+  SCENE_MESSAGE: 4,
+  DIRECT_MESSAGE: 5,
+  DIRECT_MESSAGE_ACK: 6,
+  COMPACT_POSITION: 7,
+  BATCH: 8,
+  FORWARDED_BATCH: 9
 };
 
 goog.object.extend(exports, proto.protocol);
@@ -0,0 +1,281 @@
+package protocol
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// CompactCellSize is the side in meters of the cells the compact positions are relative to, 4x4
+	// parcels aligned to the world origin. They are not the cells of the cell topics, which are
+	// aligned to MinParcel.
+	CompactCellSize = 4 * ParcelSize
+	// PositionResolution is the number of quantization steps per meter of the compact positions
+	PositionResolution = 128
+
+	rotationBits         = 10
+	rotationMask         = 1<<rotationBits - 1
+	positionHistorySize  = 64
+	maxRotationComponent = math.Sqrt2 / 2
+)
+
+// ErrUnknownBaseline is returned when decoding a delta whose baseline state was never received, or is
+// too old. The position has to be dropped until the next full state.
+var ErrUnknownBaseline = errors.New("unknown position baseline")
+
+type compactState struct {
+	sequence     uint32
+	time         int64
+	cellX, cellZ int32
+	x, y, z      int32
+	rotation     uint32
+}
+
+func quantizePosition(p *PositionData) compactState {
+	cellX, x := quantizeAxis(float64(p.PositionX))
+	cellZ, z := quantizeAxis(float64(p.PositionZ))
+
+	return compactState{
+		time:     int64(math.Round(p.Time)),
+		cellX:    cellX,
+		cellZ:    cellZ,
+		x:        x,
+		y:        int32(math.Round(float64(p.PositionY) * PositionResolution)),
+		z:        z,
+		rotation: packRotation(p.RotationX, p.RotationY, p.RotationZ, p.RotationW),
+	}
+}
+
+func quantizeAxis(v float64) (int32, int32) {
+	cell := math.Floor(v / CompactCellSize)
+	offset := math.Round((v - cell*CompactCellSize) * PositionResolution)
+
+	if offset >= CompactCellSize*PositionResolution {
+		cell++
+		offset = 0
+	}
+
+	return int32(cell), int32(offset)
+}
+
+func (s *compactState) positionData() *PositionData {
+	rx, ry, rz, rw := unpackRotation(s.rotation)
+
+	return &PositionData{
+		Category:  Category_POSITION,
+		Time:      float64(s.time),
+		PositionX: float32(float64(s.cellX)*CompactCellSize + float64(s.x)/PositionResolution),
+		PositionY: float32(float64(s.y) / PositionResolution),
+		PositionZ: float32(float64(s.cellZ)*CompactCellSize + float64(s.z)/PositionResolution),
+		RotationX: rx,
+		RotationY: ry,
+		RotationZ: rz,
+		RotationW: rw,
+	}
+}
+
+// packRotation compresses a quaternion with the smallest three method: the index of the largest
+// component in the top 2 bits, and the other three in 10 bits each. The largest component is
+// rebuilt from the unit length, its sign is dropped since q and -q are the same rotation.
+func packRotation(x, y, z, w float32) uint32 {
+	q := [4]float64{float64(x), float64(y), float64(z), float64(w)}
+
+	norm := math.Sqrt(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])
+	if norm == 0 {
+		q = [4]float64{0, 0, 0, 1}
+		norm = 1
+	}
+
+	largest := 0
+	for i := 1; i < 4; i++ {
+		if math.Abs(q[i]) > math.Abs(q[largest]) {
+			largest = i
+		}
+	}
+
+	sign := 1 / norm
+	if q[largest] < 0 {
+		sign = -sign
+	}
+
+	packed := uint32(largest) << (3 * rotationBits)
+	shift := uint(2 * rotationBits)
+
+	for i := 0; i < 4; i++ {
+		if i == largest {
+			continue
+		}
+
+		v := (q[i]*sign/maxRotationComponent + 1) / 2 * rotationMask
+		v = math.Max(0, math.Min(rotationMask, math.Round(v)))
+
+		packed |= uint32(v) << shift
+		shift -= rotationBits
+	}
+
+	return packed
+}
+
+func unpackRotation(packed uint32) (float32, float32, float32, float32) {
+	largest := int(packed >> (3 * rotationBits))
+
+	q := [4]float64{}
+	sum := 0.0
+	shift := uint(2 * rotationBits)
+
+	for i := 0; i < 4; i++ {
+		if i == largest {
+			continue
+		}
+
+		v := float64((packed >> shift) & rotationMask)
+		q[i] = (v/rotationMask*2 - 1) * maxRotationComponent
+		sum += q[i] * q[i]
+		shift -= rotationBits
+	}
+
+	q[largest] = math.Sqrt(math.Max(0, 1-sum))
+
+	return float32(q[0]), float32(q[1]), float32(q[2]), float32(q[3])
+}
+
+// PositionEncoder encodes the positions of a peer as CompactPositionData. Each state is sent as a
+// delta against the last acknowledged one, or in full if there is none.
+//
+// With a keyframe interval every Nth state is sent in full and taken as acknowledged, the way to
+// use it over the unreliable broadcast channels. A lost delta doesn't matter, a lost keyframe drops
+// the deltas until the next one. The protocol has no ack message, so it's the only mode the
+// clients can use for now.
+type PositionEncoder struct {
+	keyframeInterval uint32
+	sequence         uint32
+	history          [positionHistorySize]compactState
+
+	hasBaseline bool
+	baseline    compactState
+}
+
+// NewPositionEncoder creates a PositionEncoder, keyframeInterval is 0 if the states are acked
+// explicitly
+func NewPositionEncoder(keyframeInterval int) *PositionEncoder {
+	return &PositionEncoder{keyframeInterval: uint32(keyframeInterval)}
+}
+
+// Encode quantizes p and encodes it against the current baseline
+func (e *PositionEncoder) Encode(p *PositionData) *CompactPositionData {
+	e.sequence++
+	if e.sequence == 0 {
+		e.sequence = 1
+	}
+
+	s := quantizePosition(p)
+	s.sequence = e.sequence
+	e.history[s.sequence%positionHistorySize] = s
+
+	maxAge := uint32(positionHistorySize)
+	if e.keyframeInterval > 0 && e.keyframeInterval < maxAge {
+		maxAge = e.keyframeInterval
+	}
+
+	if !e.hasBaseline || s.sequence-e.baseline.sequence >= maxAge {
+		if e.keyframeInterval > 0 {
+			e.hasBaseline = true
+			e.baseline = s
+		}
+
+		return &CompactPositionData{
+			Category:  Category_COMPACT_POSITION,
+			Sequence:  s.sequence,
+			Time:      s.time,
+			CellX:     s.cellX,
+			CellZ:     s.cellZ,
+			PositionX: s.x,
+			PositionY: s.y,
+			PositionZ: s.z,
+			Rotation:  s.rotation,
+		}
+	}
+
+	b := &e.baseline
+
+	return &CompactPositionData{
+		Category:  Category_COMPACT_POSITION,
+		Sequence:  s.sequence,
+		Baseline:  b.sequence,
+		Time:      s.time - b.time,
+		CellX:     s.cellX - b.cellX,
+		CellZ:     s.cellZ - b.cellZ,
+		PositionX: s.x - b.x,
+		PositionY: s.y - b.y,
+		PositionZ: s.z - b.z,
+		Rotation:  s.rotation ^ b.rotation,
+	}
+}
+
+// Ack marks the state with the given sequence as received, the next deltas are encoded against it
+// if it's newer than the current baseline. Unknown or too old sequences are ignored.
+func (e *PositionEncoder) Ack(sequence uint32) {
+	s := e.history[sequence%positionHistorySize]
+	if sequence == 0 || s.sequence != sequence {
+		return
+	}
+
+	if e.hasBaseline && int32(sequence-e.baseline.sequence) <= 0 {
+		return
+	}
+
+	e.hasBaseline = true
+	e.baseline = s
+}
+
+// Reset drops the baseline, the next state is sent in full
+func (e *PositionEncoder) Reset() {
+	e.hasBaseline = false
+}
+
+// PositionDecoder decodes the CompactPositionData of a single peer, it keeps the last states to
+// resolve the delta baselines
+type PositionDecoder struct {
+	history [positionHistorySize]compactState
+}
+
+// NewPositionDecoder creates a PositionDecoder
+func NewPositionDecoder() *PositionDecoder {
+	return &PositionDecoder{}
+}
+
+// Decode returns the position encoded in m, it returns ErrUnknownBaseline if m is a delta against a
+// state this decoder doesn't have
+func (d *PositionDecoder) Decode(m *CompactPositionData) (*PositionData, error) {
+	s := compactState{
+		sequence: m.Sequence,
+		time:     m.Time,
+		cellX:    m.CellX,
+		cellZ:    m.CellZ,
+		x:        m.PositionX,
+		y:        m.PositionY,
+		z:        m.PositionZ,
+		rotation: m.Rotation,
+	}
+
+	if m.Baseline != 0 {
+		b := d.history[m.Baseline%positionHistorySize]
+		if b.sequence != m.Baseline {
+			return nil, ErrUnknownBaseline
+		}
+
+		s.time += b.time
+		s.cellX += b.cellX
+		s.cellZ += b.cellZ
+		s.x += b.x
+		s.y += b.y
+		s.z += b.z
+		s.rotation ^= b.rotation
+	}
+
+	if m.Sequence != 0 {
+		d.history[m.Sequence%positionHistorySize] = s
+	}
+
+	return s.positionData(), nil
+}
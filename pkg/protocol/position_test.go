@@ -0,0 +1,190 @@
+package protocol
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// walk returns the i-th position of a peer walking in circles at ~4m/s, sampled every 100ms
+func walk(i int) *PositionData {
+	angle := float64(i) * 0.02
+	yaw := angle + math.Pi/2
+
+	return &PositionData{
+		Category:  Category_POSITION,
+		Time:      1575000000000 + float64(i*100),
+		PositionX: float32(-40 + 20*math.Cos(angle)),
+		PositionY: 1.6,
+		PositionZ: float32(100 + 20*math.Sin(angle)),
+		RotationY: float32(math.Sin(yaw / 2)),
+		RotationW: float32(math.Cos(yaw / 2)),
+	}
+}
+
+func assertPosition(t *testing.T, expected *PositionData, actual *PositionData) {
+	assert.Equal(t, Category_POSITION, actual.Category)
+	assert.Equal(t, expected.Time, actual.Time)
+
+	delta := 1.0 / PositionResolution
+	assert.InDelta(t, expected.PositionX, actual.PositionX, delta)
+	assert.InDelta(t, expected.PositionY, actual.PositionY, delta)
+	assert.InDelta(t, expected.PositionZ, actual.PositionZ, delta)
+
+	// q and -q are the same rotation
+	dot := expected.RotationX*actual.RotationX + expected.RotationY*actual.RotationY +
+		expected.RotationZ*actual.RotationZ + expected.RotationW*actual.RotationW
+	assert.InDelta(t, 1, math.Abs(float64(dot)), 0.0001)
+}
+
+func TestPackRotation(t *testing.T) {
+	rotations := [][4]float32{
+		{0, 0, 0, 1},
+		{0, 0, 0, -1},
+		{0.5, 0.5, 0.5, 0.5},
+		{0, 0.7071068, 0, 0.7071068},
+		{-0.1, 0.9, 0.2, -0.3},
+		{0, 0, 0, 0},
+	}
+
+	for _, r := range rotations {
+		x, y, z, w := unpackRotation(packRotation(r[0], r[1], r[2], r[3]))
+
+		norm := math.Sqrt(float64(r[0]*r[0] + r[1]*r[1] + r[2]*r[2] + r[3]*r[3]))
+		if norm == 0 {
+			r, norm = [4]float32{0, 0, 0, 1}, 1
+		}
+
+		dot := (float64(r[0]*x+r[1]*y+r[2]*z+r[3]*w) / norm)
+		assert.InDelta(t, 1, math.Abs(dot), 0.0001, "rotation %v", r)
+	}
+}
+
+func TestQuantizeAxis(t *testing.T) {
+	cell, offset := quantizeAxis(-0.1)
+	assert.Equal(t, int32(-1), cell)
+	assert.Equal(t, int32(8179), offset)
+
+	cell, offset = quantizeAxis(64.5)
+	assert.Equal(t, int32(1), cell)
+	assert.Equal(t, int32(64), offset)
+
+	// rounded up to the next cell
+	cell, offset = quantizeAxis(-0.001)
+	assert.Equal(t, int32(0), cell)
+	assert.Equal(t, int32(0), offset)
+}
+
+func TestPositionEncoder(t *testing.T) {
+	t.Run("keyframes", func(t *testing.T) {
+		encoder := NewPositionEncoder(10)
+		decoder := NewPositionDecoder()
+
+		for i := 0; i < 30; i++ {
+			m := encoder.Encode(walk(i))
+			if i%10 == 0 {
+				assert.Equal(t, uint32(0), m.Baseline)
+			} else {
+				assert.Equal(t, uint32(i-i%10+1), m.Baseline)
+			}
+
+			p, err := decoder.Decode(m)
+			require.NoError(t, err)
+			assertPosition(t, walk(i), p)
+		}
+	})
+
+	t.Run("lost keyframe", func(t *testing.T) {
+		encoder := NewPositionEncoder(10)
+		decoder := NewPositionDecoder()
+
+		encoder.Encode(walk(0))
+
+		_, err := decoder.Decode(encoder.Encode(walk(1)))
+		assert.Equal(t, ErrUnknownBaseline, err)
+
+		for i := 2; i < 10; i++ {
+			encoder.Encode(walk(i))
+		}
+
+		p, err := decoder.Decode(encoder.Encode(walk(10)))
+		require.NoError(t, err)
+		assertPosition(t, walk(10), p)
+	})
+
+	t.Run("acks", func(t *testing.T) {
+		encoder := NewPositionEncoder(0)
+		decoder := NewPositionDecoder()
+
+		first := encoder.Encode(walk(0))
+		_, err := decoder.Decode(first)
+		require.NoError(t, err)
+
+		m := encoder.Encode(walk(1))
+		assert.Equal(t, uint32(0), m.Baseline, "full until acked")
+
+		encoder.Ack(first.Sequence)
+		encoder.Ack(1000)
+
+		for i := 2; i < 5; i++ {
+			m := encoder.Encode(walk(i))
+			assert.Equal(t, first.Sequence, m.Baseline)
+
+			p, err := decoder.Decode(m)
+			require.NoError(t, err)
+			assertPosition(t, walk(i), p)
+		}
+
+		encoder.Ack(m.Sequence)
+		encoder.Ack(first.Sequence)
+
+		m = encoder.Encode(walk(5))
+		assert.Equal(t, uint32(2), m.Baseline, "older acks are ignored")
+
+		encoder.Reset()
+		assert.Equal(t, uint32(0), encoder.Encode(walk(6)).Baseline)
+	})
+
+	t.Run("cell crossing", func(t *testing.T) {
+		encoder := NewPositionEncoder(10)
+		decoder := NewPositionDecoder()
+
+		positions := []*PositionData{
+			{Time: 1, PositionX: 63.9, PositionZ: -0.1, RotationW: 1},
+			{Time: 2, PositionX: 64.1, PositionZ: 0.1, RotationW: 1},
+		}
+
+		for _, position := range positions {
+			p, err := decoder.Decode(encoder.Encode(position))
+			require.NoError(t, err)
+			assertPosition(t, position, p)
+		}
+	})
+}
+
+// BenchmarkPositionEncoding reports the bytes per update of a peer walking, sending 10 updates per
+// second, with a keyframe every second
+func BenchmarkPositionEncoding(b *testing.B) {
+	b.Run("PositionData", func(b *testing.B) {
+		size := 0
+		for i := 0; i < b.N; i++ {
+			size += proto.Size(walk(i))
+		}
+
+		b.ReportMetric(float64(size)/float64(b.N), "bytes/update")
+	})
+
+	b.Run("CompactPositionData", func(b *testing.B) {
+		encoder := NewPositionEncoder(10)
+		size := 0
+
+		for i := 0; i < b.N; i++ {
+			size += proto.Size(encoder.Encode(walk(i)))
+		}
+
+		b.ReportMetric(float64(size)/float64(b.N), "bytes/update")
+	})
+}
@@ -0,0 +1,26 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// ParcelSize is the size of a parcel side in meters
+	ParcelSize = 16
+	// MaxParcel is the max parcel coordinate
+	MaxParcel = 150
+	// MinParcel is the min parcel coordinate
+	MinParcel = -150
+)
+
+// CellTopic returns the topic of the 4x4 parcels cell containing the world position x,z
+func CellTopic(x float64, z float64) string {
+	parcelX := (int(math.Floor(x/ParcelSize)) + MaxParcel) >> 2
+	parcelZ := (int(math.Floor(z/ParcelSize)) + MaxParcel) >> 2
+	return fmt.Sprintf("%d:%d", parcelX, parcelZ)
+}
+
+// DirectMessageTopic is the topic of the direct messages and their acks, the server only delivers
+// them to the peer of the recipient identity
+const DirectMessageTopic = "dm"
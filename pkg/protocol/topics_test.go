@@ -0,0 +1,25 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellTopic(t *testing.T) {
+	for _, c := range []struct {
+		x, z  float64
+		topic string
+	}{
+		{0, 0, "37:37"},
+		{8, 8, "37:37"},
+		{-8, -8, "37:37"},
+		{-32, -32, "37:37"},
+		// parcel -3, truncating the division would round it up to -2, in the 37 cell
+		{-40, -40, "36:36"},
+		{-33, 40, "36:38"},
+		{MinParcel * ParcelSize, MaxParcel*ParcelSize + ParcelSize/2, "0:75"},
+	} {
+		assert.Equal(t, c.topic, CellTopic(c.x, c.z), "%f,%f", c.x, c.z)
+	}
+}